@@ -4,10 +4,15 @@ import (
 	"context"
 	"log"
 	"time"
+	_ "time/tzdata" // saat dilimleri tzdata kurulu olmayan imajlarda da yüklenebilsin
 
 	"github.com/denizbarcak/planvia-partner-api/config"
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/caldav"
 	"github.com/denizbarcak/planvia-partner-api/internal/database"
+	"github.com/denizbarcak/planvia-partner-api/internal/events"
 	"github.com/denizbarcak/planvia-partner-api/internal/handlers"
+	"github.com/denizbarcak/planvia-partner-api/internal/mailer"
 	"github.com/denizbarcak/planvia-partner-api/internal/middleware"
 
 	"github.com/gofiber/fiber/v2"
@@ -18,6 +23,18 @@ func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	// Load JWT signing keys
+	keys, err := auth.NewKeySet(cfg.JWT)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+
+	// Initialize mailer
+	mail, err := mailer.New(cfg.Mailer)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+
 	// Create context with timeout for database operations
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -35,6 +52,14 @@ func main() {
 
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
+		// CalDAV clients use the WebDAV methods PROPFIND and REPORT
+		RequestMethods: append(fiber.DefaultMethods, caldav.Methods...),
+		// Behind a reverse proxy the client IP is read from its header, but only
+		// when the request comes from a trusted proxy
+		ProxyHeader:             cfg.Proxy.Header,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.Proxy.TrustedProxies,
+		EnableIPValidation:      true,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -48,30 +73,137 @@ func main() {
 
 	// Configure CORS
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "http://localhost:3000",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE",
+		AllowOrigins:  "http://localhost:3000",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, X-API-Key",
+		AllowMethods:  "GET, POST, PUT, DELETE",
+		ExposeHeaders: "X-Reservation-Conflicts, X-Reservation-Outside-Hours",
 	}))
 
-	// Initialize handlers
+	// Ensure indexes
 	db := client.Database(cfg.DBName)
-	partnerHandler := handlers.NewPartnerHandler(db)
+	if err := database.EnsureIndexes(ctx, db); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+
+	// Initialize handlers
+	sessions := auth.NewSessionStore(db, cfg.JWT.RefreshTokenTTL)
+	throttle := auth.NewLoginThrottle(db)
+	apiKeys := auth.NewAPIKeyStore(db)
+	appPasswords := auth.NewAppPasswordStore(db)
+	partnerHandler := handlers.NewPartnerHandler(db, handlers.PartnerHandlerConfig{
+		Keys:         keys,
+		Sessions:     sessions,
+		Throttle:     throttle,
+		AppPasswords: appPasswords,
+		Mailer:       mail,
+		AppURL:       cfg.AppURL,
+	})
 	reservationHandler := handlers.NewReservationHandler(db)
+	staffHandler := handlers.NewStaffHandler(db, sessions, throttle)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeys)
+	jwksHandler := handlers.NewJWKSHandler(keys)
+	eventHandler := handlers.NewEventHandler(events.NewOutbox(db))
+	authConfig := middleware.AuthConfig{
+		Keys:         keys,
+		Sessions:     sessions,
+		APIKeys:      apiKeys,
+		Partners:     db.Collection("partners"),
+		Staff:        db.Collection("staff"),
+		Throttle:     throttle,
+		AppPasswords: appPasswords,
+	}
+	authMiddleware := middleware.AuthMiddleware(authConfig)
+	basicAuthMiddleware := middleware.BasicAuthMiddleware(authConfig)
 
 	// Setup routes
+	app.Get("/.well-known/jwks.json", jwksHandler.GetJWKS)
+	app.All("/.well-known/caldav", reservationHandler.WellKnownCalDAV)
+
 	api := app.Group("/api")
 	
 	// Partner routes
 	partners := api.Group("/partners")
 	partners.Post("/register", partnerHandler.Register)
 	partners.Post("/login", partnerHandler.Login)
+	partners.Post("/login/2fa", partnerHandler.LoginTwoFactor)
+	partners.Post("/refresh", partnerHandler.Refresh)
+	partners.Post("/verify-email", partnerHandler.VerifyEmail)
+	partners.Post("/resend-verification", partnerHandler.ResendVerification)
+	partners.Post("/forgot-password", partnerHandler.ForgotPassword)
+	partners.Post("/reset-password", partnerHandler.ResetPassword)
+	partners.Get("/me", authMiddleware, partnerHandler.GetProfile)
+	partners.Put("/me", authMiddleware, middleware.RequirePermission(auth.PermAccountManage), partnerHandler.UpdateProfile)
+	partners.Post("/me/password", authMiddleware, partnerHandler.ChangePassword)
+	partners.Get("/me/calendar-feed", authMiddleware, middleware.RequirePermission(auth.PermAccountManage), reservationHandler.GetCalendarFeedSettings)
+	partners.Post("/me/calendar-feed", authMiddleware, middleware.RequirePermission(auth.PermAccountManage), reservationHandler.RotateCalendarFeed)
+	partners.Delete("/me/calendar-feed", authMiddleware, middleware.RequirePermission(auth.PermAccountManage), reservationHandler.RevokeCalendarFeed)
+	partners.Get("/me/app-passwords", authMiddleware, partnerHandler.ListAppPasswords)
+	partners.Post("/me/app-passwords", authMiddleware, partnerHandler.CreateAppPassword)
+	partners.Delete("/me/app-passwords/:id", authMiddleware, partnerHandler.RevokeAppPassword)
+	partners.Post("/logout", authMiddleware, partnerHandler.Logout)
+	partners.Post("/logout-all", authMiddleware, partnerHandler.LogoutAll)
+
+	// Two-factor authentication routes
+	twoFactor := partners.Group("/2fa", authMiddleware, middleware.RequirePermission(auth.PermAccountManage))
+	twoFactor.Post("/setup", partnerHandler.SetupTwoFactor)
+	twoFactor.Post("/enable", partnerHandler.EnableTwoFactor)
+	twoFactor.Post("/disable", partnerHandler.DisableTwoFactor)
+	twoFactor.Post("/recovery-codes", partnerHandler.RegenerateRecoveryCodes)
 
 	// Reservation routes (protected by auth middleware)
-	reservations := api.Group("/reservations", middleware.AuthMiddleware)
-	reservations.Post("/", reservationHandler.CreateReservation)
-	reservations.Get("/", reservationHandler.GetPartnerReservations)
-	reservations.Put("/:id", reservationHandler.UpdateReservation)
-	reservations.Delete("/:id", reservationHandler.DeleteReservation)
+	reservations := api.Group("/reservations", authMiddleware)
+	reservations.Post("/", middleware.RequirePermission(auth.PermReservationsCreate), reservationHandler.CreateReservation)
+	reservations.Get("/", middleware.RequirePermission(auth.PermReservationsRead), reservationHandler.GetPartnerReservations)
+	reservations.Get("/sync", middleware.RequirePermission(auth.PermReservationsRead), reservationHandler.SyncReservations)
+	reservations.Get("/export.ics", middleware.RequirePermission(auth.PermReservationsRead), reservationHandler.ExportCalendar)
+	reservations.Post("/import", middleware.RequirePermission(auth.PermReservationsCreate), middleware.RequirePermission(auth.PermReservationsUpdate), reservationHandler.ImportCalendar)
+	reservations.Put("/:id", middleware.RequirePermission(auth.PermReservationsUpdate), reservationHandler.UpdateReservation)
+	reservations.Delete("/:id", middleware.RequirePermission(auth.PermReservationsDelete), reservationHandler.DeleteReservation)
+	reservations.Get("/:id/bookings", middleware.RequirePermission(auth.PermBookingsRead), reservationHandler.ListBookings)
+	reservations.Post("/:id/bookings", middleware.RequirePermission(auth.PermBookingsManage), reservationHandler.CreateBooking)
+	reservations.Post("/:id/bookings/:bookingId/cancel", middleware.RequirePermission(auth.PermBookingsManage), reservationHandler.CancelBooking)
+	reservations.Post("/:id/holds", middleware.RequirePermission(auth.PermBookingsManage), reservationHandler.CreateSeatHold)
+	reservations.Post("/:id/holds/:holdId/confirm", middleware.RequirePermission(auth.PermBookingsManage), reservationHandler.ConfirmSeatHold)
+	reservations.Delete("/:id/holds/:holdId", middleware.RequirePermission(auth.PermBookingsManage), reservationHandler.ReleaseSeatHold)
+
+	// Public routes (no auth, rate limited per IP, callable from any origin)
+	public := api.Group("/public",
+		cors.New(cors.Config{AllowOrigins: "*", AllowMethods: "GET"}),
+		// Counters are incremented atomically in MongoDB and shared by all API instances
+		middleware.RateLimit(db.Collection("rate_limits"), cfg.Public.RateLimit, cfg.Public.RateWindow),
+	)
+	public.Get("/partners/:partnerId/availability", reservationHandler.GetAvailability)
+	public.Get("/calendars/:token.ics", reservationHandler.GetCalendarFeed)
+
+	// CalDAV routes (HTTP Basic auth with the account or an app password)
+	dav := app.Group("/caldav")
+	dav.Options("/*", reservationHandler.CalDAVOptions)
+	dav.Add("PROPFIND", "/", basicAuthMiddleware, middleware.RequirePermission(auth.PermReservationsRead), reservationHandler.PropfindCalDAVRoot)
+	dav.Add("PROPFIND", "/reservations", basicAuthMiddleware, middleware.RequirePermission(auth.PermReservationsRead), reservationHandler.PropfindCalDAVCalendar)
+	dav.Add("REPORT", "/reservations", basicAuthMiddleware, middleware.RequirePermission(auth.PermReservationsRead), reservationHandler.ReportCalDAVCalendar)
+	dav.Add("PROPFIND", "/reservations/:name", basicAuthMiddleware, middleware.RequirePermission(auth.PermReservationsRead), reservationHandler.PropfindCalDAVObject)
+	dav.Get("/reservations/:name", basicAuthMiddleware, middleware.RequirePermission(auth.PermReservationsRead), reservationHandler.GetCalDAVObject)
+	dav.Put("/reservations/:name", basicAuthMiddleware, middleware.RequirePermission(auth.PermReservationsCreate), middleware.RequirePermission(auth.PermReservationsUpdate), reservationHandler.PutCalDAVObject)
+	dav.Delete("/reservations/:name", basicAuthMiddleware, middleware.RequirePermission(auth.PermReservationsDelete), reservationHandler.DeleteCalDAVObject)
+
+	// Event routes (e.g. bookings promoted from the waitlist)
+	api.Get("/events", authMiddleware, middleware.RequirePermission(auth.PermBookingsRead), eventHandler.ListEvents)
+
+	// Staff routes (partner owner only)
+	staff := api.Group("/staff", authMiddleware, middleware.RequirePermission(auth.PermStaffManage))
+	staff.Get("/", staffHandler.ListStaff)
+	staff.Post("/", staffHandler.CreateStaff)
+	staff.Put("/:id", staffHandler.UpdateStaff)
+	staff.Delete("/:id", staffHandler.DeleteStaff)
+
+	// API key routes (partner owner only)
+	apiKeyRoutes := api.Group("/api-keys", authMiddleware, middleware.RequirePermission(auth.PermAPIKeysManage))
+	apiKeyRoutes.Get("/", apiKeyHandler.ListAPIKeys)
+	apiKeyRoutes.Post("/", apiKeyHandler.CreateAPIKey)
+	apiKeyRoutes.Delete("/:id", apiKeyHandler.RevokeAPIKey)
+
+	// Release seats of expired holds in the background
+	go reservationHandler.RunHoldSweeper(context.Background(), 15*time.Second)
 
 	// Start server
 	port := ":" + cfg.Port
@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +14,50 @@ type Config struct {
 	MongoURI string
 	DBName   string
 	Port     string
+	AppURL   string
+	JWT      JWTConfig
+	Mailer   MailerConfig
+	Public   PublicConfig
+	Proxy    ProxyConfig
+}
+
+// JWTConfig token imzalama ayarlarını tutar
+type JWTConfig struct {
+	Issuer          string
+	ActiveKeyID     string
+	Keys            []JWTKey
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// MailerConfig e-posta gönderim ayarlarını tutar. Driver "log" veya "file" olabilir.
+type MailerConfig struct {
+	Driver   string
+	From     string
+	FilePath string
+}
+
+// PublicConfig kimlik doğrulamasız uçların istek sınırını tutar. Her IP adresi
+// RateWindow süresinde en fazla RateLimit istek yapabilir.
+type PublicConfig struct {
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+// ProxyConfig API bir ters vekil sunucunun arkasında çalışırken istemci IP'sinin nasıl
+// bulunacağını tutar. Header yalnızca TrustedProxies'teki adreslerden gelen isteklerde
+// okunur; diğer isteklerde bağlantının adresi kullanılır.
+type ProxyConfig struct {
+	Header         string
+	TrustedProxies []string
+}
+
+// JWTKey tek bir imzalama anahtarını tanımlar. HS256 için Value gizli anahtarın
+// kendisi, RS256/ES256 için PEM dosyasının yoludur.
+type JWTKey struct {
+	ID        string
+	Algorithm string
+	Value     string
 }
 
 func LoadConfig() *Config {
@@ -23,7 +70,74 @@ func LoadConfig() *Config {
 		MongoURI: getEnv("MONGO_URI", "mongodb://localhost:27017"),
 		DBName:   getEnv("DB_NAME", "planvia"),
 		Port:     getEnv("PORT", "5000"),
+		AppURL:   getEnv("APP_URL", "http://localhost:3000"),
+		JWT:      loadJWTConfig(),
+		Mailer: MailerConfig{
+			Driver:   getEnv("MAILER_DRIVER", "log"),
+			From:     getEnv("MAILER_FROM", "no-reply@planvia.com"),
+			FilePath: getEnv("MAILER_FILE_PATH", "mail.log"),
+		},
+		Public: PublicConfig{
+			RateLimit:  getIntEnv("PUBLIC_RATE_LIMIT", 60),
+			RateWindow: getDurationEnv("PUBLIC_RATE_WINDOW", time.Minute),
+		},
+		Proxy: loadProxyConfig(),
+	}
+}
+
+// loadProxyConfig PROXY_HEADER ve virgülle ayrılmış TRUSTED_PROXIES değişkenlerini okur
+func loadProxyConfig() ProxyConfig {
+	cfg := ProxyConfig{Header: getEnv("PROXY_HEADER", "")}
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			cfg.TrustedProxies = append(cfg.TrustedProxies, proxy)
+		}
 	}
+	if cfg.Header != "" && len(cfg.TrustedProxies) == 0 {
+		log.Printf("PROXY_HEADER is set without TRUSTED_PROXIES; the header will be ignored")
+	}
+	return cfg
+}
+
+// loadJWTConfig JWT_KEYS değişkenini "kid:ALG:değer" girdilerinin virgülle
+// ayrılmış listesi olarak okur. JWT_KEYS yoksa JWT_SECRET tek bir HS256
+// anahtarı olarak kullanılır.
+func loadJWTConfig() JWTConfig {
+	cfg := JWTConfig{
+		Issuer:          getEnv("JWT_ISSUER", "planvia-partner-api"),
+		ActiveKeyID:     getEnv("JWT_ACTIVE_KID", ""),
+		AccessTokenTTL:  getDurationEnv("JWT_ACCESS_TTL", 15*time.Minute),
+		RefreshTokenTTL: getDurationEnv("JWT_REFRESH_TTL", 30*24*time.Hour),
+	}
+
+	for i, entry := range strings.Split(getEnv("JWT_KEYS", ""), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// Hatalı girdi anahtarın kendisi olabileceği için değeri değil yalnızca sırası yazılır
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			log.Fatalf("Invalid JWT_KEYS entry at index %d, expected kid:alg:key", i)
+		}
+		cfg.Keys = append(cfg.Keys, JWTKey{
+			ID:        parts[0],
+			Algorithm: strings.ToUpper(parts[1]),
+			Value:     parts[2],
+		})
+	}
+
+	if len(cfg.Keys) == 0 {
+		if secret := getEnv("JWT_SECRET", ""); secret != "" {
+			cfg.Keys = append(cfg.Keys, JWTKey{ID: "default", Algorithm: "HS256", Value: secret})
+		}
+	}
+
+	if cfg.ActiveKeyID == "" && len(cfg.Keys) > 0 {
+		cfg.ActiveKeyID = cfg.Keys[0].ID
+	}
+
+	return cfg
 }
 
 func getEnv(key, fallback string) string {
@@ -31,4 +145,30 @@ func getEnv(key, fallback string) string {
 		return value
 	}
 	return fallback
-} 
\ No newline at end of file
+}
+
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s, using default: %v", key, err)
+		return fallback
+	}
+	return d
+}
+
+func getIntEnv(key string, fallback int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		log.Printf("Invalid number for %s, using default", key)
+		return fallback
+	}
+	return n
+}
\ No newline at end of file
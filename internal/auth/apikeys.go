@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// apiKeyPrefix tüm Planvia API key'lerinin başındaki sabit önektir; log ve
+// secret tarayıcılarında key'lerin tanınmasını kolaylaştırır
+const apiKeyPrefix = "pvk_"
+
+var ErrAPIKeyInvalid = errors.New("API key geçersiz, iptal edilmiş veya süresi dolmuş")
+
+// APIKeyStore partner'a ait API key'leri MongoDB'de saklar
+type APIKeyStore struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyStore(db *mongo.Database) *APIKeyStore {
+	return &APIKeyStore{collection: db.Collection("api_keys")}
+}
+
+// Create yeni bir key üretir. Tam key yalnızca burada döner, sonradan gösterilemez.
+func (s *APIKeyStore) Create(ctx context.Context, key *models.APIKey) (string, error) {
+	prefix := make([]byte, 6)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", err
+	}
+	secret, err := NewOpaqueToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	key.ID = primitive.NewObjectID()
+	key.Prefix = apiKeyPrefix + hex.EncodeToString(prefix)
+	key.SecretHash = HashToken(secret)
+	key.CreatedAt = time.Now()
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+
+	if _, err := s.collection.InsertOne(ctx, key); err != nil {
+		return "", err
+	}
+
+	return key.Prefix + "." + secret, nil
+}
+
+// List partner'ın key'lerini en yeniden eskiye döndürür
+func (s *APIKeyStore) List(ctx context.Context, partnerID primitive.ObjectID) ([]models.APIKey, error) {
+	cursor, err := s.collection.Find(ctx,
+		bson.M{"partner_id": partnerID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revoke key'i iptal eder; iptal edilen key'le gelen istekler reddedilir
+func (s *APIKeyStore) Revoke(ctx context.Context, partnerID, keyID primitive.ObjectID) (bool, error) {
+	result, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": keyID, "partner_id": partnerID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// Authenticate "pvk_<önek>.<gizli değer>" biçimindeki key'i doğrular
+func (s *APIKeyStore) Authenticate(ctx context.Context, rawKey string) (*models.APIKey, error) {
+	prefix, secret, ok := strings.Cut(strings.TrimSpace(rawKey), ".")
+	if !ok || !strings.HasPrefix(prefix, apiKeyPrefix) || secret == "" {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	var key models.APIKey
+	err := s.collection.FindOne(ctx, bson.M{"prefix": prefix}).Decode(&key)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(key.SecretHash), []byte(HashToken(secret))) != 1 ||
+		key.RevokedAt != nil ||
+		(key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	// Her istekte yazmamak için son kullanım zamanı dakikada bir güncellenir
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute {
+		_, _ = s.collection.UpdateOne(ctx, bson.M{"_id": key.ID}, bson.M{"$set": bson.M{"last_used_at": now}})
+	}
+
+	return &key, nil
+}
+
+// APIKeyAllows key'in verilen izne sahip olup olmadığını döndürür
+func APIKeyAllows(scopes []string, perm Permission) bool {
+	if len(scopes) == 0 {
+		for _, p := range APIKeyPermissions {
+			if p == perm {
+				return true
+			}
+		}
+		return false
+	}
+	for _, scope := range scopes {
+		if Permission(scope) == perm {
+			return true
+		}
+	}
+	return false
+}
\ No newline at end of file
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Uygulama şifreleri takvim uygulamalarına elle yazılabilmesi için dört harflik dört gruptan
+// oluşur (ör. abcd-efgh-ijkl-mnop); yaklaşık 75 bit rastgelelik taşır
+const (
+	appPasswordAlphabet = "abcdefghijklmnopqrstuvwxyz"
+	appPasswordGroups   = 4
+	appPasswordGroupLen = 4
+)
+
+// MaxAppPasswords bir kullanıcının aynı anda sahip olabileceği en fazla uygulama şifresidir
+const MaxAppPasswords = 25
+
+var (
+	ErrAppPasswordInvalid = errors.New("Uygulama şifresi geçersiz veya iptal edilmiş")
+	ErrAppPasswordLimit   = errors.New("Uygulama şifresi sınırına ulaşıldı")
+)
+
+// AppPasswordStore kullanıcıların CalDAV için oluşturduğu uygulama şifrelerini saklar
+type AppPasswordStore struct {
+	collection *mongo.Collection
+}
+
+func NewAppPasswordStore(db *mongo.Database) *AppPasswordStore {
+	return &AppPasswordStore{collection: db.Collection("app_passwords")}
+}
+
+// Create kullanıcı için yeni bir uygulama şifresi üretir. Şifre yalnızca burada döner,
+// sonradan gösterilemez.
+func (s *AppPasswordStore) Create(ctx context.Context, password *models.AppPassword) (string, error) {
+	count, err := s.collection.CountDocuments(ctx, bson.M{"user_id": password.UserID})
+	if err != nil {
+		return "", err
+	}
+	if count >= MaxAppPasswords {
+		return "", ErrAppPasswordLimit
+	}
+
+	groups := make([]string, appPasswordGroups)
+	max := big.NewInt(int64(len(appPasswordAlphabet)))
+	for i := range groups {
+		group := make([]byte, appPasswordGroupLen)
+		for j := range group {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			group[j] = appPasswordAlphabet[n.Int64()]
+		}
+		groups[i] = string(group)
+	}
+	raw := strings.Join(groups, "-")
+
+	password.ID = primitive.NewObjectID()
+	password.PasswordHash = HashToken(normalizeAppPassword(raw))
+	password.CreatedAt = time.Now()
+
+	if _, err := s.collection.InsertOne(ctx, password); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// List kullanıcının uygulama şifrelerini en yeniden eskiye döndürür
+func (s *AppPasswordStore) List(ctx context.Context, userID primitive.ObjectID) ([]models.AppPassword, error) {
+	cursor, err := s.collection.Find(ctx,
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	passwords := []models.AppPassword{}
+	if err := cursor.All(ctx, &passwords); err != nil {
+		return nil, err
+	}
+	return passwords, nil
+}
+
+// Revoke uygulama şifresini siler; şifreyle gelen istekler reddedilir
+func (s *AppPasswordStore) Revoke(ctx context.Context, userID, passwordID primitive.ObjectID) (bool, error) {
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": passwordID, "user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// Authenticate şifrenin kullanıcıya ait bir uygulama şifresi olup olmadığını kontrol eder.
+// Tireler, boşluklar ve büyük/küçük harf farkı yok sayılır.
+func (s *AppPasswordStore) Authenticate(ctx context.Context, userID primitive.ObjectID, raw string) (*models.AppPassword, error) {
+	normalized := normalizeAppPassword(raw)
+	if len(normalized) != appPasswordGroups*appPasswordGroupLen {
+		return nil, ErrAppPasswordInvalid
+	}
+
+	var password models.AppPassword
+	err := s.collection.FindOne(ctx, bson.M{
+		"user_id":       userID,
+		"password_hash": HashToken(normalized),
+	}).Decode(&password)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAppPasswordInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Takvim uygulamaları her istekte şifre gönderdiği için son kullanım zamanı dakikada bir güncellenir
+	now := time.Now()
+	if password.LastUsedAt == nil || now.Sub(*password.LastUsedAt) > time.Minute {
+		_, _ = s.collection.UpdateOne(ctx, bson.M{"_id": password.ID}, bson.M{"$set": bson.M{"last_used_at": now}})
+	}
+
+	return &password, nil
+}
+
+// RevokeAll kullanıcının tüm uygulama şifrelerini siler (ör. şifre sıfırlandığında)
+func (s *AppPasswordStore) RevokeAll(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+func normalizeAppPassword(raw string) string {
+	raw = strings.ToLower(raw)
+	return strings.NewReplacer("-", "", " ", "").Replace(raw)
+}
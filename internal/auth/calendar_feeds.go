@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// calendarFeedPrefix takvim aboneliği token'larının önekidir
+const calendarFeedPrefix = "pvc_"
+
+var ErrCalendarFeedInvalid = errors.New("Takvim aboneliği geçersiz veya iptal edilmiş")
+
+// CalendarFeedStore partner'ların gizli takvim aboneliği adreslerini saklar
+type CalendarFeedStore struct {
+	collection *mongo.Collection
+}
+
+func NewCalendarFeedStore(db *mongo.Database) *CalendarFeedStore {
+	return &CalendarFeedStore{collection: db.Collection("calendar_feeds")}
+}
+
+// Rotate partner için yeni bir token üretir; varsa eski token geçersiz olur. Tam token
+// yalnızca burada döner, sonradan gösterilemez.
+func (s *CalendarFeedStore) Rotate(ctx context.Context, partnerID, createdBy primitive.ObjectID) (*models.CalendarFeed, string, error) {
+	secret, err := NewOpaqueToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	token := calendarFeedPrefix + secret
+
+	var feed models.CalendarFeed
+	err = s.collection.FindOneAndUpdate(ctx,
+		bson.M{"partner_id": partnerID},
+		bson.M{
+			"$set": bson.M{
+				"token_hash": HashToken(token),
+				"created_by": createdBy,
+				"created_at": time.Now(),
+			},
+			"$unset": bson.M{"last_accessed_at": ""},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&feed)
+	if err != nil {
+		return nil, "", err
+	}
+	return &feed, token, nil
+}
+
+// Get partner'ın aboneliğini getirir; yoksa nil döner
+func (s *CalendarFeedStore) Get(ctx context.Context, partnerID primitive.ObjectID) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := s.collection.FindOne(ctx, bson.M{"partner_id": partnerID}).Decode(&feed)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// Revoke partner'ın aboneliğini siler; adres bir daha çalışmaz
+func (s *CalendarFeedStore) Revoke(ctx context.Context, partnerID primitive.ObjectID) (bool, error) {
+	result, err := s.collection.DeleteOne(ctx, bson.M{"partner_id": partnerID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// Authenticate adresteki token'a ait aboneliği döndürür
+func (s *CalendarFeedStore) Authenticate(ctx context.Context, token string) (*models.CalendarFeed, error) {
+	if !strings.HasPrefix(token, calendarFeedPrefix) {
+		return nil, ErrCalendarFeedInvalid
+	}
+
+	var feed models.CalendarFeed
+	err := s.collection.FindOne(ctx, bson.M{"token_hash": HashToken(token)}).Decode(&feed)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrCalendarFeedInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Takvim uygulamaları sık sorguladığı için son erişim zamanı saatte bir güncellenir
+	now := time.Now()
+	if feed.LastAccessedAt == nil || now.Sub(*feed.LastAccessedAt) > time.Hour {
+		_, _ = s.collection.UpdateOne(ctx, bson.M{"_id": feed.ID}, bson.M{"$set": bson.M{"last_accessed_at": now}})
+	}
+
+	return &feed, nil
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/config"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	ErrNoSigningKey = errors.New("aktif imzalama anahtarı bulunamadı")
+	ErrUnknownKey   = errors.New("bilinmeyen anahtar kimliği")
+	ErrInvalidToken = errors.New("geçersiz token")
+)
+
+// Key tek bir imzalama/doğrulama anahtarını temsil eder
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// CanSign anahtarın token imzalamak için kullanılıp kullanılamayacağını söyler.
+// Sadece public key'i bilinen eski anahtarlar yalnızca doğrulama yapar.
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// KeySet aktif anahtarla imzalar, kid header'ına göre tüm anahtarlarla doğrular
+type KeySet struct {
+	issuer    string
+	accessTTL time.Duration
+	active    *Key
+	keys      map[string]*Key
+}
+
+// NewKeySet config'deki anahtarları yükler
+func NewKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	ks := &KeySet{
+		issuer:    cfg.Issuer,
+		accessTTL: cfg.AccessTokenTTL,
+		keys:      make(map[string]*Key),
+	}
+
+	for _, kc := range cfg.Keys {
+		key, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kc.ID, err)
+		}
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, fmt.Errorf("jwt key %q tanımlı birden fazla kez", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+
+	active, ok := ks.keys[cfg.ActiveKeyID]
+	if !ok || !active.CanSign() {
+		return nil, ErrNoSigningKey
+	}
+	ks.active = active
+
+	return ks, nil
+}
+
+func loadKey(kc config.JWTKey) (*Key, error) {
+	key := &Key{ID: kc.ID}
+
+	switch kc.Algorithm {
+	case "HS256":
+		if len(kc.Value) < 32 {
+			return nil, errors.New("HS256 gizli anahtarı en az 32 karakter olmalıdır")
+		}
+		key.Method = jwt.SigningMethodHS256
+		key.signKey = []byte(kc.Value)
+		key.verifyKey = []byte(kc.Value)
+	case "RS256":
+		pem, err := os.ReadFile(kc.Value)
+		if err != nil {
+			return nil, err
+		}
+		key.Method = jwt.SigningMethodRS256
+		if priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+			key.signKey = priv
+			key.verifyKey = &priv.PublicKey
+		} else if pub, err := jwt.ParseRSAPublicKeyFromPEM(pem); err == nil {
+			key.verifyKey = pub
+		} else {
+			return nil, errors.New("RSA anahtarı okunamadı")
+		}
+	case "ES256":
+		pem, err := os.ReadFile(kc.Value)
+		if err != nil {
+			return nil, err
+		}
+		key.Method = jwt.SigningMethodES256
+		if priv, err := jwt.ParseECPrivateKeyFromPEM(pem); err == nil {
+			key.signKey = priv
+			key.verifyKey = &priv.PublicKey
+		} else if pub, err := jwt.ParseECPublicKeyFromPEM(pem); err == nil {
+			key.verifyKey = pub
+		} else {
+			return nil, errors.New("EC anahtarı okunamadı")
+		}
+		if key.verifyKey.(*ecdsa.PublicKey).Curve.Params().Name != "P-256" {
+			return nil, errors.New("ES256 için P-256 eğrisi gereklidir")
+		}
+	default:
+		return nil, fmt.Errorf("desteklenmeyen algoritma %q", kc.Algorithm)
+	}
+
+	return key, nil
+}
+
+// Sign claims'i aktif anahtarla imzalar ve kid header'ını ekler
+func (ks *KeySet) Sign(claims jwt.MapClaims) (string, error) {
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = time.Now().Unix()
+	}
+	if ks.issuer != "" {
+		claims["iss"] = ks.issuer
+	}
+
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.ID
+	return token.SignedString(ks.active.signKey)
+}
+
+// AccessTokenTTL access token'ların geçerlilik süresidir
+func (ks *KeySet) AccessTokenTTL() time.Duration {
+	return ks.accessTTL
+}
+
+// Parse token'ı kid header'ındaki anahtarla doğrular ve claims'i döndürür.
+// kid içermeyen eski token'lar aktif anahtarla doğrulanır.
+func (ks *KeySet) Parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		key := ks.active
+		if kid, ok := token.Header["kid"].(string); ok {
+			if key, ok = ks.keys[kid]; !ok {
+				return nil, ErrUnknownKey
+			}
+		}
+		// Algoritma karışıklığı saldırılarına karşı token'ın alg değeri anahtarınkiyle aynı olmalı
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return key.verifyKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	if ks.issuer != "" && !claims.VerifyIssuer(ks.issuer, false) {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// publicKeys JWKS'te yayınlanabilecek asimetrik anahtarları döndürür
+func (ks *KeySet) publicKeys() []*Key {
+	var keys []*Key
+	for _, key := range ks.keys {
+		switch key.verifyKey.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
\ No newline at end of file
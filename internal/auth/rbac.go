@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Roller; owner partner hesabının kendisidir, diğerleri personel hesaplarıdır
+const (
+	RoleOwner     = "owner"
+	RoleManager   = "manager"
+	RoleFrontDesk = "front_desk"
+	// RoleAPIKey API key ile gelen isteklerin rolüdür; izinleri key'in scope'larıdır
+	RoleAPIKey = "api_key"
+)
+
+// Permission bir rolün yapabileceği tek bir işlemdir
+type Permission string
+
+const (
+	PermReservationsRead   Permission = "reservations:read"
+	PermReservationsCreate Permission = "reservations:create"
+	PermReservationsUpdate Permission = "reservations:update"
+	PermReservationsDelete Permission = "reservations:delete"
+	PermBookingsRead       Permission = "bookings:read"
+	PermBookingsManage     Permission = "bookings:manage"
+	PermStaffManage        Permission = "staff:manage"
+	PermAccountManage      Permission = "account:manage"
+	PermAPIKeysManage      Permission = "api_keys:manage"
+)
+
+// APIKeyPermissions API key'lere verilebilecek izinlerdir. Scope belirtilmeyen
+// key'ler bu izinlerin tamamına sahiptir.
+var APIKeyPermissions = []Permission{
+	PermReservationsRead, PermReservationsCreate, PermReservationsUpdate, PermReservationsDelete,
+	PermBookingsRead, PermBookingsManage,
+}
+
+var rolePermissions = map[string][]Permission{
+	RoleOwner: {
+		PermReservationsRead, PermReservationsCreate, PermReservationsUpdate, PermReservationsDelete,
+		PermBookingsRead, PermBookingsManage,
+		PermStaffManage, PermAccountManage, PermAPIKeysManage,
+	},
+	RoleManager: {
+		PermReservationsRead, PermReservationsCreate, PermReservationsUpdate, PermReservationsDelete,
+		PermBookingsRead, PermBookingsManage,
+	},
+	RoleFrontDesk: {
+		PermReservationsRead, PermReservationsCreate, PermReservationsUpdate,
+		PermBookingsRead, PermBookingsManage,
+	},
+}
+
+// HasPermission rolün verilen izne sahip olup olmadığını döndürür
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Principal token'ın temsil ettiği kullanıcıdır. Partner hesabının kendisi için
+// UserID partner ID'sine eşittir.
+type Principal struct {
+	PartnerID primitive.ObjectID
+	UserID    primitive.ObjectID
+	Role      string
+}
+
+// OwnerPrincipal partner hesabının kendisi için Principal döndürür
+func OwnerPrincipal(partnerID primitive.ObjectID) Principal {
+	return Principal{PartnerID: partnerID, UserID: partnerID, Role: RoleOwner}
+}
\ No newline at end of file
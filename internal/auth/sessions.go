@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrSessionInvalid        = errors.New("oturum geçersiz veya süresi dolmuş")
+	ErrRefreshTokenReused    = errors.New("refresh token tekrar kullanıldı")
+	ErrMalformedRefreshToken = errors.New("geçersiz refresh token formatı")
+)
+
+// maxPreviousTokenHashes tekrar kullanım tespiti için saklanan eski refresh token sayısıdır.
+// Kullanımda kalan bir oturumun listesi bu sayıda tutulur; daha eski bir token gelirse
+// oturum iptal edilmez, yalnızca geçersiz sayılır.
+const maxPreviousTokenHashes = 50
+
+// SessionStore oturumları ve dönen (rotating) refresh token'ları MongoDB'de saklar
+type SessionStore struct {
+	collection *mongo.Collection
+	ttl        time.Duration
+}
+
+func NewSessionStore(db *mongo.Database, refreshTTL time.Duration) *SessionStore {
+	return &SessionStore{
+		collection: db.Collection("sessions"),
+		ttl:        refreshTTL,
+	}
+}
+
+// SessionMeta oturumu açan istemcinin bilgileridir
+type SessionMeta struct {
+	UserAgent string
+	IP        string
+}
+
+// Create yeni bir oturum açar ve ilk refresh token'ı döndürür
+func (s *SessionStore) Create(ctx context.Context, principal Principal, meta SessionMeta) (*models.Session, string, error) {
+	secret, err := NewOpaqueToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:                  primitive.NewObjectID(),
+		PartnerID:           principal.PartnerID,
+		UserID:              principal.UserID,
+		RefreshTokenHash:    HashToken(secret),
+		PreviousTokenHashes: []string{},
+		UserAgent:           meta.UserAgent,
+		IP:                  meta.IP,
+		CreatedAt:           now,
+		LastUsedAt:          now,
+		ExpiresAt:           now.Add(s.ttl),
+	}
+
+	if _, err := s.collection.InsertOne(ctx, session); err != nil {
+		return nil, "", err
+	}
+
+	return session, formatRefreshToken(session.ID, secret), nil
+}
+
+// Rotate refresh token'ı tek kullanımlık olarak tüketir ve yerine yenisini verir.
+// Daha önce kullanılmış bir token gelirse tüm token ailesi (oturum) iptal edilir.
+func (s *SessionStore) Rotate(ctx context.Context, refreshToken string) (*models.Session, string, error) {
+	sessionID, secret, err := parseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	newSecret, err := NewOpaqueToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	hash := HashToken(secret)
+	filter := bson.M{
+		"_id":                sessionID,
+		"refresh_token_hash": hash,
+		"revoked_at":         bson.M{"$exists": false},
+		"expires_at":         bson.M{"$gt": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"refresh_token_hash": HashToken(newSecret),
+			"last_used_at":       now,
+			"expires_at":         now.Add(s.ttl),
+		},
+		"$push": bson.M{"previous_token_hashes": bson.M{
+			"$each":  bson.A{hash},
+			"$slice": -maxPreviousTokenHashes,
+		}},
+	}
+
+	var session models.Session
+	err = s.collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&session)
+	if err == nil {
+		return &session, formatRefreshToken(session.ID, newSecret), nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, "", err
+	}
+
+	// Eski bir token tekrar kullanıldıysa token çalınmış olabilir, aileyi iptal et
+	reuse, err := s.collection.CountDocuments(ctx, bson.M{
+		"_id":                   sessionID,
+		"previous_token_hashes": hash,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if reuse > 0 {
+		if err := s.Revoke(ctx, sessionID); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	return nil, "", ErrSessionInvalid
+}
+
+// Revoke tek bir oturumu iptal eder
+func (s *SessionStore) Revoke(ctx context.Context, sessionID primitive.ObjectID) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": sessionID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
+
+// RevokeUser bir kullanıcının tüm oturumlarını iptal eder ("tüm cihazlardan çıkış")
+func (s *SessionStore) RevokeUser(ctx context.Context, partnerID, userID primitive.ObjectID) error {
+	_, err := s.collection.UpdateMany(ctx,
+		userSessionsFilter(partnerID, userID),
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
+
+// RevokeOthers kullanıcının verilen oturum dışındaki tüm oturumlarını iptal eder
+func (s *SessionStore) RevokeOthers(ctx context.Context, partnerID, userID, keepSessionID primitive.ObjectID) error {
+	filter := userSessionsFilter(partnerID, userID)
+	filter["_id"] = bson.M{"$ne": keepSessionID}
+
+	_, err := s.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	return err
+}
+
+// userSessionsFilter bir kullanıcının iptal edilmemiş oturumlarını seçer
+func userSessionsFilter(partnerID, userID primitive.ObjectID) bson.M {
+	filter := bson.M{
+		"partner_id": partnerID,
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+	}
+	if userID == partnerID {
+		// Personel hesaplarından önce açılan partner oturumlarında user_id yoktur
+		filter["user_id"] = bson.M{"$in": bson.A{userID, nil}}
+	}
+	return filter
+}
+
+// IsActive oturumun iptal edilmemiş ve süresinin dolmamış olduğunu kontrol eder
+func (s *SessionStore) IsActive(ctx context.Context, sessionID primitive.ObjectID) (bool, error) {
+	count, err := s.collection.CountDocuments(ctx, bson.M{
+		"_id":        sessionID,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Refresh token "<oturum id>.<gizli değer>" biçimindedir; veritabanında yalnızca
+// gizli değerin özeti tutulur.
+func formatRefreshToken(sessionID primitive.ObjectID, secret string) string {
+	return sessionID.Hex() + "." + secret
+}
+
+func parseRefreshToken(token string) (primitive.ObjectID, string, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return primitive.NilObjectID, "", ErrMalformedRefreshToken
+	}
+	sessionID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, "", ErrMalformedRefreshToken
+	}
+	return sessionID, secret, nil
+}
\ No newline at end of file
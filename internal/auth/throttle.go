@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ThrottlePolicy başarısız giriş denemelerine verilecek tepkiyi belirler
+type ThrottlePolicy struct {
+	// DelayAfter bu kadar başarısız denemeden sonra her yeni deneme beklemeye tabidir
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// LockAfter bu kadar başarısız denemeden sonra anahtar geçici olarak kilitlenir.
+	// Her yeni kilitte süre ikiye katlanır.
+	LockAfter       int
+	LockDuration    time.Duration
+	MaxLockDuration time.Duration
+	// Window son başarısız denemeden sonra sayaçların tutulduğu süredir
+	Window time.Duration
+}
+
+// Varsayılan politikalar; IP limiti paylaşılan ağlar için daha geniştir
+var (
+	EmailThrottlePolicy = ThrottlePolicy{
+		DelayAfter:      3,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockAfter:       10,
+		LockDuration:    15 * time.Minute,
+		MaxLockDuration: 24 * time.Hour,
+		Window:          time.Hour,
+	}
+	IPThrottlePolicy = ThrottlePolicy{
+		DelayAfter:      20,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockAfter:       100,
+		LockDuration:    15 * time.Minute,
+		MaxLockDuration: 6 * time.Hour,
+		Window:          time.Hour,
+	}
+)
+
+// ThrottleResult bir giriş denemesine izin verilip verilmediğini bildirir
+type ThrottleResult struct {
+	Allowed    bool
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+type loginAttempt struct {
+	Key           string     `bson:"_id"`
+	Failures      int        `bson:"failures"`
+	Lockouts      int        `bson:"lockouts"`
+	LastFailureAt time.Time  `bson:"last_failure_at"`
+	LockedUntil   *time.Time `bson:"locked_until,omitempty"`
+	ExpiresAt     time.Time  `bson:"expires_at"`
+}
+
+// LoginThrottle e-posta ve IP başına başarısız giriş sayaçlarını MongoDB'de tutar
+type LoginThrottle struct {
+	collection *mongo.Collection
+	email      ThrottlePolicy
+	ip         ThrottlePolicy
+}
+
+func NewLoginThrottle(db *mongo.Database) *LoginThrottle {
+	return &LoginThrottle{
+		collection: db.Collection("login_attempts"),
+		email:      EmailThrottlePolicy,
+		ip:         IPThrottlePolicy,
+	}
+}
+
+func emailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// reservation bir denemenin sayaca yazılmadan önceki durumudur; reddedilen deneme geri alınırken kullanılır
+type reservation struct {
+	key  string
+	prev *loginAttempt
+}
+
+// Reserve yeni bir denemeyi şifre kontrolünden önce başarısız sayarak ayırır ve izin verilip
+// verilmediğini döndürür. Sayaç tek bir atomik işlemle artırıldığı için eşzamanlı istekler
+// aynı anda kontrolden geçip kilidi aşamaz. Reddedilen deneme geri alınır; izin verilen deneme
+// RecordFailure ile başarısız kalır veya RecordSuccess ile geri alınır.
+func (t *LoginThrottle) Reserve(ctx context.Context, email, ip string) (ThrottleResult, error) {
+	now := time.Now()
+	result := ThrottleResult{Allowed: true}
+
+	var reserved []reservation
+	for _, k := range []struct {
+		key    string
+		policy ThrottlePolicy
+	}{{emailKey(email), t.email}, {ipKey(ip), t.ip}} {
+		prev, err := t.reserve(ctx, k.key, k.policy, now)
+		if err != nil {
+			t.release(ctx, reserved, now)
+			return result, err
+		}
+		reserved = append(reserved, reservation{key: k.key, prev: prev})
+		if prev == nil {
+			continue
+		}
+
+		if prev.LockedUntil != nil && prev.LockedUntil.After(now) {
+			result.Allowed = false
+			result.Locked = result.Locked || k.key == emailKey(email)
+			result.RetryAfter = maxDuration(result.RetryAfter, prev.LockedUntil.Sub(now))
+			continue
+		}
+
+		if wait := k.policy.delay(prev.Failures); wait > 0 {
+			if next := prev.LastFailureAt.Add(wait); next.After(now) {
+				result.Allowed = false
+				result.RetryAfter = maxDuration(result.RetryAfter, next.Sub(now))
+			}
+		}
+	}
+
+	if !result.Allowed {
+		t.release(ctx, reserved, now)
+	}
+	return result, nil
+}
+
+// reserve sayacı artırır ve önceki durumu döndürür; sayaç yoksa nil döner
+func (t *LoginThrottle) reserve(ctx context.Context, key string, policy ThrottlePolicy, now time.Time) (*loginAttempt, error) {
+	var prev loginAttempt
+	err := t.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": key},
+		bson.M{
+			"$inc": bson.M{"failures": 1},
+			"$set": bson.M{"last_failure_at": now},
+			"$max": bson.M{"expires_at": now.Add(policy.Window)},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&prev)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &prev, nil
+}
+
+// release ayrılan denemeleri geri alır. Son deneme zamanı, arada başka bir deneme
+// yazılmadıysa eski değerine döner.
+func (t *LoginThrottle) release(ctx context.Context, reserved []reservation, at time.Time) {
+	for _, r := range reserved {
+		if _, err := t.collection.UpdateOne(ctx,
+			bson.M{"_id": r.key, "failures": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"failures": -1}},
+		); err != nil {
+			continue
+		}
+		if r.prev != nil {
+			_, _ = t.collection.UpdateOne(ctx,
+				bson.M{"_id": r.key, "last_failure_at": at},
+				bson.M{"$set": bson.M{"last_failure_at": r.prev.LastFailureAt}},
+			)
+		}
+	}
+}
+
+// RecordFailure Reserve ile ayrılan denemeyi başarısız bırakır; eşik aşıldıysa e-postayı ve IP'yi kilitler
+func (t *LoginThrottle) RecordFailure(ctx context.Context, email, ip string) error {
+	if err := t.lockIfExceeded(ctx, emailKey(email), t.email); err != nil {
+		return err
+	}
+	return t.lockIfExceeded(ctx, ipKey(ip), t.ip)
+}
+
+func (t *LoginThrottle) lockIfExceeded(ctx context.Context, key string, policy ThrottlePolicy) error {
+	var attempt loginAttempt
+	err := t.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&attempt)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if attempt.Failures < policy.LockAfter {
+		return nil
+	}
+
+	// Kilitle ve sayacı sıfırla; kilit bitince gecikme aşaması yeniden başlar
+	now := time.Now()
+	lockedUntil := now.Add(policy.lockDuration(attempt.Lockouts + 1))
+	_, err = t.collection.UpdateOne(ctx,
+		bson.M{"_id": key, "failures": bson.M{"$gte": policy.LockAfter}},
+		bson.M{
+			"$set": bson.M{"failures": 0, "locked_until": lockedUntil},
+			"$inc": bson.M{"lockouts": 1},
+			"$max": bson.M{"expires_at": lockedUntil.Add(policy.Window)},
+		},
+	)
+	return err
+}
+
+// RecordSuccess başarılı denemeden sonra e-posta sayacını kaldırır ve IP için ayrılan denemeyi geri alır
+func (t *LoginThrottle) RecordSuccess(ctx context.Context, email, ip string) error {
+	if err := t.Reset(ctx, email); err != nil {
+		return err
+	}
+	_, err := t.collection.UpdateOne(ctx,
+		bson.M{"_id": ipKey(ip), "failures": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"failures": -1}},
+	)
+	return err
+}
+
+// Reset başarılı giriş veya şifre sıfırlamadan sonra e-posta sayacını ve kilidini kaldırır.
+// IP sayacı korunur, böylece tek bir geçerli hesap başka hesaplara yönelik denemeleri sıfırlayamaz.
+func (t *LoginThrottle) Reset(ctx context.Context, email string) error {
+	_, err := t.collection.DeleteOne(ctx, bson.M{"_id": emailKey(email)})
+	return err
+}
+
+// delay başarısız deneme sayısına göre bir sonraki denemeden önceki bekleme süresidir
+func (p ThrottlePolicy) delay(failures int) time.Duration {
+	if failures < p.DelayAfter {
+		return 0
+	}
+	d := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(failures-p.DelayAfter)))
+	if d <= 0 || d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+func (p ThrottlePolicy) lockDuration(lockouts int) time.Duration {
+	d := time.Duration(float64(p.LockDuration) * math.Pow(2, float64(lockouts-1)))
+	if d <= 0 || d > p.MaxLockDuration {
+		return p.MaxLockDuration
+	}
+	return d
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
\ No newline at end of file
@@ -0,0 +1,137 @@
+package booking
+
+import (
+	"context"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Yer ayırmaların varsayılan süresi ve biten ayırmaların silinmeden önce saklandığı süre
+const (
+	DefaultHoldTTL = 10 * time.Minute
+	HoldRetention  = 24 * time.Hour
+)
+
+// Holds süreli yer ayırmaları seat_holds koleksiyonunda tutar. Yerler Seats sayaçlarından
+// ayrılır; ayırmanın durumu yerlerin kimde olduğunu gösterir.
+type Holds struct {
+	collection *mongo.Collection
+}
+
+func NewHolds(db *mongo.Database) *Holds {
+	return &Holds{collection: db.Collection("seat_holds")}
+}
+
+// Create yerleri ayrılmış bir ayırmayı kaydeder
+func (h *Holds) Create(ctx context.Context, hold *models.SeatHold) error {
+	_, err := h.collection.InsertOne(ctx, hold)
+	return err
+}
+
+// Get partner'ın rezervasyondaki bir ayırmasını getirir; yoksa nil döner
+func (h *Holds) Get(ctx context.Context, partnerID, reservationID, holdID primitive.ObjectID) (*models.SeatHold, error) {
+	var hold models.SeatHold
+	err := h.collection.FindOne(ctx, bson.M{"_id": holdID, "partnerId": partnerID, "reservationId": reservationID}).Decode(&hold)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// Transition süresi dolmamış, held durumundaki ayırmayı yeni duruma geçirir. Ayırma
+// bulunamaz, süresi dolmuş veya başka duruma geçmişse nil döner.
+func (h *Holds) Transition(ctx context.Context, partnerID, reservationID, holdID primitive.ObjectID, status string, set bson.M) (*models.SeatHold, error) {
+	now := time.Now()
+	update := bson.M{"status": status, "updatedAt": now}
+	for k, v := range set {
+		update[k] = v
+	}
+
+	var hold models.SeatHold
+	err := h.collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":           holdID,
+			"partnerId":     partnerID,
+			"reservationId": reservationID,
+			"status":        models.SeatHoldStatusHeld,
+			"expiresAt":     bson.M{"$gt": now},
+		},
+		bson.M{"$set": update},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&hold)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// Reopen onaylanırken hata alınan ayırmayı yeniden held durumuna alır
+func (h *Holds) Reopen(ctx context.Context, holdID primitive.ObjectID) error {
+	_, err := h.collection.UpdateOne(ctx,
+		bson.M{"_id": holdID, "status": models.SeatHoldStatusConfirmed},
+		bson.M{
+			"$set":   bson.M{"status": models.SeatHoldStatusHeld, "updatedAt": time.Now()},
+			"$unset": bson.M{"bookingId": ""},
+		},
+	)
+	return err
+}
+
+// ExpireNext filter'a uyan, süresi dolmuş bir ayırmayı expired durumuna geçirip döndürür;
+// kalmadıysa nil döner. Geçiş tek işlemle yapıldığından birden fazla API örneği aynı
+// ayırmanın yerlerini iki kez bırakamaz.
+func (h *Holds) ExpireNext(ctx context.Context, filter bson.M) (*models.SeatHold, error) {
+	now := time.Now()
+	f := bson.M{"status": models.SeatHoldStatusHeld, "expiresAt": bson.M{"$lte": now}}
+	for k, v := range filter {
+		f[k] = v
+	}
+
+	var hold models.SeatHold
+	err := h.collection.FindOneAndUpdate(ctx, f,
+		bson.M{"$set": bson.M{"status": models.SeatHoldStatusExpired, "updatedAt": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&hold)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// Move occurrences koşuluna uyan gerçekleşmelerin ayırmalarını başka bir rezervasyona taşır
+func (h *Holds) Move(ctx context.Context, fromID, toID primitive.ObjectID, occurrences interface{}, shift time.Duration) error {
+	_, err := h.collection.UpdateMany(ctx, Filter(fromID, occurrences), MoveUpdate(toID, shift))
+	return err
+}
+
+// ReleaseAll silinen rezervasyonların (veya gerçekleşmelerin) açık ayırmalarını bırakılmış sayar.
+// Sayaçlar ayrıca silindiği için yerler burada bırakılmaz.
+func (h *Holds) ReleaseAll(ctx context.Context, reservationIDs []primitive.ObjectID, occurrences interface{}) error {
+	filter := bson.M{
+		"reservationId": bson.M{"$in": reservationIDs},
+		"status":        models.SeatHoldStatusHeld,
+	}
+	if occurrences != nil {
+		filter["occurrenceStart"] = occurrences
+	}
+	_, err := h.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{
+		"status":    models.SeatHoldStatusReleased,
+		"updatedAt": time.Now(),
+	}})
+	return err
+}
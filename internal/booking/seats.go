@@ -0,0 +1,172 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrCapacityExceeded = errors.New("yeterli boş yer yok")
+
+// Seats her gerçekleşme için dolu yer sayısını tutar. Sayaçlar koşullu $inc ile
+// güncellendiği için eşzamanlı istekler kapasiteyi aşamaz.
+type Seats struct {
+	collection *mongo.Collection
+}
+
+func NewSeats(db *mongo.Database) *Seats {
+	return &Seats{collection: db.Collection("booking_seats")}
+}
+
+// Reserve gerçekleşmede count yer ayırır; kapasite yetmiyorsa ErrCapacityExceeded döner
+func (s *Seats) Reserve(ctx context.Context, partnerID, reservationID primitive.ObjectID, occurrence time.Time, count, capacity int) error {
+	if count > capacity {
+		return ErrCapacityExceeded
+	}
+
+	// Sayaç yoksa eklenir. Varsa ve yer yetmiyorsa filtre eşleşmez, upsert aynı gerçekleşme için
+	// eklemeye çalışır ve duplicate key hatası alır. İlk ekleme yarışında kaybeden istek bir kez
+	// daha dener; o sırada sayaç vardır.
+	for attempt := 0; attempt < 2; attempt++ {
+		_, err := s.collection.UpdateOne(ctx,
+			bson.M{
+				"reservationId":   reservationID,
+				"occurrenceStart": occurrence,
+				"partnerId":       partnerID,
+				"seats":           bson.M{"$lte": capacity - count},
+			},
+			bson.M{"$inc": bson.M{"seats": count}},
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return ErrCapacityExceeded
+}
+
+// Release gerçekleşmede ayrılmış count yeri geri bırakır
+func (s *Seats) Release(ctx context.Context, reservationID primitive.ObjectID, occurrence time.Time, count int) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"reservationId": reservationID, "occurrenceStart": occurrence},
+		bson.M{"$inc": bson.M{"seats": -count}},
+	)
+	return err
+}
+
+// Move occurrences koşuluna uyan gerçekleşmelerin sayaçlarını başka bir rezervasyona,
+// başlangıçlarını shift kadar kaydırarak taşır. occurrences nil ise tüm sayaçlar taşınır.
+func (s *Seats) Move(ctx context.Context, fromID, toID primitive.ObjectID, occurrences interface{}, shift time.Duration) error {
+	if fromID != toID {
+		_, err := s.collection.UpdateMany(ctx, Filter(fromID, occurrences), MoveUpdate(toID, shift))
+		return err
+	}
+
+	// Aynı rezervasyon içinde kaydırırken sayaçlar birbirinin yerine geçebilir; tekil index
+	// çakışmaması için önce geçici bir kimliğe taşınır
+	tempID := primitive.NewObjectID()
+	if _, err := s.collection.UpdateMany(ctx, Filter(fromID, occurrences), MoveUpdate(tempID, shift)); err != nil {
+		return err
+	}
+	_, err := s.collection.UpdateMany(ctx, Filter(tempID, nil), MoveUpdate(toID, 0))
+	return err
+}
+
+// Clear occurrences koşuluna uyan gerçekleşmelerin sayaçlarını siler
+func (s *Seats) Clear(ctx context.Context, reservationIDs []primitive.ObjectID, occurrences interface{}) error {
+	filter := bson.M{"reservationId": bson.M{"$in": reservationIDs}}
+	if occurrences != nil {
+		filter["occurrenceStart"] = occurrences
+	}
+	_, err := s.collection.DeleteMany(ctx, filter)
+	return err
+}
+
+// Counts verilen rezervasyonların gerçekleşme başına dolu yer sayılarını getirir
+func (s *Seats) Counts(ctx context.Context, reservationIDs []primitive.ObjectID) (Counts, error) {
+	counts := Counts{}
+	if len(reservationIDs) == 0 {
+		return counts, nil
+	}
+
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"reservationId": bson.M{"$in": reservationIDs},
+		"seats":         bson.M{"$gt": 0},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ReservationID   primitive.ObjectID `bson:"reservationId"`
+			OccurrenceStart time.Time          `bson:"occurrenceStart"`
+			Seats           int                `bson:"seats"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		counts[countKey(doc.ReservationID, doc.OccurrenceStart)] = doc.Seats
+	}
+	return counts, cursor.Err()
+}
+
+// MaxBooked rezervasyonun occurrences koşuluna uyan gerçekleşmelerinden en dolusunun yer sayısını döndürür
+func (s *Seats) MaxBooked(ctx context.Context, reservationID primitive.ObjectID, occurrences interface{}) (int, error) {
+	var doc struct {
+		Seats int `bson:"seats"`
+	}
+	err := s.collection.FindOne(ctx, Filter(reservationID, occurrences),
+		options.FindOne().SetSort(bson.D{{Key: "seats", Value: -1}}),
+	).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return doc.Seats, err
+}
+
+// Counts gerçekleşme başına dolu yer sayılarıdır
+type Counts map[string]int
+
+// Booked gerçekleşmenin dolu yer sayısını döndürür
+func (c Counts) Booked(reservationID primitive.ObjectID, occurrence time.Time) int {
+	return c[countKey(reservationID, occurrence)]
+}
+
+// Remaining gerçekleşmede kalan yer sayısını döndürür; kapasite sonradan düşürüldüyse sıfırdır
+func (c Counts) Remaining(reservationID primitive.ObjectID, occurrence time.Time, capacity int) int {
+	return max(capacity-c.Booked(reservationID, occurrence), 0)
+}
+
+// Filter bir rezervasyonun occurrences koşuluna uyan gerçekleşmelerini seçer.
+// Sayaçlar ve müşteri rezervasyonları aynı alanları kullanır.
+func Filter(reservationID primitive.ObjectID, occurrences interface{}) bson.M {
+	filter := bson.M{"reservationId": reservationID}
+	if occurrences != nil {
+		filter["occurrenceStart"] = occurrences
+	}
+	return filter
+}
+
+// MoveUpdate kayıtları başka bir rezervasyona, başlangıçlarını shift kadar kaydırarak taşıyan güncellemedir
+func MoveUpdate(toID primitive.ObjectID, shift time.Duration) mongo.Pipeline {
+	return mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"reservationId":   toID,
+		"occurrenceStart": bson.M{"$add": bson.A{"$occurrenceStart", shift.Milliseconds()}},
+	}}}}
+}
+
+// Veritabanı zamanları milisaniye hassasiyetinde tutar
+func countKey(reservationID primitive.ObjectID, occurrence time.Time) string {
+	return reservationID.Hex() + "@" + strconv.FormatInt(occurrence.UnixMilli(), 10)
+}
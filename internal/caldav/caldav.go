@@ -0,0 +1,344 @@
+// Package caldav CalDAV (RFC 4791) ve WebDAV (RFC 4918) isteklerinin XML gövdelerini çözer
+// ve multistatus yanıtlarını yazar. Kaynakların ne olduğu handler'lara bırakılmıştır.
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// XML ad alanları
+const (
+	NamespaceDAV            = "DAV:"
+	NamespaceCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	NamespaceCalendarServer = "http://calendarserver.org/ns/"
+)
+
+// Methods Fiber'ın varsayılan metotlarına eklenmesi gereken WebDAV metotlarıdır
+var Methods = []string{"PROPFIND", "REPORT"}
+
+// Compliance OPTIONS yanıtındaki DAV başlığının değeridir
+const Compliance = "1, 3, calendar-access"
+
+// REPORT türleri
+const (
+	ReportCalendarQuery    = "calendar-query"
+	ReportCalendarMultiget = "calendar-multiget"
+)
+
+// Özellik adları
+var (
+	PropResourceType                  = xml.Name{Space: NamespaceDAV, Local: "resourcetype"}
+	PropDisplayName                   = xml.Name{Space: NamespaceDAV, Local: "displayname"}
+	PropGetETag                       = xml.Name{Space: NamespaceDAV, Local: "getetag"}
+	PropGetContentType                = xml.Name{Space: NamespaceDAV, Local: "getcontenttype"}
+	PropCurrentUserPrincipal          = xml.Name{Space: NamespaceDAV, Local: "current-user-principal"}
+	PropPrincipalURL                  = xml.Name{Space: NamespaceDAV, Local: "principal-URL"}
+	PropOwner                         = xml.Name{Space: NamespaceDAV, Local: "owner"}
+	PropCurrentUserPrivilegeSet       = xml.Name{Space: NamespaceDAV, Local: "current-user-privilege-set"}
+	PropSupportedReportSet            = xml.Name{Space: NamespaceDAV, Local: "supported-report-set"}
+	PropCalendarHomeSet               = xml.Name{Space: NamespaceCalDAV, Local: "calendar-home-set"}
+	PropCalendarData                  = xml.Name{Space: NamespaceCalDAV, Local: "calendar-data"}
+	PropSupportedCalendarComponentSet = xml.Name{Space: NamespaceCalDAV, Local: "supported-calendar-component-set"}
+	PropGetCTag                       = xml.Name{Space: NamespaceCalendarServer, Local: "getctag"}
+)
+
+// Kaynak tipleri ve yetkiler
+var (
+	Collection   = xml.Name{Space: NamespaceDAV, Local: "collection"}
+	Principal    = xml.Name{Space: NamespaceDAV, Local: "principal"}
+	CalendarType = xml.Name{Space: NamespaceCalDAV, Local: "calendar"}
+
+	PrivilegeRead         = xml.Name{Space: NamespaceDAV, Local: "read"}
+	PrivilegeWriteContent = xml.Name{Space: NamespaceDAV, Local: "write-content"}
+	PrivilegeBind         = xml.Name{Space: NamespaceDAV, Local: "bind"}
+	PrivilegeUnbind       = xml.Name{Space: NamespaceDAV, Local: "unbind"}
+)
+
+// ErrUnsupportedReport desteklenmeyen bir REPORT istendiğinde döner
+var ErrUnsupportedReport = errors.New("Bu rapor türü desteklenmiyor")
+
+// prefixes yanıtlarda kullanılan ad alanı önekleridir
+var prefixes = map[string]string{
+	NamespaceDAV:            "D",
+	NamespaceCalDAV:         "C",
+	NamespaceCalendarServer: "CS",
+}
+
+const timeRangeLayout = "20060102T150405Z"
+
+// Request bir PROPFIND veya REPORT isteğidir
+type Request struct {
+	Report  string // PROPFIND için boş
+	AllProp bool
+	Props   []xml.Name
+	// Hrefs calendar-multiget'te istenen kaynaklardır
+	Hrefs []string
+	// Component ve Start/End calendar-query'deki en içteki comp-filter'ın adı ve time-range'idir;
+	// sıfır zaman sınırsız demektir
+	Component  string
+	Start, End time.Time
+}
+
+type propBody struct {
+	Names []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+type compFilter struct {
+	Name      string       `xml:"name,attr"`
+	Filters   []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	TimeRange *struct {
+		Start string `xml:"start,attr"`
+		End   string `xml:"end,attr"`
+	} `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+}
+
+type requestBody struct {
+	XMLName  xml.Name
+	AllProp  *struct{} `xml:"DAV: allprop"`
+	PropName *struct{} `xml:"DAV: propname"`
+	Prop     *propBody `xml:"DAV: prop"`
+	Hrefs    []string  `xml:"DAV: href"`
+	Filter   *struct {
+		CompFilter compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+// ParsePropfind PROPFIND gövdesini çözer. Boş gövde tüm özellikleri ister.
+func ParsePropfind(body []byte) (*Request, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return &Request{AllProp: true}, nil
+	}
+
+	var b requestBody
+	if err := xml.Unmarshal(body, &b); err != nil {
+		return nil, errors.New("Geçersiz PROPFIND gövdesi")
+	}
+	if b.XMLName != (xml.Name{Space: NamespaceDAV, Local: "propfind"}) {
+		return nil, errors.New("Geçersiz PROPFIND gövdesi")
+	}
+	return newRequest(b), nil
+}
+
+// ParseReport calendar-query veya calendar-multiget gövdesini çözer
+func ParseReport(body []byte) (*Request, error) {
+	var b requestBody
+	if err := xml.Unmarshal(body, &b); err != nil {
+		return nil, errors.New("Geçersiz REPORT gövdesi")
+	}
+	if b.XMLName.Space != NamespaceCalDAV ||
+		(b.XMLName.Local != ReportCalendarQuery && b.XMLName.Local != ReportCalendarMultiget) {
+		return nil, ErrUnsupportedReport
+	}
+
+	req := newRequest(b)
+	req.Report = b.XMLName.Local
+	req.Hrefs = b.Hrefs
+	if b.Filter == nil {
+		return req, nil
+	}
+
+	// VCALENDAR > VEVENT > time-range; daha derindeki filtreler (prop-filter vb.) uygulanmaz
+	filter := b.Filter.CompFilter
+	for {
+		req.Component = filter.Name
+		if filter.TimeRange != nil {
+			var err error
+			if req.Start, err = parseTimeRange(filter.TimeRange.Start); err != nil {
+				return nil, err
+			}
+			if req.End, err = parseTimeRange(filter.TimeRange.End); err != nil {
+				return nil, err
+			}
+		}
+		if len(filter.Filters) == 0 {
+			break
+		}
+		filter = filter.Filters[0]
+	}
+	return req, nil
+}
+
+func newRequest(b requestBody) *Request {
+	req := &Request{AllProp: b.AllProp != nil || b.PropName != nil || b.Prop == nil}
+	if b.Prop != nil {
+		for _, n := range b.Prop.Names {
+			req.Props = append(req.Props, n.XMLName)
+		}
+	}
+	return req
+}
+
+func parseTimeRange(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(timeRangeLayout, value)
+	if err != nil {
+		return time.Time{}, errors.New("Geçersiz time-range değeri; UTC biçiminde olmalıdır (ör. 20260301T000000Z)")
+	}
+	return t, nil
+}
+
+// Property bir kaynağın özelliğidir. Value hazır XML içeriğidir; Text, Href ve Empty ile oluşturulur.
+type Property struct {
+	Name  xml.Name
+	Value string
+}
+
+// Select istenen özelliklerden kaynakta bulunanları ve bulunmayanları ayırır. Tüm özellikler
+// istendiğinde calendar-data döndürülmez (RFC 4791 9.6).
+func (r *Request) Select(available []Property) ([]Property, []xml.Name) {
+	if r.AllProp {
+		var found []Property
+		for _, p := range available {
+			if p.Name != PropCalendarData {
+				found = append(found, p)
+			}
+		}
+		return found, nil
+	}
+
+	var found []Property
+	var missing []xml.Name
+	for _, name := range r.Props {
+		ok := false
+		for _, p := range available {
+			if p.Name == name {
+				found = append(found, p)
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			missing = append(missing, name)
+		}
+	}
+	return found, missing
+}
+
+// Wants özelliğin açıkça istenip istenmediğini döndürür. Tüm özellikler istendiğinde
+// calendar-data gibi pahalı özellikler hazırlanmayabilir.
+func (r *Request) Wants(name xml.Name) bool {
+	for _, n := range r.Props {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Response multistatus yanıtındaki bir kaynaktır. Status doluysa (ör. bulunamayan kaynak)
+// özellikler yazılmaz.
+type Response struct {
+	Href    string
+	Found   []Property
+	Missing []xml.Name
+	Status  int
+}
+
+// Multistatus 207 yanıtının gövdesini yazar
+func Multistatus(responses []Response) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString(`<D:multistatus xmlns:D="DAV:" xmlns:C="` + NamespaceCalDAV + `" xmlns:CS="` + NamespaceCalendarServer + `">`)
+	for _, r := range responses {
+		b.WriteString("<D:response>")
+		b.WriteString(Href(r.Href))
+		if r.Status != 0 {
+			b.WriteString("<D:status>" + statusLine(r.Status) + "</D:status>")
+			b.WriteString("</D:response>")
+			continue
+		}
+		if len(r.Found) > 0 || len(r.Missing) == 0 {
+			b.WriteString("<D:propstat><D:prop>")
+			for _, p := range r.Found {
+				b.WriteString(element(p.Name, p.Value))
+			}
+			b.WriteString("</D:prop><D:status>" + statusLine(http.StatusOK) + "</D:status></D:propstat>")
+		}
+		if len(r.Missing) > 0 {
+			b.WriteString("<D:propstat><D:prop>")
+			for _, name := range r.Missing {
+				b.WriteString(element(name, ""))
+			}
+			b.WriteString("</D:prop><D:status>" + statusLine(http.StatusNotFound) + "</D:status></D:propstat>")
+		}
+		b.WriteString("</D:response>")
+	}
+	b.WriteString("</D:multistatus>")
+	return []byte(b.String())
+}
+
+// Text XML'e kaçışlanmış metin döndürür
+func Text(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Href bir D:href elemanı döndürür
+func Href(path string) string {
+	return "<D:href>" + Text(path) + "</D:href>"
+}
+
+// Empty içeriği olmayan elemanlar döndürür (ör. resourcetype için D:collection)
+func Empty(names ...xml.Name) string {
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(element(name, ""))
+	}
+	return b.String()
+}
+
+// Privileges current-user-privilege-set değerini döndürür
+func Privileges(names ...xml.Name) string {
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString("<D:privilege>" + element(name, "") + "</D:privilege>")
+	}
+	return b.String()
+}
+
+// Components supported-calendar-component-set değerini döndürür
+func Components(names ...string) string {
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(`<C:comp name="` + Text(name) + `"/>`)
+	}
+	return b.String()
+}
+
+// Reports supported-report-set değerini döndürür
+func Reports(names ...string) string {
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString("<D:supported-report><D:report>" + element(xml.Name{Space: NamespaceCalDAV, Local: name}, "") + "</D:report></D:supported-report>")
+	}
+	return b.String()
+}
+
+// element adı bilinen bir ad alanındaysa önekle, değilse kendi xmlns'iyle yazar
+func element(name xml.Name, value string) string {
+	tag, attr := name.Local, ""
+	if prefix, ok := prefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		attr = ` xmlns="` + Text(name.Space) + `"`
+	}
+	if value == "" {
+		return "<" + tag + attr + "/>"
+	}
+	return "<" + tag + attr + ">" + value + "</" + tag + ">"
+}
+
+func statusLine(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/booking"
+	"github.com/denizbarcak/planvia-partner-api/internal/events"
+	"github.com/denizbarcak/planvia-partner-api/internal/tombstones"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes uygulamanın ihtiyaç duyduğu index'leri oluşturur
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := map[string][]mongo.IndexModel{
+		"sessions": {
+			{Keys: bson.D{{Key: "partner_id", Value: 1}, {Key: "user_id", Value: 1}}},
+			// Süresi dolan oturumlar MongoDB tarafından silinir
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"rate_limits": {
+			// İstek sınırlayıcının süresi dolan sayaçları silinir
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"login_attempts": {
+			// Sayaçlar son başarısız denemeden (veya kilit bitiminden) bir süre sonra silinir
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"staff": {
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "partner_id", Value: 1}}},
+		},
+		"api_keys": {
+			{Keys: bson.D{{Key: "prefix", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "partner_id", Value: 1}}},
+		},
+		"calendar_feeds": {
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			// Her partner'ın tek bir abonelik adresi olur
+			{Keys: bson.D{{Key: "partner_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		"app_passwords": {
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "password_hash", Value: 1}}},
+		},
+		"password_resets": {
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "partner_id", Value: 1}}},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"locks": {
+			// Bırakılmadan kalan kilitler süresi dolduktan sonra silinir
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"reservations": {
+			// Bir gerçekleşme yalnızca bir kez ayrı kayıtla değiştirilebilir
+			{
+				Keys: bson.D{{Key: "seriesId", Value: 1}, {Key: "recurrenceId", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"seriesId": bson.M{"$exists": true}}),
+			},
+			// .ics'ten içe aktarılan her etkinlik (ve düzenlenmiş gerçekleşmesi) bir kez kaydedilir
+			{
+				Keys: bson.D{{Key: "partnerId", Value: 1}, {Key: "importUid", Value: 1}, {Key: "recurrenceId", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"importUid": bson.M{"$exists": true}}),
+			},
+			// CalDAV'daki kaynak adları partner'ın takviminde tekildir
+			{
+				Keys: bson.D{{Key: "partnerId", Value: 1}, {Key: "resourceName", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"resourceName": bson.M{"$exists": true}}),
+			},
+			// CalDAV ctag'i ve takvim aboneliği için partner'ın son güncellenen rezervasyonları
+			{Keys: bson.D{{Key: "partnerId", Value: 1}, {Key: "updatedAt", Value: -1}}},
+			// Artımlı senkronizasyon değişiklikleri sıra numarasıyla okur
+			{Keys: bson.D{{Key: "partnerId", Value: 1}, {Key: "changeSeq", Value: 1}}},
+			// Rezervasyon listesinin sıralama alanları; eşit değerler ID ile sıralanır
+			{Keys: bson.D{{Key: "partnerId", Value: 1}, {Key: "startDate", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "partnerId", Value: 1}, {Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "partnerId", Value: 1}, {Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+			// Pencerede listelenecek seriler son bitişlerine göre seçilir
+			{Keys: bson.D{{Key: "partnerId", Value: 1}, {Key: "recurrence.enabled", Value: 1}, {Key: "recurrence.lastEnd", Value: 1}}},
+		},
+		"booking_seats": {
+			// Her gerçekleşmenin tek bir dolu yer sayacı olur
+			{
+				Keys:    bson.D{{Key: "reservationId", Value: 1}, {Key: "occurrenceStart", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		"bookings": {
+			{Keys: bson.D{{Key: "reservationId", Value: 1}, {Key: "occurrenceStart", Value: 1}}},
+			{Keys: bson.D{{Key: "partnerId", Value: 1}}},
+			// Olayı yayınlanamayan onaylar sweeper tarafından yeniden yayınlanır
+			{
+				Keys:    bson.D{{Key: "updatedAt", Value: 1}},
+				Options: options.Index().SetPartialFilterExpression(bson.M{"pendingEventId": bson.M{"$exists": true}}),
+			},
+		},
+		"seat_holds": {
+			// Süresi dolan ayırmaların yerlerini sweeper bırakır; kayıtlar bir gün sonra silinir
+			{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(booking.HoldRetention.Seconds()))},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expiresAt", Value: 1}}},
+			{Keys: bson.D{{Key: "reservationId", Value: 1}, {Key: "occurrenceStart", Value: 1}}},
+		},
+		"events": {
+			// Olaylar partner başına sıra numarasıyla sayfalanır
+			{
+				Keys: bson.D{{Key: "partnerId", Value: 1}, {Key: "seq", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"seq": bson.M{"$exists": true}}),
+			},
+			// Partner'ın çekmediği eski olaylar saklama süresi sonunda silinir
+			{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(events.Retention.Seconds()))},
+		},
+		"reservation_tombstones": {
+			{Keys: bson.D{{Key: "partnerId", Value: 1}, {Key: "deletedAt", Value: 1}}},
+			{Keys: bson.D{{Key: "partnerId", Value: 1}, {Key: "seq", Value: 1}}},
+			// Senkronizasyon belirteçlerinin geçerlilik süresi dolan silme kayıtları silinir
+			{Keys: bson.D{{Key: "deletedAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(tombstones.Retention.Seconds()))},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
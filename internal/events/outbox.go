@@ -0,0 +1,88 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/sequence"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Retention olayların saklandığı süredir; daha eski olaylar TTL index'iyle silinir
+const Retention = 30 * 24 * time.Hour
+
+// Outbox partner olaylarını events koleksiyonunda saklar. Her olay partner başına artan bir
+// sıra numarası alır; istemciler olayları bu numarayla sayfalar.
+type Outbox struct {
+	collection *mongo.Collection
+	seq        *sequence.Counter
+}
+
+func NewOutbox(db *mongo.Database) *Outbox {
+	return &Outbox{collection: db.Collection("events"), seq: sequence.NewCounter(db, "events")}
+}
+
+// Publish partner için id ile yeni bir olay kaydeder. Aynı id ile yeniden yayınlamak
+// olayı ikinci kez eklemez; yarıda kalan yayınlar böylece güvenle tekrarlanabilir.
+func (o *Outbox) Publish(ctx context.Context, id, partnerID primitive.ObjectID, eventType string, data interface{}) error {
+	seq, err := o.seq.Next(ctx, partnerID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := o.seq.Done(context.Background(), partnerID, seq); err != nil {
+			log.Printf("Event sequence %d could not be completed for partner %s: %v", seq, partnerID.Hex(), err)
+		}
+	}()
+
+	event := &models.Event{
+		ID:        id,
+		PartnerID: partnerID,
+		Seq:       seq,
+		Type:      eventType,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+	if _, err := o.collection.InsertOne(ctx, event); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+// List partner'ın after numarasından sonraki olaylarını sırayla getirir. after sıfırsa en
+// eski olaydan başlanır; eventType boş değilse yalnızca o tür döner. Önündeki bir olay henüz
+// yazılmakta olan olaylar dönmez, bir sonraki istekte gelir.
+func (o *Outbox) List(ctx context.Context, partnerID primitive.ObjectID, after int64, eventType string, limit int64) ([]models.Event, error) {
+	visible, err := o.seq.Visible(ctx, partnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []models.Event{}
+	if visible <= after {
+		return events, nil
+	}
+	filter := bson.M{"partnerId": partnerID, "seq": bson.M{"$gt": after, "$lte": visible}}
+	if eventType != "" {
+		filter["type"] = eventType
+	}
+
+	cursor, err := o.collection.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/validation"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type APIKeyHandler struct {
+	keys     *auth.APIKeyStore
+	validate *validator.Validate
+}
+
+func NewAPIKeyHandler(keys *auth.APIKeyStore) *APIKeyHandler {
+	return &APIKeyHandler{
+		keys:     keys,
+		validate: validation.New(),
+	}
+}
+
+// ListAPIKeys partner'ın API key'lerini getirir; gizli değerler döndürülmez
+func (h *APIKeyHandler) ListAPIKeys(c *fiber.Ctx) error {
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	keys, err := h.keys.List(c.Context(), partnerObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "API key'ler getirilemedi",
+		})
+	}
+
+	return c.JSON(keys)
+}
+
+// CreateAPIKey yeni bir API key oluşturur. Tam key yalnızca bu yanıtta görünür.
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	userObjID, _ := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+
+	var req models.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Son kullanma tarihi gelecekte olmalıdır",
+		})
+	}
+
+	key := models.APIKey{
+		PartnerID: partnerObjID,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		CreatedBy: userObjID,
+		ExpiresAt: req.ExpiresAt,
+	}
+	rawKey, err := h.keys.Create(c.Context(), &key)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "API key oluşturulamadı",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "API key oluşturuldu, bu değeri güvenli bir yerde saklayın",
+		"key":     rawKey,
+		"apiKey":  key,
+	})
+}
+
+// RevokeAPIKey bir API key'i iptal eder
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	keyObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz API key ID",
+		})
+	}
+
+	revoked, err := h.keys.Revoke(c.Context(), partnerObjID, keyObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "API key iptal edilemedi",
+		})
+	}
+	if !revoked {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "API key bulunamadı veya zaten iptal edilmiş",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "API key iptal edildi",
+	})
+}
\ No newline at end of file
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListAppPasswords returns the current user's app passwords without the passwords themselves
+func (h *PartnerHandler) ListAppPasswords(c *fiber.Ctx) error {
+	userObjID, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz kullanıcı ID",
+		})
+	}
+
+	passwords, err := h.appPwds.List(c.Context(), userObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Uygulama şifreleri getirilemedi",
+		})
+	}
+
+	return c.JSON(passwords)
+}
+
+// CreateAppPassword creates an app password for CalDAV clients. The password is
+// only shown in this response.
+func (h *PartnerHandler) CreateAppPassword(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	// API key'lerin kullanıcısı yoktur; uygulama şifresi yalnızca kullanıcı hesapları için oluşturulur
+	userObjID, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz kullanıcı ID",
+		})
+	}
+
+	var req models.CreateAppPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	password := models.AppPassword{
+		PartnerID: partnerObjID,
+		UserID:    userObjID,
+		Name:      req.Name,
+	}
+	raw, err := h.appPwds.Create(ctx, &password)
+	if err == auth.ErrAppPasswordLimit {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "En fazla 25 uygulama şifresi oluşturulabilir; kullanmadıklarınızı silin",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Uygulama şifresi oluşturulamadı",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":     "Uygulama şifresi oluşturuldu, bu değeri takvim uygulamanıza girin",
+		"password":    raw,
+		"appPassword": password,
+	})
+}
+
+// RevokeAppPassword deletes one of the current user's app passwords
+func (h *PartnerHandler) RevokeAppPassword(c *fiber.Ctx) error {
+	userObjID, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz kullanıcı ID",
+		})
+	}
+	passwordObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz uygulama şifresi ID",
+		})
+	}
+
+	revoked, err := h.appPwds.Revoke(c.Context(), userObjID, passwordObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Uygulama şifresi silinemedi",
+		})
+	}
+	if !revoked {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Uygulama şifresi bulunamadı",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Uygulama şifresi silindi",
+	})
+}
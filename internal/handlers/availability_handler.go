@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/schedule"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxAvailabilityWindow herkese açık müsaitlik aramasında sorgulanabilecek en uzun aralıktır
+const maxAvailabilityWindow = 62 * 24 * time.Hour
+
+// availabilitySlot herkese açık müsaitlik yanıtındaki rezervasyona açık bir gerçekleşmedir.
+// Partner'ın iç verileri (müşteriler, kapasite ayarları) döndürülmez.
+type availabilitySlot struct {
+	ReservationID  primitive.ObjectID `json:"reservationId"`
+	Name           string             `json:"name"`
+	StartDate      time.Time          `json:"startDate"`
+	EndDate        time.Time          `json:"endDate"`
+	IsAllDay       bool               `json:"isAllDay"`
+	StartDay       string             `json:"startDay,omitempty"`
+	EndDay         string             `json:"endDay,omitempty"`
+	RemainingSeats int                `json:"remainingSeats"`
+}
+
+// GetAvailability bir partner'ın verilen aralıkta partySize kişilik grup için boş yeri olan
+// gerçekleşmelerini getirir. Kimlik doğrulaması gerektirmez; yalnızca aktif partner'lar aranabilir.
+func (h *ReservationHandler) GetAvailability(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Params("partnerId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	start, err := time.Parse(time.RFC3339, c.Query("start"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz başlangıç tarihi formatı",
+		})
+	}
+	end, err := time.Parse(time.RFC3339, c.Query("end"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz bitiş tarihi formatı",
+		})
+	}
+	if !end.After(start) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Bitiş tarihi başlangıç tarihinden sonra olmalıdır",
+		})
+	}
+	if end.Sub(start) > maxAvailabilityWindow {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Aralık en fazla 62 gün olabilir",
+		})
+	}
+
+	partySize := 1
+	if s := c.Query("partySize"); s != "" {
+		if partySize, err = strconv.Atoi(s); err != nil || partySize < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Kişi sayısı en az 1 olmalıdır",
+			})
+		}
+	}
+
+	var partner models.Partner
+	err = h.db.Collection("partners").FindOne(ctx,
+		bson.M{"_id": partnerObjID},
+		options.FindOne().SetProjection(partnerSettingsProjection),
+	).Decode(&partner)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+	if err == mongo.ErrNoDocuments || partner.AccountStatus() != models.PartnerStatusActive {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Partner bulunamadı",
+		})
+	}
+
+	// Başlamış gerçekleşmelere yer ayrılamaz
+	if now := time.Now(); start.Before(now) {
+		start = now
+	}
+
+	slots := []availabilitySlot{}
+	if end.After(start) {
+		cursor, err := h.db.Collection("reservations").Find(ctx, overlapFilter(partnerObjID, start, end))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Rezervasyonlar getirilemedi",
+			})
+		}
+		defer cursor.Close(ctx)
+
+		var reservations []models.Reservation
+		if err := cursor.All(ctx, &reservations); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Rezervasyonlar parse edilemedi",
+			})
+		}
+
+		ids := make([]primitive.ObjectID, len(reservations))
+		for i, reservation := range reservations {
+			ids[i] = reservation.ID
+		}
+		counts, err := h.seats.Counts(ctx, ids)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Dolu yer sayıları getirilemedi",
+			})
+		}
+
+		sched := schedule.New(&partner)
+		loc := sched.Location()
+		for _, reservation := range reservations {
+			if reservation.Capacity < partySize {
+				continue
+			}
+			for _, o := range sched.Expand(reservation, start, end) {
+				if o.Start.Before(start) {
+					continue
+				}
+				remaining := counts.Remaining(reservation.ID, o.Start, reservation.Capacity)
+				if remaining < partySize {
+					continue
+				}
+				slot := availabilitySlot{
+					ReservationID:  reservation.ID,
+					Name:           reservation.Name,
+					StartDate:      o.Start,
+					EndDate:        o.End,
+					IsAllDay:       reservation.IsAllDay,
+					RemainingSeats: remaining,
+				}
+				if reservation.IsAllDay {
+					rl := reservation.Location(loc)
+					slot.StartDay = o.Start.In(rl).Format(dayLayout)
+					slot.EndDay = o.End.Add(-time.Nanosecond).In(rl).Format(dayLayout)
+				}
+				slots = append(slots, slot)
+			}
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		return slots[i].StartDate.Before(slots[j].StartDate)
+	})
+
+	return c.JSON(fiber.Map{
+		"partnerId": partnerObjID,
+		"timeZone":  partner.Location().String(),
+		"partySize": partySize,
+		"slots":     slots,
+	})
+}
@@ -0,0 +1,620 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/booking"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/recurrence"
+	"github.com/denizbarcak/planvia-partner-api/internal/validation"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ListBookings rezervasyonun müşteri rezervasyonlarını getirir. occurrence ile tek bir
+// gerçekleşme, status ile durum seçilebilir.
+func (h *ReservationHandler) ListBookings(c *fiber.Ctx) error {
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	reservationObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz rezervasyon ID",
+		})
+	}
+
+	filter := bson.M{"partnerId": partnerObjID, "reservationId": reservationObjID}
+	if s := c.Query("occurrence"); s != "" {
+		occurrence, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz gerçekleşme tarihi formatı",
+			})
+		}
+		filter["occurrenceStart"] = occurrence
+	}
+	if status := c.Query("status"); status != "" {
+		switch status {
+		case models.BookingStatusConfirmed, models.BookingStatusWaitlisted, models.BookingStatusCancelled:
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçerli bir durum seçiniz (confirmed, waitlisted, cancelled)",
+			})
+		}
+		filter["status"] = status
+	}
+
+	cursor, err := h.db.Collection("bookings").Find(context.Background(), filter,
+		options.Find().SetSort(bson.D{{Key: "occurrenceStart", Value: 1}, {Key: "createdAt", Value: 1}}),
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Müşteri rezervasyonları getirilemedi",
+		})
+	}
+	defer cursor.Close(context.Background())
+
+	bookings := []models.Booking{}
+	if err := cursor.All(context.Background(), &bookings); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Müşteri rezervasyonları parse edilemedi",
+		})
+	}
+
+	return c.JSON(bookings)
+}
+
+// CreateBooking rezervasyonun (tekrar ediyorsa bir gerçekleşmesinin) kapasitesinden yer ayırır.
+// Yer kalmadıysa ve istenmişse müşteri bekleme listesine eklenir.
+func (h *ReservationHandler) CreateBooking(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	reservationObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz rezervasyon ID",
+		})
+	}
+
+	var req models.CreateBookingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	req.CustomerName = strings.TrimSpace(req.CustomerName)
+	req.CustomerEmail = strings.ToLower(strings.TrimSpace(req.CustomerEmail))
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+	if req.CustomerPhone != "" {
+		req.CustomerPhone, _ = validation.NormalizePhone(req.CustomerPhone)
+	}
+
+	reservation, occurrence, err := h.bookableOccurrence(ctx, c, partnerObjID, reservationObjID, req.OccurrenceStart)
+	if reservation == nil {
+		return err
+	}
+
+	// Kapasiteden büyük gruplar bekleme listesinden de hiçbir zaman onaylanamaz
+	if req.PartySize > reservation.Capacity {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Kişi sayısı rezervasyonun kapasitesini aşıyor",
+			"code":  "CAPACITY_EXCEEDED",
+		})
+	}
+
+	status := models.BookingStatusConfirmed
+	if err := h.reserveSeats(ctx, reservation, occurrence, req.PartySize); err != nil {
+		if err != booking.ErrCapacityExceeded {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Veritabanı hatası",
+			})
+		}
+		if !req.Waitlist {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Bu rezervasyonda yeterli boş yer yok",
+				"code":  "CAPACITY_EXCEEDED",
+			})
+		}
+		status = models.BookingStatusWaitlisted
+	}
+
+	now := time.Now()
+	b := models.Booking{
+		ID:              primitive.NewObjectID(),
+		PartnerID:       partnerObjID,
+		ReservationID:   reservation.ID,
+		OccurrenceStart: occurrence,
+		CustomerName:    req.CustomerName,
+		CustomerEmail:   req.CustomerEmail,
+		CustomerPhone:   req.CustomerPhone,
+		PartySize:       req.PartySize,
+		Status:          status,
+		Notes:           req.Notes,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if _, err := h.db.Collection("bookings").InsertOne(ctx, b); err != nil {
+		if status == models.BookingStatusConfirmed {
+			_ = h.seats.Release(ctx, reservation.ID, occurrence, req.PartySize)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Müşteri rezervasyonu kaydedilemedi",
+		})
+	}
+
+	// Kayıt sırasında yer açılmış olabilir; bekleme listesi hemen işlenir
+	if status == models.BookingStatusWaitlisted {
+		promoted, err := h.promoteWaitlist(ctx, reservation.ID, occurrence)
+		if err != nil {
+			log.Printf("Waitlist promotion failed for reservation %s: %v", reservation.ID.Hex(), err)
+		}
+		for _, p := range promoted {
+			if p.ID == b.ID {
+				b = p
+			}
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(b)
+}
+
+// CancelBooking onaylı veya bekleme listesindeki bir müşteri rezervasyonunu iptal eder.
+// Onaylı kaydın yerleri doğrudan bekleme listesindekilere devredilir, kalanı serbest bırakılır.
+func (h *ReservationHandler) CancelBooking(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	reservationObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz rezervasyon ID",
+		})
+	}
+
+	bookingObjID, err := primitive.ObjectIDFromHex(c.Params("bookingId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz müşteri rezervasyonu ID",
+		})
+	}
+
+	// Durum koşulu aynı kaydın iki kez iptal edilip yerlerin iki kez bırakılmasını önler.
+	// Önceki durum yerlerin bırakılıp bırakılmayacağını belirler.
+	now := time.Now()
+	var b models.Booking
+	err = h.db.Collection("bookings").FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":           bookingObjID,
+			"partnerId":     partnerObjID,
+			"reservationId": reservationObjID,
+			"status":        bson.M{"$in": bson.A{models.BookingStatusConfirmed, models.BookingStatusWaitlisted}},
+		},
+		bson.M{"$set": bson.M{
+			"status":      models.BookingStatusCancelled,
+			"cancelledAt": now,
+			"updatedAt":   now,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&b)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "İptal edilebilecek müşteri rezervasyonu bulunamadı",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Müşteri rezervasyonu iptal edilemedi",
+		})
+	}
+
+	wasConfirmed := b.Status == models.BookingStatusConfirmed
+	b.Status = models.BookingStatusCancelled
+	b.CancelledAt = &now
+	b.UpdatedAt = now
+	if !wasConfirmed {
+		return c.JSON(b)
+	}
+
+	free, err := h.transferSeats(ctx, b)
+	if err != nil {
+		log.Printf("Seats could not be transferred to the waitlist for reservation %s: %v", b.ReservationID.Hex(), err)
+	}
+	if free == 0 {
+		return c.JSON(b)
+	}
+
+	if err := h.seats.Release(ctx, b.ReservationID, b.OccurrenceStart, free); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Yerler serbest bırakılamadı",
+		})
+	}
+
+	// Kalan boş yerlere (ör. daha önce artırılan kapasiteye) sığan büyük gruplar da onaylanır
+	if _, err := h.promoteWaitlist(ctx, b.ReservationID, b.OccurrenceStart); err != nil {
+		log.Printf("Waitlist promotion failed for reservation %s: %v", b.ReservationID.Hex(), err)
+	}
+
+	return c.JSON(b)
+}
+
+// bookableOccurrence müşteri rezervasyonu yapılacak rezervasyonu ve gerçekleşmeyi bulur.
+// Tekrar eden rezervasyonlarda gerçekleşme zorunludur, iptal edilmiş veya ayrı kayıtla
+// değiştirilmiş olamaz. Hata durumunda yanıt yazılmıştır ve rezervasyon nil döner.
+func (h *ReservationHandler) bookableOccurrence(ctx context.Context, c *fiber.Ctx, partnerObjID, reservationObjID primitive.ObjectID, requested *time.Time) (*models.Reservation, time.Time, error) {
+	var reservation models.Reservation
+	err := h.db.Collection("reservations").FindOne(ctx, bson.M{
+		"_id":       reservationObjID,
+		"partnerId": partnerObjID,
+	}).Decode(&reservation)
+	if err == mongo.ErrNoDocuments {
+		return nil, time.Time{}, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Rezervasyon bulunamadı veya bu partner'a ait değil",
+		})
+	}
+	if err != nil {
+		return nil, time.Time{}, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyon getirilemedi",
+		})
+	}
+
+	if !reservation.Recurrence.Enabled {
+		if requested != nil && !requested.Equal(reservation.StartDate) {
+			return nil, time.Time{}, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Gerçekleşme tarihi rezervasyonun başlangıç tarihiyle aynı olmalıdır",
+			})
+		}
+		return &reservation, reservation.StartDate, nil
+	}
+
+	if requested == nil {
+		return nil, time.Time{}, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Tekrar eden rezervasyonlarda gerçekleşme tarihi (occurrenceStart) zorunludur",
+		})
+	}
+
+	sched, err := h.partnerSchedule(ctx, partnerObjID)
+	if err != nil {
+		return nil, time.Time{}, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+
+	occurrence := *requested
+	if _, ok := sched.Occurrence(reservation, occurrence); !ok {
+		return nil, time.Time{}, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Serinin bu tarihte rezervasyona açık bir gerçekleşmesi yok",
+		})
+	}
+
+	return &reservation, occurrence, nil
+}
+
+// moveBookings occurrences koşuluna uyan gerçekleşmelerin müşteri rezervasyonlarını, yer
+// ayırmalarını ve dolu yer sayaçlarını başka bir rezervasyona, başlangıçlarını shift kadar kaydırarak taşır
+func (h *ReservationHandler) moveBookings(ctx context.Context, fromID, toID primitive.ObjectID, occurrences interface{}, shift time.Duration) error {
+	if fromID == toID && shift == 0 {
+		return nil
+	}
+	if err := h.seats.Move(ctx, fromID, toID, occurrences, shift); err != nil {
+		return err
+	}
+	if err := h.holds.Move(ctx, fromID, toID, occurrences, shift); err != nil {
+		return err
+	}
+	_, err := h.db.Collection("bookings").UpdateMany(ctx, booking.Filter(fromID, occurrences), booking.MoveUpdate(toID, shift))
+	return err
+}
+
+// promoteWaitlist occurrences koşuluna uyan gerçekleşmelerin bekleme listesini kayıt sırasıyla
+// işler: boş yerlere sığan ilk kayıtlar onaylanır, her onay için booking.promoted olayı yayınlanır.
+// Sığmayan büyük gruplar sıralarını korur, arkalarındaki küçük gruplar onaylanabilir.
+func (h *ReservationHandler) promoteWaitlist(ctx context.Context, reservationObjID primitive.ObjectID, occurrences interface{}) ([]models.Booking, error) {
+	var reservation models.Reservation
+	err := h.db.Collection("reservations").FindOne(ctx, bson.M{"_id": reservationObjID}).Decode(&reservation)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	filter := booking.Filter(reservationObjID, occurrences)
+	filter["status"] = models.BookingStatusWaitlisted
+	cursor, err := h.db.Collection("bookings").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "occurrenceStart", Value: 1}, {Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var waiting []models.Booking
+	if err := cursor.All(ctx, &waiting); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	promoted := []models.Booking{}
+	for _, w := range waiting {
+		// Başlamış gerçekleşmelerin bekleme listesi işlenmez
+		if w.OccurrenceStart.Before(now) || w.PartySize > reservation.Capacity {
+			continue
+		}
+
+		err := h.seats.Reserve(ctx, reservation.PartnerID, reservation.ID, w.OccurrenceStart, w.PartySize, reservation.Capacity)
+		if err == booking.ErrCapacityExceeded {
+			continue
+		}
+		if err != nil {
+			return promoted, err
+		}
+
+		// Kayıt bu arada iptal edildiyse veya başka bir istek onayladıysa yerler geri bırakılır
+		b, err := h.confirmWaitlisted(ctx, w.ID, now)
+		if err != nil || b == nil {
+			if releaseErr := h.seats.Release(ctx, reservation.ID, w.OccurrenceStart, w.PartySize); releaseErr != nil {
+				return promoted, releaseErr
+			}
+			if err == nil {
+				continue
+			}
+			return promoted, err
+		}
+		promoted = append(promoted, *b)
+	}
+
+	return promoted, nil
+}
+
+// transferSeats iptal edilen onaylı kaydın yerlerini bırakmadan, sırayla sığan bekleme listesi
+// kayıtlarına devreder; böylece araya giren yeni bir istek bu yerleri kapamaz. Devredilemeyen
+// yer sayısını döndürür; bunları çağıran bırakır.
+func (h *ReservationHandler) transferSeats(ctx context.Context, cancelled models.Booking) (int, error) {
+	free := cancelled.PartySize
+	now := time.Now()
+	if cancelled.OccurrenceStart.Before(now) {
+		return free, nil
+	}
+
+	filter := booking.Filter(cancelled.ReservationID, cancelled.OccurrenceStart)
+	filter["status"] = models.BookingStatusWaitlisted
+	cursor, err := h.db.Collection("bookings").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return free, err
+	}
+	var waiting []models.Booking
+	if err := cursor.All(ctx, &waiting); err != nil {
+		return free, err
+	}
+
+	for _, w := range waiting {
+		if free == 0 {
+			break
+		}
+		if w.PartySize > free {
+			continue
+		}
+		b, err := h.confirmWaitlisted(ctx, w.ID, now)
+		if err != nil {
+			return free, err
+		}
+		if b != nil {
+			free -= w.PartySize
+		}
+	}
+	return free, nil
+}
+
+// confirmWaitlisted yerleri ayrılmış bekleme listesi kaydını onaylar. booking.promoted olayının
+// ID'si onayla aynı yazmada kayda işlenir; olay yayınlanamazsa sweeper yeniden yayınlar. Kayıt
+// bu arada iptal edildiyse veya başka bir istek onayladıysa nil döner.
+func (h *ReservationHandler) confirmWaitlisted(ctx context.Context, bookingObjID primitive.ObjectID, now time.Time) (*models.Booking, error) {
+	var b models.Booking
+	err := h.db.Collection("bookings").FindOneAndUpdate(ctx,
+		bson.M{"_id": bookingObjID, "status": models.BookingStatusWaitlisted},
+		bson.M{"$set": bson.M{
+			"status":         models.BookingStatusConfirmed,
+			"promotedAt":     now,
+			"updatedAt":      now,
+			"pendingEventId": primitive.NewObjectID(),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&b)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h.publishPromoted(ctx, &b)
+	return &b, nil
+}
+
+// publishPromoted onaylanan kaydın bekleyen booking.promoted olayını yayınlar. Hata yalnızca
+// loglanır; olay kayıtta beklemeye devam eder ve sweeper tarafından yeniden yayınlanır.
+func (h *ReservationHandler) publishPromoted(ctx context.Context, b *models.Booking) {
+	eventID := *b.PendingEventID
+	b.PendingEventID = nil
+	if err := h.events.Publish(ctx, eventID, b.PartnerID, models.EventBookingPromoted, b); err != nil {
+		log.Printf("Booking promoted event could not be published for booking %s: %v", b.ID.Hex(), err)
+		return
+	}
+	if _, err := h.db.Collection("bookings").UpdateOne(ctx,
+		bson.M{"_id": b.ID, "pendingEventId": eventID},
+		bson.M{"$unset": bson.M{"pendingEventId": ""}},
+	); err != nil {
+		log.Printf("Pending event could not be cleared for booking %s: %v", b.ID.Hex(), err)
+	}
+}
+
+// pendingEventDelay yayınlanmakta olan olayların sweeper tarafından ikinci kez ele alınmaması
+// için beklenen süredir
+const pendingEventDelay = 30 * time.Second
+
+// publishPendingEvents onaylandığı halde olayı yayınlanamamış kayıtların olaylarını yeniden yayınlar
+func (h *ReservationHandler) publishPendingEvents(ctx context.Context) error {
+	cursor, err := h.db.Collection("bookings").Find(ctx, bson.M{
+		"pendingEventId": bson.M{"$exists": true},
+		"updatedAt":      bson.M{"$lt": time.Now().Add(-pendingEventDelay)},
+	})
+	if err != nil {
+		return err
+	}
+	var pending []models.Booking
+	if err := cursor.All(ctx, &pending); err != nil {
+		return err
+	}
+	for i := range pending {
+		h.publishPromoted(ctx, &pending[i])
+	}
+	return nil
+}
+
+// promoteAfterCapacityChange kapasitesi artırılan rezervasyonun bekleme listesini işler.
+// Rezervasyon kaydedilmiş olduğundan hata yalnızca loglanır.
+func (h *ReservationHandler) promoteAfterCapacityChange(ctx context.Context, reservationObjID primitive.ObjectID, oldCapacity, newCapacity int) {
+	if newCapacity <= oldCapacity {
+		return
+	}
+	if _, err := h.promoteWaitlist(ctx, reservationObjID, nil); err != nil {
+		log.Printf("Waitlist promotion failed for reservation %s: %v", reservationObjID.Hex(), err)
+	}
+}
+
+// cancelBookings silinen rezervasyonların (veya gerçekleşmelerin) onaylı ve bekleme
+// listesindeki müşteri rezervasyonlarını iptal eder, açık yer ayırmalarını bırakır ve
+// sayaçlarını siler
+func (h *ReservationHandler) cancelBookings(ctx context.Context, reservationIDs []primitive.ObjectID, occurrences interface{}) error {
+	filter := bson.M{
+		"reservationId": bson.M{"$in": reservationIDs},
+		"status":        bson.M{"$in": bson.A{models.BookingStatusConfirmed, models.BookingStatusWaitlisted}},
+	}
+	if occurrences != nil {
+		filter["occurrenceStart"] = occurrences
+	}
+
+	now := time.Now()
+	if _, err := h.db.Collection("bookings").UpdateMany(ctx, filter, bson.M{"$set": bson.M{
+		"status":      models.BookingStatusCancelled,
+		"cancelledAt": now,
+		"updatedAt":   now,
+	}}); err != nil {
+		return err
+	}
+	if err := h.holds.ReleaseAll(ctx, reservationIDs, occurrences); err != nil {
+		return err
+	}
+	return h.seats.Clear(ctx, reservationIDs, occurrences)
+}
+
+// checkBookedCapacity yeni kapasitenin from'dan (geçmişteyse şimdiden) itibaren başlayan
+// gerçekleşmelerin dolu yerlerinden az olmadığını kontrol eder. Az ise 409 hatası döner.
+func (h *ReservationHandler) checkBookedCapacity(ctx context.Context, reservationObjID primitive.ObjectID, from time.Time, capacity int) error {
+	if now := time.Now(); from.Before(now) {
+		from = now
+	}
+	booked, err := h.seats.MaxBooked(ctx, reservationObjID, bson.M{"$gte": from})
+	if err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Müşteri rezervasyonları getirilemedi",
+		})
+	}
+	if capacity < booked {
+		return failWrite(fiber.StatusConflict, fiber.Map{
+			"error":  "Kapasite dolu yer sayısından az olamaz",
+			"code":   "CAPACITY_BELOW_BOOKED",
+			"booked": booked,
+		})
+	}
+	return nil
+}
+
+// cancelOrphanedBookings güncellenen rezervasyonun artık var olmayan gelecek gerçekleşmelerindeki
+// müşteri rezervasyonlarını iptal eder ve yerlerini bırakır. Tekrar deseni değiştiğinde başlangıç
+// kaydırması bu gerçekleşmeleri yeni bir gerçekleşmeye taşıyamaz.
+func (h *ReservationHandler) cancelOrphanedBookings(ctx context.Context, reservation models.Reservation, loc *time.Location) error {
+	upcoming := bson.M{"$gte": time.Now()}
+	seatStarts, err := h.db.Collection("booking_seats").Distinct(ctx, "occurrenceStart",
+		bson.M{"reservationId": reservation.ID, "occurrenceStart": upcoming, "seats": bson.M{"$gt": 0}},
+	)
+	if err != nil {
+		return err
+	}
+	bookingStarts, err := h.db.Collection("bookings").Distinct(ctx, "occurrenceStart", bson.M{
+		"reservationId":   reservation.ID,
+		"occurrenceStart": upcoming,
+		"status":          bson.M{"$in": bson.A{models.BookingStatusConfirmed, models.BookingStatusWaitlisted}},
+	})
+	if err != nil {
+		return err
+	}
+
+	seen := map[int64]bool{}
+	var orphaned bson.A
+	for _, v := range append(seatStarts, bookingStarts...) {
+		dt, ok := v.(primitive.DateTime)
+		if !ok || seen[int64(dt)] {
+			continue
+		}
+		seen[int64(dt)] = true
+		t := dt.Time()
+		if _, ok := recurrence.At(reservation, loc, t); ok && !recurrence.IsException(reservation, loc, t) {
+			continue
+		}
+		orphaned = append(orphaned, t)
+	}
+	if len(orphaned) == 0 {
+		return nil
+	}
+	return h.cancelBookings(ctx, []primitive.ObjectID{reservation.ID}, bson.M{"$in": orphaned})
+}
+
+// overrideIDs serinin ayrı kayıtla değiştirilmiş gerçekleşmelerinden recurrenceIDs koşuluna
+// uyanların ID'lerini getirir
+func (h *ReservationHandler) overrideIDs(ctx context.Context, seriesObjID primitive.ObjectID, recurrenceIDs interface{}) ([]primitive.ObjectID, error) {
+	filter := bson.M{"seriesId": seriesObjID}
+	if recurrenceIDs != nil {
+		filter["recurrenceId"] = recurrenceIDs
+	}
+
+	values, err := h.db.Collection("reservations").Distinct(ctx, "_id", filter)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
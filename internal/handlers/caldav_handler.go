@@ -0,0 +1,891 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/caldav"
+	"github.com/denizbarcak/planvia-partner-api/internal/ical"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/recurrence"
+	"github.com/denizbarcak/planvia-partner-api/internal/schedule"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CalDAV adresleri. Kullanıcının principal'ı ve takvim evi köktedir; partner'ın tüm
+// rezervasyonları tek bir takvimdir.
+const (
+	davRoot     = "/caldav/"
+	davCalendar = "/caldav/reservations/"
+)
+
+// davObjectContentType CalDAV'daki takvim nesnelerinin MIME tipidir
+const davObjectContentType = "text/calendar; charset=utf-8; component=vevent"
+
+// davObject CalDAV'daki bir takvim nesnesidir. Tekrar eden seri, ayrı düzenlenmiş
+// gerçekleşmeleriyle birlikte tek bir .ics kaynağıdır.
+type davObject struct {
+	name      string
+	series    models.Reservation
+	overrides []models.Reservation
+	data      []byte
+	etag      string
+}
+
+func (o *davObject) href() string {
+	return davCalendar + url.PathEscape(o.name)
+}
+
+func (o *davObject) props() []caldav.Property {
+	return []caldav.Property{
+		{Name: caldav.PropResourceType},
+		{Name: caldav.PropGetETag, Value: caldav.Text(o.etag)},
+		{Name: caldav.PropGetContentType, Value: caldav.Text(davObjectContentType)},
+		{Name: caldav.PropCalendarData, Value: caldav.Text(string(o.data))},
+	}
+}
+
+// WellKnownCalDAV takvim uygulamalarının otomatik keşif adresini CalDAV köküne yönlendirir
+func (h *ReservationHandler) WellKnownCalDAV(c *fiber.Ctx) error {
+	return c.Redirect(davRoot, fiber.StatusMovedPermanently)
+}
+
+// CalDAVOptions desteklenen metotları ve CalDAV uyumluluğunu bildirir. Bazı takvim uygulamaları
+// bu isteği kimlik bilgisi göndermeden yapar.
+func (h *ReservationHandler) CalDAVOptions(c *fiber.Ctx) error {
+	c.Set("DAV", caldav.Compliance)
+	c.Set(fiber.HeaderAllow, "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// PropfindCalDAVRoot kullanıcının principal'ını ve takvim evini döndürür; Depth 1 ile
+// rezervasyon takvimi de listelenir
+func (h *ReservationHandler) PropfindCalDAVRoot(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	req, err := caldav.ParsePropfind(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	partner, err := h.partnerSettings(ctx, partnerObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+
+	responses := []caldav.Response{davResponse(req, davRoot, []caldav.Property{
+		{Name: caldav.PropResourceType, Value: caldav.Empty(caldav.Collection, caldav.Principal)},
+		{Name: caldav.PropDisplayName, Value: caldav.Text(davDisplayName(partner))},
+		{Name: caldav.PropCurrentUserPrincipal, Value: caldav.Href(davRoot)},
+		{Name: caldav.PropPrincipalURL, Value: caldav.Href(davRoot)},
+		{Name: caldav.PropCalendarHomeSet, Value: caldav.Href(davRoot)},
+		{Name: caldav.PropCurrentUserPrivilegeSet, Value: caldav.Privileges(caldav.PrivilegeRead)},
+	})}
+	if c.Get("Depth") != "0" {
+		props, err := h.davCalendarProps(ctx, c, partnerObjID, partner)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Rezervasyonlar getirilemedi",
+			})
+		}
+		responses = append(responses, davResponse(req, davCalendar, props))
+	}
+
+	return davMultistatus(c, responses)
+}
+
+// PropfindCalDAVCalendar rezervasyon takviminin özelliklerini döndürür; Depth 1 ile takvim
+// nesneleri ETag'leriyle listelenir
+func (h *ReservationHandler) PropfindCalDAVCalendar(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	req, err := caldav.ParsePropfind(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	partner, err := h.partnerSettings(ctx, partnerObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+
+	props, err := h.davCalendarProps(ctx, c, partnerObjID, partner)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyonlar getirilemedi",
+		})
+	}
+	responses := []caldav.Response{davResponse(req, davCalendar, props)}
+	if c.Get("Depth") != "0" {
+		objects, err := h.davObjects(ctx, partnerObjID, partner, bson.M{}, req.Wants(caldav.PropCalendarData))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Rezervasyonlar getirilemedi",
+			})
+		}
+		for i := range objects {
+			responses = append(responses, davResponse(req, objects[i].href(), objects[i].props()))
+		}
+	}
+
+	return davMultistatus(c, responses)
+}
+
+// PropfindCalDAVObject tek bir takvim nesnesinin özelliklerini döndürür
+func (h *ReservationHandler) PropfindCalDAVObject(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	req, err := caldav.ParsePropfind(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	obj, err := h.davObjectFromParams(ctx, c, partnerObjID, req.Wants(caldav.PropCalendarData))
+	if obj == nil {
+		return err
+	}
+	return davMultistatus(c, []caldav.Response{davResponse(req, obj.href(), obj.props())})
+}
+
+// ReportCalDAVCalendar calendar-query (isteğe bağlı zaman aralığıyla) ve calendar-multiget
+// raporlarını yanıtlar
+func (h *ReservationHandler) ReportCalDAVCalendar(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	req, err := caldav.ParseReport(c.Body())
+	if err == caldav.ErrUnsupportedReport {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	partner, err := h.partnerSettings(ctx, partnerObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+
+	responses := []caldav.Response{}
+	if req.Report == caldav.ReportCalendarMultiget {
+		var names []string
+		for _, href := range req.Hrefs {
+			if name, ok := davHrefName(href); ok {
+				names = append(names, name)
+			}
+		}
+		byName := map[string]*davObject{}
+		if len(names) > 0 {
+			objects, err := h.davObjects(ctx, partnerObjID, partner, davNameFilter(names), req.Wants(caldav.PropCalendarData))
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Rezervasyonlar getirilemedi",
+				})
+			}
+			for i := range objects {
+				byName[objects[i].name] = &objects[i]
+			}
+		}
+		for _, href := range req.Hrefs {
+			name, _ := davHrefName(href)
+			if obj, ok := byName[name]; ok {
+				responses = append(responses, davResponse(req, obj.href(), obj.props()))
+			} else {
+				responses = append(responses, caldav.Response{Href: href, Status: fiber.StatusNotFound})
+			}
+		}
+		return davMultistatus(c, responses)
+	}
+
+	// Takvimde yalnızca VEVENT vardır; görev (VTODO) gibi bileşen sorguları boş döner
+	if req.Component != "" && req.Component != "VCALENDAR" && req.Component != "VEVENT" {
+		return davMultistatus(c, responses)
+	}
+	filter := bson.M{}
+	if !req.Start.IsZero() || !req.End.IsZero() {
+		end := req.End
+		if end.IsZero() {
+			end = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+		}
+		filter = overlapFilter(partnerObjID, req.Start, end)
+	}
+	objects, err := h.davObjects(ctx, partnerObjID, partner, filter, req.Wants(caldav.PropCalendarData))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyonlar getirilemedi",
+		})
+	}
+	for i := range objects {
+		responses = append(responses, davResponse(req, objects[i].href(), objects[i].props()))
+	}
+	return davMultistatus(c, responses)
+}
+
+// GetCalDAVObject takvim nesnesini .ics olarak döndürür
+func (h *ReservationHandler) GetCalDAVObject(c *fiber.Ctx) error {
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	obj, err := h.davObjectFromParams(context.Background(), c, partnerObjID, true)
+	if obj == nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, obj.etag)
+	if c.Fresh() {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	c.Set(fiber.HeaderContentType, ical.ContentType)
+	return c.Send(obj.data)
+}
+
+// PutCalDAVObject takvim uygulamasından gelen nesneyi kaydeder. Yeni nesne rezervasyon
+// olarak oluşturulur; mevcut nesnedeki değişiklikler API'deki güncelleme ve silme
+// işlemlerine çevrilir: serinin kendisi scope=all, düzenlenen gerçekleşme scope=this
+// güncellemesi, EXDATE ile çıkarılan gerçekleşme scope=this silmesi olarak uygulanır.
+// Böylece çakışma, çalışma saatleri ve müşteri rezervasyonu kuralları aynen geçerlidir.
+func (h *ReservationHandler) PutCalDAVObject(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	name, err := url.PathUnescape(c.Params("name"))
+	if err != nil || name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz takvim nesnesi adı",
+		})
+	}
+	partner, err := h.partnerSettings(ctx, partnerObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+	partnerLoc := partner.Location()
+
+	objects, err := h.davObjects(ctx, partnerObjID, partner, davNameFilter([]string{name}), true)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyon getirilemedi",
+		})
+	}
+	var obj *davObject
+	if len(objects) > 0 {
+		obj = &objects[0]
+	}
+	if ok, err := davPreconditions(c, obj); !ok {
+		return err
+	}
+
+	events, err := ical.Parse(c.Body(), partnerLoc)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	master, overrides, err := splitDAVEvents(events)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	reservation, err := reservationFromEvent(*master, partnerLoc)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// UID nesne boyunca değişmez ve partner'ın takviminde tekildir
+	other, err := h.reservationByUID(ctx, partnerObjID, master.UID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyon getirilemedi",
+		})
+	}
+	if (obj == nil && other != nil) || (obj != nil && ical.UID(obj.series) != master.UID) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Bu UID'ye sahip başka bir etkinlik var veya etkinliğin UID'si değiştirilmiş",
+		})
+	}
+
+	sched := schedule.New(partner)
+	if obj == nil {
+		reservation.ID = primitive.NewObjectID()
+		reservation.Capacity = 1
+		reservation.ResourceName = name
+		created, warnings, err := h.createReservation(ctx, partnerObjID, reservation)
+		if err == nil {
+			var more writeWarnings
+			more, err = h.applyDAVOverrides(ctx, partnerObjID, sched, created, nil, nil, overrides)
+			warnings.add(more)
+		}
+		return davWritten(c, fiber.StatusCreated, warnings, err)
+	}
+
+	finish, err := h.davClaim(ctx, c, obj)
+	if finish == nil {
+		return err
+	}
+	defer finish()
+	series := obj.series
+	exdates := reservation.ExceptionDates
+	reservation.ID = series.ID
+	reservation.Capacity = series.Capacity
+	reservation.ExceptionDates = uniqueTimes(series.ExceptionDates)
+	keepTimeZone(&reservation, series, master.Location, partnerLoc)
+	var warnings writeWarnings
+	if importChanged(series, reservation) {
+		_, warnings, err = h.updateSeries(ctx, partnerObjID, series.ID, reservation)
+	}
+	if err == nil {
+		var more writeWarnings
+		more, err = h.applyDAVOverrides(ctx, partnerObjID, sched, reservation, obj.overrides, exdates, overrides)
+		warnings.add(more)
+	}
+	return davWritten(c, fiber.StatusNoContent, warnings, err)
+}
+
+// DeleteCalDAVObject takvim nesnesini, tekrar ediyorsa tüm seriyi siler
+func (h *ReservationHandler) DeleteCalDAVObject(c *fiber.Ctx) error {
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	obj, err := h.davObjectFromParams(context.Background(), c, partnerObjID, false)
+	if obj == nil {
+		return err
+	}
+	if ok, err := davPreconditions(c, obj); !ok {
+		return err
+	}
+	finish, err := h.davClaim(context.Background(), c, obj)
+	if finish == nil {
+		return err
+	}
+	defer finish()
+
+	err = h.deleteSeries(context.Background(), partnerObjID, obj.series.ID)
+	return davWritten(c, fiber.StatusNoContent, writeWarnings{}, err)
+}
+
+// applyDAVOverrides takvim nesnesindeki iptalleri ve ayrı düzenlenmiş gerçekleşmeleri seriye
+// uygular. Planvia'da zaten iptal edilmiş gerçekleşmeler ve kapalı günlerde atlananlar
+// (nesnede EXDATE olarak yazılır) yok sayılır; nesneden kaldırılan düzenlemeler silinmez.
+func (h *ReservationHandler) applyDAVOverrides(ctx context.Context, partnerObjID primitive.ObjectID, sched *schedule.Schedule, series models.Reservation, existing []models.Reservation, exdates []time.Time, events []ical.Event) (writeWarnings, error) {
+	var warnings writeWarnings
+	if !series.Recurrence.Enabled {
+		return warnings, nil
+	}
+	loc := sched.Location()
+
+	edited := map[int64]*models.Reservation{}
+	for i := range existing {
+		if existing[i].RecurrenceID != nil {
+			edited[existing[i].RecurrenceID.UnixNano()] = &existing[i]
+		}
+	}
+	inFile := map[int64]bool{}
+	for _, e := range events {
+		if !e.Cancelled {
+			inFile[e.RecurrenceID.UnixNano()] = true
+		}
+	}
+
+	// İptaller: yeni EXDATE'ler, iptal edilmiş gerçekleşmeler ve EXDATE'e alınan düzenlemeler
+	cancelled := map[int64]bool{}
+	cancel := append([]time.Time{}, exdates...)
+	for _, e := range events {
+		if e.Cancelled {
+			cancel = append(cancel, *e.RecurrenceID)
+		}
+	}
+	for _, t := range uniqueTimes(cancel) {
+		_, isEdited := edited[t.UnixNano()]
+		if inFile[t.UnixNano()] || (recurrence.IsException(series, loc, t) && !isEdited) {
+			continue
+		}
+		if _, ok := recurrence.IndexOf(series, loc, t); !ok || len(sched.Skipped(series, t, t.Add(time.Second))) > 0 {
+			continue
+		}
+		if err := h.deleteOccurrence(ctx, partnerObjID, series.ID, t); err != nil {
+			return warnings, err
+		}
+		cancelled[t.UnixNano()] = true
+	}
+
+	for _, e := range events {
+		if e.Cancelled || cancelled[e.RecurrenceID.UnixNano()] {
+			continue
+		}
+		occurrence, err := reservationFromEvent(e, loc)
+		if err != nil {
+			return warnings, failWrite(fiber.StatusBadRequest, fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if prev, ok := edited[e.RecurrenceID.UnixNano()]; ok {
+			occurrence.Capacity = prev.Capacity
+			keepTimeZone(&occurrence, *prev, e.Location, loc)
+			if !importChanged(*prev, occurrence) {
+				continue
+			}
+			_, more, err := h.updateSeries(ctx, partnerObjID, prev.ID, occurrence)
+			warnings.add(more)
+			if err != nil {
+				return warnings, err
+			}
+			continue
+		}
+
+		if recurrence.IsException(series, loc, *e.RecurrenceID) {
+			continue
+		}
+		if _, ok := recurrence.IndexOf(series, loc, *e.RecurrenceID); !ok {
+			continue
+		}
+		occurrence.Capacity = series.Capacity
+		_, more, err := h.updateOccurrence(ctx, partnerObjID, series.ID, *e.RecurrenceID, occurrence)
+		warnings.add(more)
+		if err != nil {
+			return warnings, err
+		}
+	}
+	return warnings, nil
+}
+
+// davListProjection nesnelerin adları ve ETag'leri için yeterli rezervasyon alanlarıdır
+var davListProjection = bson.M{"partnerId": 1, "resourceName": 1, "updatedAt": 1, "changeSeq": 1, "recurrence.enabled": 1, "seriesId": 1}
+
+// davObjects filtreye uyan rezervasyonları ayrı düzenlenmiş gerçekleşmeleriyle birlikte takvim
+// nesnelerine çevirir. ETag'ler kayıtların güncelleme zamanlarından türetilir; nesneler yalnızca
+// withData ile .ics olarak yazılır. withData olmadan rezervasyonların yalnızca ad ve ETag için
+// gereken alanları getirilir.
+func (h *ReservationHandler) davObjects(ctx context.Context, partnerObjID primitive.ObjectID, partner *models.Partner, filter bson.M, withData bool) ([]davObject, error) {
+	collection := h.db.Collection("reservations")
+
+	find := options.Find().SetSort(bson.D{{Key: "startDate", Value: 1}})
+	findOverrides := options.Find()
+	if !withData {
+		find.SetProjection(davListProjection)
+		findOverrides.SetProjection(davListProjection)
+	}
+
+	filter["partnerId"] = partnerObjID
+	filter["seriesId"] = bson.M{"$exists": false}
+	cursor, err := collection.Find(ctx, filter, find)
+	if err != nil {
+		return nil, err
+	}
+	var found []models.Reservation
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	overrides := map[primitive.ObjectID][]models.Reservation{}
+	var seriesIDs []primitive.ObjectID
+	for _, r := range found {
+		if r.Recurrence.Enabled {
+			seriesIDs = append(seriesIDs, r.ID)
+		}
+	}
+	if len(seriesIDs) > 0 {
+		cursor, err := collection.Find(ctx, bson.M{"partnerId": partnerObjID, "seriesId": bson.M{"$in": seriesIDs}}, findOverrides)
+		if err != nil {
+			return nil, err
+		}
+		var edited []models.Reservation
+		if err := cursor.All(ctx, &edited); err != nil {
+			return nil, err
+		}
+		for _, o := range edited {
+			overrides[*o.SeriesID] = append(overrides[*o.SeriesID], o)
+		}
+	}
+
+	sched := schedule.New(partner)
+	from, to := davWindow()
+	objects := make([]davObject, 0, len(found))
+	for _, r := range found {
+		obj := davObject{name: davName(r), series: r, overrides: overrides[r.ID]}
+		obj.etag = davETag(partner, r, obj.overrides, from)
+		if withData {
+			cal := ical.Calendar{
+				Location:     sched.Location(),
+				Reservations: append([]models.Reservation{r}, obj.overrides...),
+				Until:        to,
+				Skipped: func(r models.Reservation) []time.Time {
+					return sched.Skipped(r, from, to)
+				},
+			}
+			obj.data = cal.Encode()
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// davETag nesnenin ETag'idir. Nesne serinin ve düzenlenmiş gerçekleşmelerinin kayıtlarından,
+// partner'ın takvim ayarlarından ve nesnelerin yazıldığı aralıktan oluşur; biri değişmedikçe
+// nesne de değişmez.
+func davETag(partner *models.Partner, series models.Reservation, overrides []models.Reservation, from time.Time) string {
+	var latest time.Time
+	for _, o := range overrides {
+		if o.UpdatedAt.After(latest) {
+			latest = o.UpdatedAt
+		}
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s-%d-%d-%d-%d-%d", series.ID.Hex(), series.UpdatedAt.UnixMilli(),
+		len(overrides), latest.UnixMilli(), partner.SettingsVersion, from.Unix())))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// davObjectFromParams adresteki takvim nesnesini getirir. Hata durumunda yanıt yazılmıştır
+// ve nesne nil döner.
+func (h *ReservationHandler) davObjectFromParams(ctx context.Context, c *fiber.Ctx, partnerObjID primitive.ObjectID, withData bool) (*davObject, error) {
+	name, err := url.PathUnescape(c.Params("name"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz takvim nesnesi adı",
+		})
+	}
+	partner, err := h.partnerSettings(ctx, partnerObjID)
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+
+	objects, err := h.davObjects(ctx, partnerObjID, partner, davNameFilter([]string{name}), withData)
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyon getirilemedi",
+		})
+	}
+	if len(objects) == 0 {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Takvim nesnesi bulunamadı",
+		})
+	}
+	return &objects[0], nil
+}
+
+// davCalendarProps rezervasyon takviminin özelliklerini döndürür
+func (h *ReservationHandler) davCalendarProps(ctx context.Context, c *fiber.Ctx, partnerObjID primitive.ObjectID, partner *models.Partner) ([]caldav.Property, error) {
+	ctag, err := h.davCTag(ctx, partnerObjID, partner)
+	if err != nil {
+		return nil, err
+	}
+	return []caldav.Property{
+		{Name: caldav.PropResourceType, Value: caldav.Empty(caldav.Collection, caldav.CalendarType)},
+		{Name: caldav.PropDisplayName, Value: caldav.Text(davDisplayName(partner))},
+		{Name: caldav.PropCurrentUserPrincipal, Value: caldav.Href(davRoot)},
+		{Name: caldav.PropOwner, Value: caldav.Href(davRoot)},
+		{Name: caldav.PropSupportedCalendarComponentSet, Value: caldav.Components("VEVENT")},
+		{Name: caldav.PropSupportedReportSet, Value: caldav.Reports(caldav.ReportCalendarQuery, caldav.ReportCalendarMultiget)},
+		{Name: caldav.PropCurrentUserPrivilegeSet, Value: caldav.Privileges(davPrivileges(c)...)},
+		{Name: caldav.PropGetCTag, Value: caldav.Text(ctag)},
+		{Name: caldav.PropGetETag, Value: caldav.Text(`"` + ctag + `"`)},
+	}, nil
+}
+
+// davCTag takvimin herhangi bir nesnesi değiştiğinde değişen etikettir. Rezervasyon sayısı
+// (silinenler için), en son güncelleme zamanı, partner'ın takvim ayarları ve nesnelerin
+// yazıldığı aralıktan türetilir.
+func (h *ReservationHandler) davCTag(ctx context.Context, partnerObjID primitive.ObjectID, partner *models.Partner) (string, error) {
+	count, updatedAt, err := h.reservationsVersion(ctx, partnerObjID)
+	if err != nil {
+		return "", err
+	}
+
+	from, _ := davWindow()
+	return fmt.Sprintf("%d-%d-%d-%d", updatedAt.UnixMilli(), count, partner.SettingsVersion, from.Unix()), nil
+}
+
+// reservationByUID takvim UID'sine sahip rezervasyonu getirir; yoksa nil döner
+func (h *ReservationHandler) reservationByUID(ctx context.Context, partnerObjID primitive.ObjectID, uid string) (*models.Reservation, error) {
+	or := bson.A{bson.M{"importUid": uid}}
+	if id, ok := ical.ReservationID(uid); ok {
+		or = append(or, bson.M{"_id": id, "importUid": bson.M{"$exists": false}})
+	}
+
+	var reservation models.Reservation
+	err := h.db.Collection("reservations").FindOne(ctx, bson.M{
+		"partnerId": partnerObjID,
+		"seriesId":  bson.M{"$exists": false},
+		"$or":       or,
+	}).Decode(&reservation)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// splitDAVEvents takvim nesnesindeki etkinlikleri seri ve ayrı düzenlenmiş gerçekleşmelerine
+// ayırır. CalDAV'da bir nesne tek bir etkinlik ve aynı UID'li gerçekleşmelerinden oluşur.
+func splitDAVEvents(events []ical.Event) (*ical.Event, []ical.Event, error) {
+	var master *ical.Event
+	var overrides []ical.Event
+	for i := range events {
+		e := &events[i]
+		if e.Invalid != "" {
+			return nil, nil, errors.New(e.Invalid)
+		}
+		if e.RecurrenceID != nil {
+			overrides = append(overrides, *e)
+			continue
+		}
+		if master != nil {
+			return nil, nil, errors.New("Takvim nesnesi yalnızca bir etkinlik içerebilir")
+		}
+		master = e
+	}
+
+	if master == nil {
+		return nil, nil, errors.New("Takvim nesnesinde etkinlik (VEVENT) bulunamadı")
+	}
+	if master.Cancelled {
+		return nil, nil, errors.New("İptal edilmiş etkinlik kaydedilemez; etkinliği silin")
+	}
+	for _, o := range overrides {
+		if o.UID != master.UID {
+			return nil, nil, errors.New("Takvim nesnesindeki etkinliklerin UID'leri aynı olmalıdır")
+		}
+	}
+	return master, overrides, nil
+}
+
+// keepTimeZone etkinliğin saat dilimi değişmediyse rezervasyonda kayıtlı değeri korur.
+// Etkinlikten yalnızca partner'ınkinden farklı ve gerekli saat dilimleri alınır.
+func keepTimeZone(r *models.Reservation, existing models.Reservation, eventLoc, partnerLoc *time.Location) {
+	if existing.Location(partnerLoc).String() == eventLoc.String() {
+		r.TimeZone = existing.TimeZone
+	}
+}
+
+// davName rezervasyonun takvimdeki kaynak adıdır. CalDAV ile oluşturulanlar takvim
+// uygulamasının seçtiği adı korur.
+func davName(r models.Reservation) string {
+	if r.ResourceName != "" {
+		return r.ResourceName
+	}
+	return r.ID.Hex() + ".ics"
+}
+
+// davNameFilter kaynak adlarına sahip rezervasyonları seçer
+func davNameFilter(names []string) bson.M {
+	or := bson.A{bson.M{"resourceName": bson.M{"$in": names}}}
+	var ids []primitive.ObjectID
+	for _, name := range names {
+		hex, ok := strings.CutSuffix(name, ".ics")
+		if !ok {
+			continue
+		}
+		if id, err := primitive.ObjectIDFromHex(hex); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		or = append(or, bson.M{"_id": bson.M{"$in": ids}, "resourceName": bson.M{"$exists": false}})
+	}
+	return bson.M{"$or": or}
+}
+
+// davHrefName multiget'teki adresten (tam URL veya yol) takvim nesnesinin adını çıkarır
+func davHrefName(href string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", false
+	}
+	dir, name := path.Split(u.Path)
+	if dir != davCalendar || name == "" {
+		return "", false
+	}
+	return name, true
+}
+
+// davWindow kapalı günlerde atlanan gerçekleşmelerin ve saat dilimi geçişlerinin yazıldığı
+// aralıktır. Aralık ay başına sabitlenir; böylece nesnelerin ETag'leri her gün değişmez.
+func davWindow() (time.Time, time.Time) {
+	y, m, _ := time.Now().UTC().Date()
+	month := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	return month.Add(-calendarFeedPast), month.Add(calendarFeedAhead)
+}
+
+func davDisplayName(partner *models.Partner) string {
+	if partner.CompanyName != "" {
+		return partner.CompanyName
+	}
+	return "Planvia"
+}
+
+// davPrivileges kullanıcının rolüne göre takvimdeki yetkilerini döndürür
+func davPrivileges(c *fiber.Ctx) []xml.Name {
+	role, _ := c.Locals("role").(string)
+	privileges := []xml.Name{caldav.PrivilegeRead}
+	if auth.HasPermission(role, auth.PermReservationsCreate) && auth.HasPermission(role, auth.PermReservationsUpdate) {
+		privileges = append(privileges, caldav.PrivilegeBind, caldav.PrivilegeWriteContent)
+	}
+	if auth.HasPermission(role, auth.PermReservationsDelete) {
+		privileges = append(privileges, caldav.PrivilegeUnbind)
+	}
+	return privileges
+}
+
+// davPreconditions If-Match ve If-None-Match başlıklarını nesnenin ETag'iyle karşılaştırır;
+// takvim uygulamaları başka bir istemcinin değişikliğini ezmemek için bunları gönderir.
+// false dönerse yanıt yazılmıştır.
+func davPreconditions(c *fiber.Ctx, obj *davObject) (bool, error) {
+	failed := false
+	if match := c.Get(fiber.HeaderIfMatch); match != "" {
+		failed = obj == nil || (match != "*" && !etagListContains(match, obj.etag))
+	}
+	if none := c.Get(fiber.HeaderIfNoneMatch); none != "" && obj != nil {
+		failed = failed || none == "*" || etagListContains(none, obj.etag)
+	}
+	if failed {
+		return false, c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"error": "Takvim nesnesi başka bir istemci tarafından değiştirilmiş",
+		})
+	}
+	return true, nil
+}
+
+// davClaim If-Match ile koşullu bir yazmada serinin kaydını, ETag'i hesaplanırken okunan
+// güncelleme zamanı koşuluyla işaretler. Kontrolle yazma arasında başka bir istemci nesneyi
+// değiştirdiyse filtre eşleşmez ve 412 döner; aynı ETag'le gelen ikinci yazma da reddedilir.
+// Dönen fonksiyon istek bitince çağrılır: yazma reddedildiyse veya bir şey değişmediyse seri
+// kaydı o zamandan beri yazılmamıştır ve işaret geri alınır, böylece ETag değişmez. Hata
+// durumunda yanıt yazılmıştır ve fonksiyon nil döner.
+func (h *ReservationHandler) davClaim(ctx context.Context, c *fiber.Ctx, obj *davObject) (func(), error) {
+	if match := c.Get(fiber.HeaderIfMatch); match == "" || match == "*" {
+		return func() {}, nil
+	}
+
+	collection := h.db.Collection("reservations")
+	claimedAt := time.Now()
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": obj.series.ID, "partnerId": obj.series.PartnerID, "updatedAt": obj.series.UpdatedAt},
+		bson.M{"$set": bson.M{"updatedAt": claimedAt}},
+	)
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyon güncellenirken bir hata oluştu",
+		})
+	}
+	if result.MatchedCount == 0 {
+		return nil, c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"error": "Takvim nesnesi başka bir istemci tarafından değiştirilmiş",
+		})
+	}
+
+	// Gerçek yazmalar yeni bir değişiklik numarası işler; numara aynıysa kayıt yalnızca işaretlidir
+	unchanged := bson.M{"_id": obj.series.ID, "updatedAt": claimedAt, "changeSeq": obj.series.ChangeSeq}
+	if obj.series.ChangeSeq == 0 {
+		unchanged["changeSeq"] = bson.M{"$exists": false}
+	}
+	return func() {
+		if _, err := collection.UpdateOne(context.Background(), unchanged,
+			bson.M{"$set": bson.M{"updatedAt": obj.series.UpdatedAt}},
+		); err != nil {
+			log.Printf("CalDAV claim could not be released for reservation %s: %v", obj.series.ID.Hex(), err)
+		}
+	}, nil
+}
+
+func etagListContains(list, etag string) bool {
+	for _, value := range strings.Split(list, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(value), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func davResponse(req *caldav.Request, href string, available []caldav.Property) caldav.Response {
+	found, missing := req.Select(available)
+	return caldav.Response{Href: href, Found: found, Missing: missing}
+}
+
+func davMultistatus(c *fiber.Ctx, responses []caldav.Response) error {
+	c.Set(fiber.HeaderContentType, "application/xml; charset=utf-8")
+	return c.Status(fiber.StatusMultiStatus).Send(caldav.Multistatus(responses))
+}
+
+// davWritten yazma yollarının sonucunu CalDAV yanıtına çevirir. Hatalar API'deki gibi JSON
+// olarak döner; başarılı yazmalar gövdesiz yanıt alır. Kaydedilen nesne gönderilenden farklı
+// olabileceği için ETag gönderilmez; takvim uygulaması nesneyi yeniden okur.
+func davWritten(c *fiber.Ctx, status int, warnings writeWarnings, err error) error {
+	warnings.setHeaders(c)
+	if err != nil {
+		return respondWriteError(c, err)
+	}
+	c.Status(status)
+	return nil
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/ical"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/schedule"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxExportWindow .ics dışa aktarımında seçilebilecek en uzun aralıktır
+const maxExportWindow = 366 * 24 * time.Hour
+
+// Abonelik adresi bugünden calendarFeedPast öncesinden calendarFeedAhead sonrasına kadarki
+// rezervasyonları içerir; tekrar eden seriler RRULE ile tamamı olarak yazılır
+const (
+	calendarFeedPast  = 90 * 24 * time.Hour
+	calendarFeedAhead = 365 * 24 * time.Hour
+)
+
+// ExportCalendar partner'ın verilen aralıktaki rezervasyonlarını .ics dosyası olarak indirir
+func (h *ReservationHandler) ExportCalendar(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	start, err := time.Parse(time.RFC3339, c.Query("start"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz başlangıç tarihi formatı",
+		})
+	}
+	end, err := time.Parse(time.RFC3339, c.Query("end"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz bitiş tarihi formatı",
+		})
+	}
+	if !end.After(start) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Bitiş tarihi başlangıç tarihinden sonra olmalıdır",
+		})
+	}
+	if end.Sub(start) > maxExportWindow {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Aralık en fazla 366 gün olabilir",
+		})
+	}
+
+	partner, err := h.partnerSettings(ctx, partnerObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+
+	cal, err := h.buildCalendar(ctx, partnerObjID, partner, start, end)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyonlar getirilemedi",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, ical.ContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="planvia-%s.ics"`, start.Format(dayLayout)))
+	return c.Send(cal)
+}
+
+// GetCalendarFeed gizli abonelik adresinden partner'ın takvimini döndürür. JWT gerektirmez;
+// takvim uygulamaları ETag ve Last-Modified ile değişmeyen takvimi yeniden indirmez.
+func (h *ReservationHandler) GetCalendarFeed(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	feed, err := h.feeds.Authenticate(ctx, c.Params("token"))
+	if err == auth.ErrCalendarFeedInvalid {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Takvim bulunamadı",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	partner, err := h.partnerSettings(ctx, feed.PartnerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+	if partner.AccountStatus() != models.PartnerStatusActive {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Takvim bulunamadı",
+		})
+	}
+
+	// Pencere gün başına hizalanır; böylece takvim gün içinde yalnızca kayıtlar değişince değişir
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	start, end := today.Add(-calendarFeedPast), today.Add(calendarFeedAhead)
+
+	// Doğrulayıcı takvim oluşturulmadan önce üst verilerden hesaplanır; değişmeyen takvim
+	// için rezervasyonlar yüklenmez
+	count, lastModified, err := h.reservationsVersion(ctx, feed.PartnerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyonlar getirilemedi",
+		})
+	}
+	// Silinen rezervasyonlar da takvimi değiştirir
+	deletedAt, err := h.tombstones.Latest(ctx, feed.PartnerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyonlar getirilemedi",
+		})
+	}
+	if lastModified.Before(deletedAt) {
+		lastModified = deletedAt
+	}
+	if lastModified.Before(feed.CreatedAt) {
+		lastModified = feed.CreatedAt
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d-%d-%d-%d-%d",
+		count, lastModified.UnixMilli(), deletedAt.UnixMilli(), partner.SettingsVersion, start.Unix())))
+	c.Set(fiber.HeaderETag, `"`+hex.EncodeToString(sum[:16])+`"`)
+	c.Set(fiber.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	if c.Fresh() {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	cal, err := h.buildCalendar(ctx, feed.PartnerID, partner, start, end)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyonlar getirilemedi",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, ical.ContentType)
+	return c.Send(cal)
+}
+
+// GetCalendarFeedSettings partner'ın abonelik adresinin durumunu döndürür. Adres yalnızca
+// oluşturulurken gösterilir.
+func (h *ReservationHandler) GetCalendarFeedSettings(c *fiber.Ctx) error {
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	feed, err := h.feeds.Get(context.Background(), partnerObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+	if feed == nil {
+		return c.JSON(fiber.Map{"enabled": false})
+	}
+	return c.JSON(fiber.Map{
+		"enabled":        true,
+		"createdAt":      feed.CreatedAt,
+		"lastAccessedAt": feed.LastAccessedAt,
+	})
+}
+
+// RotateCalendarFeed yeni bir gizli abonelik adresi oluşturur; varsa eski adres çalışmaz olur
+func (h *ReservationHandler) RotateCalendarFeed(c *fiber.Ctx) error {
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	userObjID, _ := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+
+	feed, token, err := h.feeds.Rotate(context.Background(), partnerObjID, userObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Takvim aboneliği oluşturulamadı",
+		})
+	}
+
+	url := c.BaseURL() + "/api/public/calendars/" + token + ".ics"
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"url":       url,
+		"webcalUrl": "webcal://" + strings.SplitN(url, "://", 2)[1],
+		"createdAt": feed.CreatedAt,
+	})
+}
+
+// RevokeCalendarFeed abonelik adresini iptal eder
+func (h *ReservationHandler) RevokeCalendarFeed(c *fiber.Ctx) error {
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	revoked, err := h.feeds.Revoke(context.Background(), partnerObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+	if !revoked {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Takvim aboneliği bulunamadı",
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// reservationsVersion partner'ın rezervasyon sayısını ve en son güncellenen rezervasyonun
+// zamanını döndürür. Biri değişmediyse takvim de değişmemiştir; silmeler ayrıca izlenir.
+func (h *ReservationHandler) reservationsVersion(ctx context.Context, partnerObjID primitive.ObjectID) (int64, time.Time, error) {
+	collection := h.db.Collection("reservations")
+
+	count, err := collection.CountDocuments(ctx, bson.M{"partnerId": partnerObjID})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	var latest struct {
+		UpdatedAt time.Time `bson:"updatedAt"`
+	}
+	err = collection.FindOne(ctx,
+		bson.M{"partnerId": partnerObjID},
+		options.FindOne().SetSort(bson.D{{Key: "updatedAt", Value: -1}}).SetProjection(bson.M{"updatedAt": 1}),
+	).Decode(&latest)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return 0, time.Time{}, err
+	}
+	return count, latest.UpdatedAt, nil
+}
+
+// buildCalendar [start, end] aralığına düşen rezervasyonlardan takvimi oluşturur. Ayrı düzenlenmiş gerçekleşmeler serileriyle
+// birlikte yazıldığı için aralık dışındaki seriler ve gerçekleşmeler de eklenir.
+func (h *ReservationHandler) buildCalendar(ctx context.Context, partnerObjID primitive.ObjectID, partner *models.Partner, start, end time.Time) ([]byte, error) {
+	collection := h.db.Collection("reservations")
+
+	var reservations []models.Reservation
+	find := func(filter bson.M) error {
+		cursor, err := collection.Find(ctx, filter)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		var found []models.Reservation
+		if err := cursor.All(ctx, &found); err != nil {
+			return err
+		}
+		reservations = append(reservations, found...)
+		return nil
+	}
+
+	if err := find(overlapFilter(partnerObjID, start, end)); err != nil {
+		return nil, err
+	}
+
+	included := map[primitive.ObjectID]bool{}
+	for _, r := range reservations {
+		included[r.ID] = true
+	}
+	var seriesIDs, missing []primitive.ObjectID
+	for _, r := range reservations {
+		if r.SeriesID == nil && r.Recurrence.Enabled {
+			seriesIDs = append(seriesIDs, r.ID)
+		}
+		if r.SeriesID != nil && !included[*r.SeriesID] {
+			included[*r.SeriesID] = true
+			missing = append(missing, *r.SeriesID)
+		}
+	}
+	ids := make([]primitive.ObjectID, 0, len(included))
+	for id := range included {
+		ids = append(ids, id)
+	}
+	if len(missing) > 0 {
+		if err := find(bson.M{"partnerId": partnerObjID, "_id": bson.M{"$in": missing}}); err != nil {
+			return nil, err
+		}
+	}
+	if len(seriesIDs) > 0 {
+		if err := find(bson.M{"partnerId": partnerObjID, "seriesId": bson.M{"$in": seriesIDs}, "_id": bson.M{"$nin": ids}}); err != nil {
+			return nil, err
+		}
+	}
+
+	sched := schedule.New(partner)
+	cal := ical.Calendar{
+		Name:         partner.CompanyName,
+		Method:       ical.MethodPublish,
+		Location:     sched.Location(),
+		Reservations: reservations,
+		Until:        end,
+		Skipped: func(r models.Reservation) []time.Time {
+			return sched.Skipped(r, start, end)
+		},
+	}
+	return cal.Encode(), nil
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/mailer"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// emailVerificationTTL doğrulama bağlantısının geçerlilik süresidir
+const emailVerificationTTL = 48 * time.Hour
+
+// VerifyEmail marks the partner's email as verified and activates a pending account
+func (h *PartnerHandler) VerifyEmail(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	claims, err := h.keys.Parse(req.Token)
+	if err != nil || claims[auth.ClaimTokenType] != auth.TokenTypeEmailVerification {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Doğrulama bağlantısı geçersiz veya süresi dolmuş",
+		})
+	}
+
+	partnerID, _ := claims["partnerId"].(string)
+	email, _ := claims["email"].(string)
+	partnerObjID, err := primitive.ObjectIDFromHex(partnerID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Doğrulama bağlantısı geçersiz veya süresi dolmuş",
+		})
+	}
+
+	// Token e-posta adresine bağlıdır; adres değiştiyse eski bağlantı çalışmaz
+	filter := bson.M{"_id": partnerObjID, "email": email}
+	now := time.Now()
+	result, err := h.collection.UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{"email_verified": true, "updated_at": now},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Doğrulama bağlantısı geçersiz veya süresi dolmuş",
+		})
+	}
+
+	// Askıya alınmış hesaplar doğrulama ile aktifleşmez
+	filter["status"] = models.PartnerStatusPending
+	if _, err := h.collection.UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{"status": models.PartnerStatusActive},
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "E-posta adresiniz doğrulandı",
+	})
+}
+
+// ResendVerification sends a new verification email to an unverified partner.
+// The response is the same whether or not the email exists.
+func (h *PartnerHandler) ResendVerification(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.ResendVerificationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	response := fiber.Map{
+		"message": "Bu e-posta adresi doğrulama bekliyorsa yeni bir bağlantı gönderildi",
+	}
+
+	var partner models.Partner
+	err := h.collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&partner)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(response)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	if !partner.EmailVerified && partner.AccountStatus() != models.PartnerStatusSuspended {
+		if err := h.sendVerificationEmail(ctx, &partner); err != nil {
+			log.Printf("Verification mail could not be sent to %s: %v", partner.Email, err)
+		}
+	}
+
+	return c.JSON(response)
+}
+
+// sendVerificationEmail mails a signed verification link bound to the partner's current email
+func (h *PartnerHandler) sendVerificationEmail(ctx context.Context, partner *models.Partner) error {
+	token, err := h.keys.Sign(jwt.MapClaims{
+		auth.ClaimTokenType: auth.TokenTypeEmailVerification,
+		"partnerId":         partner.ID.Hex(),
+		"email":             partner.Email,
+		"exp":               time.Now().Add(emailVerificationTTL).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", h.appURL, url.QueryEscape(token))
+	return h.mailer.Send(ctx, mailer.Message{
+		To:      partner.Email,
+		Subject: "Planvia e-posta doğrulama",
+		Body: fmt.Sprintf("Merhaba %s,\n\nHesabınızı etkinleştirmek için e-posta adresinizi doğrulayın. "+
+			"Bağlantı 48 saat geçerlidir.\n\n%s", partner.ContactPerson, link),
+	})
+}
\ No newline at end of file
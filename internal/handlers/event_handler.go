@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/events"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Olay listesinde varsayılan ve en fazla sayfa boyutu
+const (
+	defaultEventLimit = 50
+	maxEventLimit     = 200
+)
+
+type EventHandler struct {
+	events *events.Outbox
+}
+
+func NewEventHandler(outbox *events.Outbox) *EventHandler {
+	return &EventHandler{events: outbox}
+}
+
+// ListEvents partner'ın olaylarını sırayla getirir. İstemci son aldığı olayın sıra numarasını
+// after ile göndererek yalnızca yeni olayları çeker.
+func (h *EventHandler) ListEvents(c *fiber.Ctx) error {
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	// after son işlenen olayın sıra numarasıdır
+	var after int64
+	if s := c.Query("after"); s != "" {
+		if after, err = strconv.ParseInt(s, 10, 64); err != nil || after < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz olay sıra numarası",
+			})
+		}
+	}
+
+	limit := defaultEventLimit
+	if s := c.Query("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 || limit > maxEventLimit {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "limit 1 ile " + strconv.Itoa(maxEventLimit) + " arasında olmalıdır",
+			})
+		}
+	}
+
+	list, err := h.events.List(c.Context(), partnerObjID, after, c.Query("type"), int64(limit))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Olaylar getirilemedi",
+		})
+	}
+
+	return c.JSON(list)
+}
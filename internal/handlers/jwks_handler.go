@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type JWKSHandler struct {
+	keys *auth.KeySet
+}
+
+func NewJWKSHandler(keys *auth.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// GetJWKS diğer servislerin token doğrulaması için public key'leri yayınlar
+func (h *JWKSHandler) GetJWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(h.keys.JWKS())
+}
\ No newline at end of file
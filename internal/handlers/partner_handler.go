@@ -3,9 +3,16 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/mailer"
 	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/validation"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -16,15 +23,45 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// PartnerHandlerConfig PartnerHandler'ın bağımlılıklarını tutar
+type PartnerHandlerConfig struct {
+	Keys         *auth.KeySet
+	Sessions     *auth.SessionStore
+	Throttle     *auth.LoginThrottle
+	AppPasswords *auth.AppPasswordStore
+	Mailer       mailer.Mailer
+	AppURL       string
+}
+
 type PartnerHandler struct {
-	collection *mongo.Collection
-	validate   *validator.Validate
+	collection   *mongo.Collection
+	staff        *mongo.Collection
+	resets       *mongo.Collection
+	reservations *mongo.Collection
+	bookings     *mongo.Collection
+	validate     *validator.Validate
+	keys         *auth.KeySet
+	sessions     *auth.SessionStore
+	throttle     *auth.LoginThrottle
+	appPwds      *auth.AppPasswordStore
+	mailer       mailer.Mailer
+	appURL       string
 }
 
-func NewPartnerHandler(db *mongo.Database) *PartnerHandler {
+func NewPartnerHandler(db *mongo.Database, cfg PartnerHandlerConfig) *PartnerHandler {
 	return &PartnerHandler{
-		collection: db.Collection("partners"),
-		validate:   validator.New(),
+		collection:   db.Collection("partners"),
+		staff:        db.Collection("staff"),
+		resets:       db.Collection("password_resets"),
+		reservations: db.Collection("reservations"),
+		bookings:     db.Collection("bookings"),
+		validate:     validation.New(),
+		keys:         cfg.Keys,
+		sessions:     cfg.Sessions,
+		throttle:     cfg.Throttle,
+		appPwds:      cfg.AppPasswords,
+		mailer:       cfg.Mailer,
+		appURL:       cfg.AppURL,
 	}
 }
 
@@ -56,6 +93,10 @@ func (h *PartnerHandler) Register(c *fiber.Ctx) error {
 		})
 	}
 
+	// Store phone and tax numbers in a single canonical form
+	req.PhoneNumber, _ = validation.NormalizePhone(req.PhoneNumber)
+	req.TaxNumber = validation.NormalizeTaxNumber(req.TaxNumber)
+
 	// Check if email already exists
 	existingPartner := h.collection.FindOne(ctx, bson.M{"email": req.Email})
 	if existingPartner.Err() != mongo.ErrNoDocuments {
@@ -64,6 +105,14 @@ func (h *PartnerHandler) Register(c *fiber.Ctx) error {
 		})
 	}
 
+	// Staff accounts share the login endpoint, so their emails must not clash
+	existingStaff := h.staff.FindOne(ctx, bson.M{"email": req.Email})
+	if existingStaff.Err() != mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Bu e-posta adresi zaten kullanımda",
+		})
+	}
+
 	// Check if tax number already exists
 	existingPartner = h.collection.FindOne(ctx, bson.M{"tax_number": req.TaxNumber})
 	if existingPartner.Err() != mongo.ErrNoDocuments {
@@ -95,8 +144,13 @@ func (h *PartnerHandler) Register(c *fiber.Ctx) error {
 	// Set ID from insert result
 	partner.ID = result.InsertedID.(primitive.ObjectID)
 
+	// Send verification email; the account stays pending until it is verified
+	if err := h.sendVerificationEmail(ctx, &partner); err != nil {
+		log.Printf("Verification mail could not be sent to %s: %v", partner.Email, err)
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "İşletme başarıyla kaydedildi",
+		"message": "İşletme başarıyla kaydedildi, lütfen e-posta adresinizi doğrulayın",
 		"partner": partner.ToResponse(),
 	})
 }
@@ -121,36 +175,118 @@ func (h *PartnerHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
+	// Reserve the attempt before checking the password; rejected if the email or IP is throttled
+	ip := c.IP()
+	throttle, err := h.throttle.Reserve(c.Context(), loginData.Email, ip)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+	if !throttle.Allowed {
+		return throttledResponse(c, throttle)
+	}
+
 	var partner models.Partner
-	err := h.collection.FindOne(context.Background(), bson.M{
+	err = h.collection.FindOne(context.Background(), bson.M{
 		"email": loginData.Email,
 	}).Decode(&partner)
 
+	if err == mongo.ErrNoDocuments {
+		// Staff accounts log in through the same endpoint
+		return h.staffLogin(c, loginData.Email, loginData.Password, ip)
+	}
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Geçersiz email veya şifre",
-			})
-		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Veritabanı hatası",
 		})
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(partner.Password), []byte(loginData.Password)); err != nil {
+		if err := h.throttle.RecordFailure(c.Context(), loginData.Email, ip); err != nil {
+			log.Printf("Failed login could not be recorded for %s: %v", loginData.Email, err)
+		}
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Geçersiz email veya şifre",
 		})
 	}
 
-	// Create JWT token
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["partnerId"] = partner.ID.Hex()
-	claims["exp"] = time.Now().Add(time.Hour * 72).Unix() // 3 gün geçerli
+	if err := h.throttle.RecordSuccess(c.Context(), loginData.Email, ip); err != nil {
+		log.Printf("Login attempts could not be reset for %s: %v", loginData.Email, err)
+	}
+
+	// Only active accounts may log in
+	if ok, err := checkAccountStatus(c, &partner); !ok {
+		return err
+	}
+
+	// Partners with 2FA must confirm a TOTP code before getting tokens
+	if partner.TwoFactor.Enabled {
+		return h.mfaChallenge(c, &partner)
+	}
+
+	return h.completeLogin(c, &partner)
+}
+
+// staffLogin authenticates a staff account. Unknown emails count as failed
+// attempts too, so they can't be told apart from wrong passwords.
+func (h *PartnerHandler) staffLogin(c *fiber.Ctx, email, password, ip string) error {
+	var staff models.StaffUser
+	err := h.staff.FindOne(c.Context(), bson.M{"email": email}).Decode(&staff)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
 
-	// Sign token
-	t, err := token.SignedString([]byte("your-secret-key")) // TODO: Move to config
+	if err == mongo.ErrNoDocuments ||
+		bcrypt.CompareHashAndPassword([]byte(staff.Password), []byte(password)) != nil {
+		if err := h.throttle.RecordFailure(c.Context(), email, ip); err != nil {
+			log.Printf("Failed login could not be recorded for %s: %v", email, err)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Geçersiz email veya şifre",
+		})
+	}
+
+	if err := h.throttle.RecordSuccess(c.Context(), email, ip); err != nil {
+		log.Printf("Login attempts could not be reset for %s: %v", email, err)
+	}
+
+	if staff.Status != models.StaffStatusActive {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Personel hesabı devre dışı",
+			"code":  "ACCOUNT_DISABLED",
+		})
+	}
+
+	// The partner account must be active as well
+	var partner models.Partner
+	if err := h.collection.FindOne(c.Context(), bson.M{"_id": staff.PartnerID}).Decode(&partner); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+	if ok, err := checkAccountStatus(c, &partner); !ok {
+		return err
+	}
+
+	tokens, err := h.issueTokens(c, auth.Principal{PartnerID: partner.ID, UserID: staff.ID, Role: staff.Role})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Token oluşturulamadı",
+		})
+	}
+
+	tokens["message"] = "Giriş başarılı"
+	tokens["partner"] = partner.ToResponse()
+	tokens["user"] = staff
+	return c.JSON(tokens)
+}
+
+// completeLogin opens a session for an authenticated partner and writes the login response
+func (h *PartnerHandler) completeLogin(c *fiber.Ctx, partner *models.Partner) error {
+	tokens, err := h.issueTokens(c, auth.OwnerPrincipal(partner.ID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Token oluşturulamadı",
@@ -160,15 +296,229 @@ func (h *PartnerHandler) Login(c *fiber.Ctx) error {
 	// Clear password before sending response
 	partner.Password = ""
 
+	tokens["message"] = "Giriş başarılı"
+	tokens["partner"] = partner
+	return c.JSON(tokens)
+}
+
+// checkAccountStatus rejects pending and suspended accounts. When it returns
+// false the error response has already been written.
+func checkAccountStatus(c *fiber.Ctx, partner *models.Partner) (bool, error) {
+	switch partner.AccountStatus() {
+	case models.PartnerStatusPending:
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "E-posta adresiniz henüz doğrulanmadı",
+			"code":  "EMAIL_NOT_VERIFIED",
+		})
+	case models.PartnerStatusSuspended:
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Hesabınız askıya alınmış",
+			"code":  "ACCOUNT_SUSPENDED",
+		})
+	}
+	return true, nil
+}
+
+// throttledResponse rejects a login attempt that is delayed or locked out
+func throttledResponse(c *fiber.Ctx, throttle auth.ThrottleResult) error {
+	retryAfter := int(math.Ceil(throttle.RetryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	if throttle.Locked {
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error":      "Çok fazla başarısız deneme nedeniyle hesabınız geçici olarak kilitlendi. Şifrenizi sıfırlayarak kilidi açabilirsiniz",
+			"code":       "ACCOUNT_LOCKED",
+			"retryAfter": retryAfter,
+		})
+	}
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":      "Çok fazla başarısız deneme, lütfen daha sonra tekrar deneyin",
+		"code":       "TOO_MANY_ATTEMPTS",
+		"retryAfter": retryAfter,
+	})
+}
+
+// Refresh exchanges a refresh token for a new access/refresh token pair
+func (h *PartnerHandler) Refresh(c *fiber.Ctx) error {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+
+	session, refreshToken, err := h.sessions.Rotate(c.Context(), req.RefreshToken)
+	if err != nil {
+		switch err {
+		case auth.ErrRefreshTokenReused:
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Refresh token daha önce kullanılmış, oturum sonlandırıldı",
+			})
+		case auth.ErrSessionInvalid, auth.ErrMalformedRefreshToken:
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Oturum geçersiz veya süresi dolmuş",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	// Role may have changed since login, so it is resolved again
+	principal, err := h.sessionPrincipal(c.Context(), session)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+	if principal == nil {
+		if err := h.sessions.Revoke(c.Context(), session.ID); err != nil {
+			log.Printf("Session %s could not be revoked: %v", session.ID.Hex(), err)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Oturum geçersiz veya süresi dolmuş",
+		})
+	}
+
+	tokens, err := h.tokenResponse(session, *principal, refreshToken)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Token oluşturulamadı",
+		})
+	}
+
+	return c.JSON(tokens)
+}
+
+// Logout revokes the session of the current access token
+func (h *PartnerHandler) Logout(c *fiber.Ctx) error {
+	// API key requests have no session to end
+	sessionHex, _ := c.Locals("sessionId").(string)
+	sessionID, err := primitive.ObjectIDFromHex(sessionHex)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz oturum",
+		})
+	}
+
+	if err := h.sessions.Revoke(c.Context(), sessionID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Çıkış yapılamadı",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Çıkış yapıldı",
+	})
+}
+
+// LogoutAll revokes every session of the current user ("log out all devices")
+func (h *PartnerHandler) LogoutAll(c *fiber.Ctx) error {
+	partnerID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	userID, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz kullanıcı ID",
+		})
+	}
+
+	if err := h.sessions.RevokeUser(c.Context(), partnerID, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Çıkış yapılamadı",
+		})
+	}
+
 	return c.JSON(fiber.Map{
-		"message": "Giriş başarılı",
-		"token":   t,
-		"partner": partner,
+		"message": "Tüm cihazlardan çıkış yapıldı",
+	})
+}
+
+// issueTokens opens a new session and returns the token part of the login response
+func (h *PartnerHandler) issueTokens(c *fiber.Ctx, principal auth.Principal) (fiber.Map, error) {
+	session, refreshToken, err := h.sessions.Create(c.Context(), principal, auth.SessionMeta{
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		IP:        c.IP(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return h.tokenResponse(session, principal, refreshToken)
+}
+
+// tokenResponse signs a short-lived access token bound to the session
+func (h *PartnerHandler) tokenResponse(session *models.Session, principal auth.Principal, refreshToken string) (fiber.Map, error) {
+	accessToken, err := h.keys.Sign(jwt.MapClaims{
+		auth.ClaimTokenType: auth.TokenTypeAccess,
+		"partnerId":         principal.PartnerID.Hex(),
+		"userId":            principal.UserID.Hex(),
+		"role":              principal.Role,
+		"sid":               session.ID.Hex(),
+		"exp":               time.Now().Add(h.keys.AccessTokenTTL()).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fiber.Map{
+		"token":        accessToken,
+		"refreshToken": refreshToken,
+		"expiresIn":    int(h.keys.AccessTokenTTL().Seconds()),
+		"role":         principal.Role,
+	}, nil
+}
+
+// sessionPrincipal resolves the user behind a session. It returns nil when a
+// staff account was deleted or disabled after the session was opened.
+func (h *PartnerHandler) sessionPrincipal(ctx context.Context, session *models.Session) (*auth.Principal, error) {
+	if session.UserID.IsZero() || session.UserID == session.PartnerID {
+		principal := auth.OwnerPrincipal(session.PartnerID)
+		return &principal, nil
+	}
+
+	var staff models.StaffUser
+	err := h.staff.FindOne(ctx, bson.M{"_id": session.UserID, "partner_id": session.PartnerID}).Decode(&staff)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if staff.Status != models.StaffStatusActive {
+		return nil, nil
+	}
+
+	return &auth.Principal{PartnerID: staff.PartnerID, UserID: staff.ID, Role: staff.Role}, nil
+}
+
+// validationErrorResponse validator hatalarını Türkçe mesajlarla döndürür
+func validationErrorResponse(c *fiber.Ctx, err error) error {
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	errorMessages := make([]string, len(validationErrors))
+	for i, e := range validationErrors {
+		errorMessages[i] = translateValidationError(e)
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":   "Validation error",
+		"details": errorMessages,
 	})
 }
 
 func translateValidationError(e validator.FieldError) string {
-	switch e.Field() {
+	// Liste elemanlarında alan adı indeksle gelir (ör. OpenHolidays[0])
+	field, _, _ := strings.Cut(e.Field(), "[")
+	switch field {
 	case "CompanyName":
 		return "İşletme adı zorunludur"
 	case "Email":
@@ -181,8 +531,18 @@ func translateValidationError(e validator.FieldError) string {
 			return "Şifre zorunludur"
 		}
 		return "Şifre en az 6 karakter olmalıdır"
+	case "CurrentPassword":
+		return "Mevcut şifre zorunludur"
+	case "NewPassword":
+		if e.Tag() == "required" {
+			return "Yeni şifre zorunludur"
+		}
+		return "Yeni şifre en az 6 karakter olmalıdır"
 	case "PhoneNumber":
-		return "Telefon numarası zorunludur"
+		if e.Tag() == "required" {
+			return "Telefon numarası zorunludur"
+		}
+		return "Geçerli bir telefon numarası giriniz (ör. 0532 123 45 67 veya +90 532 123 45 67)"
 	case "Address":
 		return "Adres zorunludur"
 	case "City":
@@ -190,10 +550,69 @@ func translateValidationError(e validator.FieldError) string {
 	case "BusinessType":
 		return "İşletme kategorisi seçimi zorunludur"
 	case "TaxNumber":
-		return "Vergi numarası zorunludur"
+		if e.Tag() == "required" {
+			return "Vergi numarası zorunludur"
+		}
+		if value, ok := e.Value().(string); ok {
+			if problem := validation.TaxNumberProblem(value); problem != "" {
+				return problem
+			}
+		}
+		return "Geçerli bir vergi numarası giriniz"
 	case "ContactPerson":
 		return "Yetkili kişi bilgisi zorunludur"
+	case "TimeZone":
+		return "Geçerli bir IANA saat dilimi giriniz (ör. Europe/Istanbul)"
+	case "OverlapPolicy":
+		return "Çakışma politikası reject, warn veya allow olmalıdır"
+	case "Day":
+		return "Gün 0 (Pazar) ile 6 (Cumartesi) arasında olmalıdır"
+	case "Open", "Close":
+		return "Açılış ve kapanış saatleri HH:MM biçiminde zorunludur"
+	case "StartDay", "EndDay":
+		return "Kapalı gün tarihleri YYYY-MM-DD biçiminde olmalıdır"
+	case "HolidayCalendar":
+		return "Tatil takvimi tr veya none olmalıdır"
+	case "OpenHolidays":
+		return "Açık tatil günleri YYYY-MM-DD biçiminde olmalıdır"
+	case "HoursPolicy":
+		return "Çalışma saatleri politikası reject, warn veya ignore olmalıdır"
+	case "CustomerName":
+		return "Müşteri adı zorunludur"
+	case "CustomerEmail":
+		if e.Tag() == "required_without" {
+			return "Müşteri e-posta adresi veya telefon numarası zorunludur"
+		}
+		return "Geçerli bir müşteri e-posta adresi giriniz"
+	case "CustomerPhone":
+		if e.Tag() == "required_without" {
+			return "Müşteri e-posta adresi veya telefon numarası zorunludur"
+		}
+		return "Geçerli bir müşteri telefon numarası giriniz"
+	case "PartySize":
+		return "Kişi sayısı en az 1 olmalıdır"
+	case "Notes":
+		return "Not en fazla 500 karakter olabilir"
+	case "Seats":
+		return "Ayrılacak yer sayısı en az 1 olmalıdır"
+	case "TTLSeconds":
+		return "Ayırma süresi 60 ile 1800 saniye arasında olmalıdır"
+	case "Name":
+		if e.Tag() == "max" {
+			return fmt.Sprintf("Ad en fazla %s karakter olabilir", e.Param())
+		}
+		return "Ad zorunludur"
+	case "Role":
+		return "Geçerli bir rol seçiniz (manager, front_desk)"
+	case "Status":
+		return "Geçerli bir durum seçiniz (active, disabled)"
+	case "Scopes":
+		return "Geçersiz scope; reservations:read, reservations:create, reservations:update, reservations:delete, bookings:read veya bookings:manage olmalıdır"
+	case "Token", "ChallengeToken":
+		return "Token zorunludur"
+	case "Code":
+		return "Doğrulama kodu zorunludur"
 	default:
 		return fmt.Sprintf("%s alanı için %s kuralı geçerli değil", e.Field(), e.Tag())
 	}
-} 
\ No newline at end of file
+}
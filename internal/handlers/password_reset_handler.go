@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/mailer"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordResetTTL şifre sıfırlama bağlantısının geçerlilik süresidir
+const passwordResetTTL = time.Hour
+
+// ForgotPassword sends a single-use password reset link to the partner's email.
+// The response is the same whether or not the email exists.
+func (h *PartnerHandler) ForgotPassword(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	response := fiber.Map{
+		"message": "Bu e-posta adresi kayıtlıysa şifre sıfırlama bağlantısı gönderildi",
+	}
+
+	var partner models.Partner
+	err := h.collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&partner)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(response)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	token, err := auth.NewOpaqueToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Şifre sıfırlama isteği oluşturulamadı",
+		})
+	}
+
+	// Yeni bağlantı öncekileri geçersiz kılar
+	now := time.Now()
+	if _, err := h.resets.UpdateMany(ctx,
+		bson.M{"partner_id": partner.ID, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": now}},
+	); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	reset := models.PasswordReset{
+		ID:        primitive.NewObjectID(),
+		PartnerID: partner.ID,
+		TokenHash: auth.HashToken(token),
+		IP:        c.IP(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(passwordResetTTL),
+	}
+	if _, err := h.resets.InsertOne(ctx, reset); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Şifre sıfırlama isteği oluşturulamadı",
+		})
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", h.appURL, url.QueryEscape(token))
+	err = h.mailer.Send(ctx, mailer.Message{
+		To:      partner.Email,
+		Subject: "Planvia şifre sıfırlama",
+		Body: fmt.Sprintf("Merhaba %s,\n\nŞifrenizi sıfırlamak için aşağıdaki bağlantıyı kullanın. "+
+			"Bağlantı 1 saat geçerlidir ve yalnızca bir kez kullanılabilir.\n\n%s\n\n"+
+			"Bu isteği siz yapmadıysanız bu e-postayı dikkate almayın.", partner.ContactPerson, link),
+	})
+	if err != nil {
+		log.Printf("Password reset mail could not be sent to %s: %v", partner.Email, err)
+	}
+
+	return c.JSON(response)
+}
+
+// resetClaimTTL is how long a reset token stays claimed by a request that is
+// updating the password. A claim left by a crashed request expires after it.
+const resetClaimTTL = time.Minute
+
+// ResetPassword sets a new password using a reset token. The token is claimed
+// atomically so concurrent requests cannot both use it, and it is only consumed
+// once the password is updated. All existing sessions are revoked.
+func (h *PartnerHandler) ResetPassword(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Şifre işlenirken bir hata oluştu",
+		})
+	}
+
+	// Token önce kısa süreliğine ayrılır, şifre güncellendikten sonra kullanılmış sayılır.
+	// Güncelleme başarısız olursa ayrılma kaldırılır ve kullanıcı aynı bağlantıyla tekrar dener.
+	now := time.Now()
+	var reset models.PasswordReset
+	err = h.resets.FindOneAndUpdate(ctx,
+		bson.M{
+			"token_hash": auth.HashToken(req.Token),
+			"used_at":    bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": now},
+			"$or": bson.A{
+				bson.M{"claimed_at": bson.M{"$exists": false}},
+				bson.M{"claimed_at": bson.M{"$lt": now.Add(-resetClaimTTL)}},
+			},
+		},
+		bson.M{"$set": bson.M{"claimed_at": now}},
+	).Decode(&reset)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Şifre sıfırlama bağlantısı geçersiz veya süresi dolmuş",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	result, err := h.collection.UpdateOne(ctx,
+		bson.M{"_id": reset.PartnerID},
+		bson.M{"$set": bson.M{"password": string(hashedPassword), "updated_at": now}},
+	)
+	if err != nil || result.MatchedCount == 0 {
+		if _, err := h.resets.UpdateOne(ctx, bson.M{"_id": reset.ID}, bson.M{"$unset": bson.M{"claimed_at": ""}}); err != nil {
+			log.Printf("Password reset claim could not be released for partner %s: %v", reset.PartnerID.Hex(), err)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Şifre güncellenemedi",
+		})
+	}
+	if _, err := h.resets.UpdateOne(ctx, bson.M{"_id": reset.ID}, bson.M{"$set": bson.M{"used_at": now}}); err != nil {
+		log.Printf("Password reset token could not be marked used for partner %s: %v", reset.PartnerID.Hex(), err)
+	}
+
+	// Eski şifreyle açılmış oturumları kapat
+	if err := h.sessions.RevokeUser(ctx, reset.PartnerID, reset.PartnerID); err != nil {
+		log.Printf("Sessions could not be revoked for partner %s: %v", reset.PartnerID.Hex(), err)
+	}
+	if err := h.appPwds.RevokeAll(ctx, reset.PartnerID); err != nil {
+		log.Printf("App passwords could not be revoked for partner %s: %v", reset.PartnerID.Hex(), err)
+	}
+
+	// Başarısız giriş kilidini kaldır; kilitlenen hesaplar bu yolla açılır
+	var partner models.Partner
+	if err := h.collection.FindOne(ctx, bson.M{"_id": reset.PartnerID}).Decode(&partner); err == nil {
+		if err := h.throttle.Reset(ctx, partner.Email); err != nil {
+			log.Printf("Login lockout could not be cleared for %s: %v", partner.Email, err)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Şifreniz başarıyla güncellendi",
+	})
+}
\ No newline at end of file
@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/schedule"
+	"github.com/denizbarcak/planvia-partner-api/internal/validation"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// GetProfile returns the authenticated partner's profile
+func (h *PartnerHandler) GetProfile(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	partner, err := h.currentPartner(ctx, c)
+	if partner == nil {
+		return err
+	}
+
+	return c.JSON(partner.ToResponse())
+}
+
+// calendarSettingsFields are the profile fields that change how reservations are
+// rendered in calendar feeds and CalDAV; changing any of them bumps settings_version.
+var calendarSettingsFields = []string{
+	"company_name", "time_zone", "business_hours", "closures",
+	"holiday_calendar", "open_holidays", "skip_closed_days",
+}
+
+// UpdateProfile applies a validated partial update to the partner profile.
+// Changing the email address marks it unverified and sends a new verification link.
+func (h *PartnerHandler) UpdateProfile(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.UpdateProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	partner, err := h.currentPartner(ctx, c)
+	if partner == nil {
+		return err
+	}
+
+	if req.PhoneNumber != nil {
+		phone, _ := validation.NormalizePhone(*req.PhoneNumber)
+		req.PhoneNumber = &phone
+	}
+	if req.TaxNumber != nil {
+		taxNumber := validation.NormalizeTaxNumber(*req.TaxNumber)
+		req.TaxNumber = &taxNumber
+	}
+
+	set := bson.M{}
+	setIfPresent := func(field string, value *string) {
+		if value != nil {
+			set[field] = *value
+		}
+	}
+	setIfPresent("company_name", req.CompanyName)
+	setIfPresent("phone_number", req.PhoneNumber)
+	setIfPresent("address", req.Address)
+	setIfPresent("city", req.City)
+	setIfPresent("business_type", req.BusinessType)
+	setIfPresent("contact_person", req.ContactPerson)
+	setIfPresent("time_zone", req.TimeZone)
+	setIfPresent("overlap_policy", req.OverlapPolicy)
+	setIfPresent("holiday_calendar", req.HolidayCalendar)
+	setIfPresent("hours_policy", req.HoursPolicy)
+
+	if req.BusinessHours != nil || req.Closures != nil {
+		var hours []models.BusinessHours
+		var closures []models.Closure
+		if req.BusinessHours != nil {
+			hours = *req.BusinessHours
+			set["business_hours"] = hours
+		}
+		if req.Closures != nil {
+			closures = *req.Closures
+			set["closures"] = closures
+		}
+		if err := schedule.Validate(hours, closures); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	if req.OpenHolidays != nil {
+		set["open_holidays"] = *req.OpenHolidays
+	}
+	if req.SkipClosedDays != nil {
+		set["skip_closed_days"] = *req.SkipClosedDays
+	}
+
+	if hidden, err := h.hiddenBookedOccurrences(ctx, partner, req); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Müşteri rezervasyonları kontrol edilemedi",
+		})
+	} else if len(hidden) > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":       "Yeni ayarlar onaylı müşteri rezervasyonu olan gerçekleşmeleri kapalı güne düşürüp atlıyor; önce bu rezervasyonları iptal edin veya taşıyın",
+			"code":        "CLOSES_BOOKED_OCCURRENCES",
+			"occurrences": hidden,
+		})
+	}
+
+	emailChanged := req.Email != nil && *req.Email != partner.Email
+	if emailChanged {
+		// Partner ve personel hesapları aynı giriş ucunu kullandığı için adres ikisinde de benzersiz olmalı
+		if h.collection.FindOne(ctx, bson.M{"email": *req.Email}).Err() != mongo.ErrNoDocuments ||
+			h.staff.FindOne(ctx, bson.M{"email": *req.Email}).Err() != mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Bu e-posta adresi zaten kullanımda",
+			})
+		}
+		set["email"] = *req.Email
+		set["email_verified"] = false
+	}
+
+	if req.TaxNumber != nil && *req.TaxNumber != partner.TaxNumber {
+		existing := h.collection.FindOne(ctx, bson.M{"tax_number": *req.TaxNumber, "_id": bson.M{"$ne": partner.ID}})
+		if existing.Err() != mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Bu vergi numarası zaten kullanımda",
+			})
+		}
+		set["tax_number"] = *req.TaxNumber
+	}
+
+	if len(set) == 0 {
+		return c.JSON(partner.ToResponse())
+	}
+	set["updated_at"] = time.Now()
+	update := bson.M{"$set": set}
+	for _, field := range calendarSettingsFields {
+		if _, ok := set[field]; ok {
+			update["$inc"] = bson.M{"settings_version": 1}
+			break
+		}
+	}
+
+	var updated models.Partner
+	err = h.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": partner.ID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Profil güncellenemedi",
+		})
+	}
+
+	if emailChanged {
+		if err := h.sendVerificationEmail(ctx, &updated); err != nil {
+			log.Printf("Verification mail could not be sent to %s: %v", updated.Email, err)
+		}
+	}
+
+	return c.JSON(updated.ToResponse())
+}
+
+// maxHiddenOccurrences is the most occurrences listed when a settings change is refused
+const maxHiddenOccurrences = 10
+
+// hiddenOccurrence is an upcoming occurrence with confirmed bookings that new settings would skip
+type hiddenOccurrence struct {
+	ReservationID   primitive.ObjectID `json:"reservationId"`
+	OccurrenceStart time.Time          `json:"occurrenceStart"`
+	Reason          string             `json:"reason"`
+}
+
+// hiddenBookedOccurrences returns the upcoming occurrences with confirmed bookings that are
+// listed under the current settings but would be skipped as closed days after req is applied.
+func (h *PartnerHandler) hiddenBookedOccurrences(ctx context.Context, partner *models.Partner, req models.UpdateProfileRequest) ([]hiddenOccurrence, error) {
+	next := *partner
+	if req.TimeZone != nil {
+		next.TimeZone = *req.TimeZone
+	}
+	if req.HolidayCalendar != nil {
+		next.HolidayCalendar = *req.HolidayCalendar
+	}
+	if req.BusinessHours != nil {
+		next.BusinessHours = *req.BusinessHours
+	}
+	if req.Closures != nil {
+		next.Closures = *req.Closures
+	}
+	if req.OpenHolidays != nil {
+		next.OpenHolidays = *req.OpenHolidays
+	}
+	if req.SkipClosedDays != nil {
+		next.SkipClosedDays = *req.SkipClosedDays
+	}
+	// Atlama yalnızca tekrar eden serilerde ve skipClosedDays açıkken olur
+	if !next.SkipClosedDays {
+		return nil, nil
+	}
+
+	cursor, err := h.bookings.Find(ctx, bson.M{
+		"partnerId":       partner.ID,
+		"status":          models.BookingStatusConfirmed,
+		"occurrenceStart": bson.M{"$gte": time.Now()},
+	}, options.Find().SetProjection(bson.M{"reservationId": 1, "occurrenceStart": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var booked []models.Booking
+	if err := cursor.All(ctx, &booked); err != nil {
+		return nil, err
+	}
+	if len(booked) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(booked))
+	for _, b := range booked {
+		ids = append(ids, b.ReservationID)
+	}
+	cursor, err = h.reservations.Find(ctx, bson.M{
+		"_id":                bson.M{"$in": ids},
+		"partnerId":          partner.ID,
+		"recurrence.enabled": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var series []models.Reservation
+	if err := cursor.All(ctx, &series); err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]models.Reservation, len(series))
+	for _, r := range series {
+		byID[r.ID] = r
+	}
+
+	before, after := schedule.New(partner), schedule.New(&next)
+	seen := map[string]bool{}
+	hidden := []hiddenOccurrence{}
+	for _, b := range booked {
+		r, ok := byID[b.ReservationID]
+		key := b.ReservationID.Hex() + "@" + b.OccurrenceStart.String()
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, listed := before.Occurrence(r, b.OccurrenceStart); !listed {
+			continue
+		}
+		if _, listed := after.Occurrence(r, b.OccurrenceStart); listed {
+			continue
+		}
+		hidden = append(hidden, hiddenOccurrence{
+			ReservationID:   r.ID,
+			OccurrenceStart: b.OccurrenceStart,
+			Reason:          after.ClosedReason(b.OccurrenceStart),
+		})
+	}
+	sort.Slice(hidden, func(i, j int) bool { return hidden[i].OccurrenceStart.Before(hidden[j].OccurrenceStart) })
+	if len(hidden) > maxHiddenOccurrences {
+		hidden = hidden[:maxHiddenOccurrences]
+	}
+	return hidden, nil
+}
+
+// ChangePassword sets a new password after checking the current one. It works
+// for the partner account and for staff accounts, and ends the user's other sessions.
+func (h *PartnerHandler) ChangePassword(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	userObjID, err := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz kullanıcı ID",
+		})
+	}
+
+	// Partner hesabının kendisi partners, personel hesapları staff koleksiyonundadır
+	collection, filter := h.collection, bson.M{"_id": partnerObjID}
+	if c.Locals("role") != auth.RoleOwner {
+		collection, filter = h.staff, bson.M{"_id": userObjID, "partner_id": partnerObjID}
+	}
+
+	var account struct {
+		Email    string `bson:"email"`
+		Password string `bson:"password"`
+	}
+	if err := collection.FindOne(ctx, filter).Decode(&account); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Hesap bulunamadı",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	if ok, err := h.checkPassword(ctx, c, account.Email, account.Password, req.CurrentPassword, fiber.Map{
+		"error": "Mevcut şifre hatalı",
+	}); !ok {
+		return err
+	}
+	h.passwordChecked(ctx, c, account.Email)
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Şifre işlenirken bir hata oluştu",
+		})
+	}
+
+	if _, err := collection.UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{"password": string(hashedPassword), "updated_at": time.Now()},
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Şifre güncellenemedi",
+		})
+	}
+
+	// Mevcut oturum açık kalır, diğer cihazlardaki oturumlar kapatılır
+	sessionHex, _ := c.Locals("sessionId").(string)
+	if sessionObjID, err := primitive.ObjectIDFromHex(sessionHex); err == nil {
+		if err := h.sessions.RevokeOthers(ctx, partnerObjID, userObjID, sessionObjID); err != nil {
+			log.Printf("Sessions could not be revoked for user %s: %v", userObjID.Hex(), err)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Şifreniz başarıyla güncellendi",
+	})
+}
+
+// checkPassword compares a re-entered password with the account's hash. Attempts go
+// through the login throttle, so a stolen session cannot brute-force the password.
+// On a throttled or wrong attempt it writes the response (failure for a wrong
+// password) and returns false. Callers reset the counters with passwordChecked once
+// every check of the request has passed.
+func (h *PartnerHandler) checkPassword(ctx context.Context, c *fiber.Ctx, email, hash, password string, failure fiber.Map) (bool, error) {
+	throttle, err := h.throttle.Reserve(ctx, email, c.IP())
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+	if !throttle.Allowed {
+		return false, throttledResponse(c, throttle)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		h.passwordFailed(ctx, c, email)
+		return false, c.Status(fiber.StatusUnauthorized).JSON(failure)
+	}
+	return true, nil
+}
+
+// passwordFailed records a failed check against the login throttle
+func (h *PartnerHandler) passwordFailed(ctx context.Context, c *fiber.Ctx, email string) {
+	if err := h.throttle.RecordFailure(ctx, email, c.IP()); err != nil {
+		log.Printf("Failed password check could not be recorded for %s: %v", email, err)
+	}
+}
+
+// passwordChecked resets the login throttle after a successful check
+func (h *PartnerHandler) passwordChecked(ctx context.Context, c *fiber.Ctx, email string) {
+	if err := h.throttle.RecordSuccess(ctx, email, c.IP()); err != nil {
+		log.Printf("Login attempts could not be reset for %s: %v", email, err)
+	}
+}
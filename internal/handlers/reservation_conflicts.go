@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/lock"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/recurrence"
+	"github.com/denizbarcak/planvia-partner-api/internal/schedule"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// conflictHorizon sonu olmayan serilerde çakışmaların kontrol edildiği süredir
+const conflictHorizon = 366 * 24 * time.Hour
+
+// Partner kilidinin süresi ve kilidi beklerken vazgeçilecek süre
+const (
+	conflictLockTTL  = 10 * time.Second
+	conflictLockWait = 5 * time.Second
+)
+
+// conflictHeader warn politikasında çakışan rezervasyon ID'lerinin döndüğü başlıktır
+const conflictHeader = "X-Reservation-Conflicts"
+
+// reservationConflict çakışan bir rezervasyonu ve ilk çakışan gerçekleşmesini tanımlar
+type reservationConflict struct {
+	ID        primitive.ObjectID `json:"id"`
+	Name      string             `json:"name"`
+	StartDate time.Time          `json:"startDate"`
+	EndDate   time.Time          `json:"endDate"`
+}
+
+// ignoreFunc çakışma kontrolünde dikkate alınmayacak gerçekleşmeleri seçer
+// (ör. güncellenen kaydın kendisi veya yerine geçilen gerçekleşme)
+type ignoreFunc func(r models.Reservation, o recurrence.Occurrence) bool
+
+// guardConflicts aday rezervasyonu partner'ın çalışma saatleri ve çakışma politikalarına göre kontrol eder.
+// reject politikasında partner için kilit alınır, böylece eşzamanlı iki istek aynı
+// aralığı kapatamaz. Dönen fonksiyon kaydetme bittikten sonra çağrılmalıdır; hata
+// durumunda fonksiyon nil döner. warn politikalarının uyarıları warnings'e eklenir.
+func (h *ReservationHandler) guardConflicts(ctx context.Context, partnerObjID primitive.ObjectID, candidate models.Reservation, ignore ignoreFunc, warnings *writeWarnings) (func(), error) {
+	partner, err := h.partnerSettings(ctx, partnerObjID)
+	if err != nil {
+		return nil, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+
+	sched := schedule.New(partner)
+	if err := checkHours(partner, sched, candidate, warnings); err != nil {
+		return nil, err
+	}
+
+	policy := partner.ReservationOverlapPolicy()
+	release := func() {}
+	if policy == models.OverlapPolicyAllow {
+		return release, nil
+	}
+
+	var lease *lock.Lease
+	if policy == models.OverlapPolicyReject {
+		lease, err = h.locks.Acquire(ctx, "reservations:"+partnerObjID.Hex(), conflictLockTTL, conflictLockWait)
+		if err == lock.ErrTimeout {
+			return nil, errReservationsBusy()
+		}
+		if err != nil {
+			return nil, failWrite(fiber.StatusInternalServerError, fiber.Map{
+				"error": "Veritabanı hatası",
+			})
+		}
+		release = func() {
+			if err := lease.Release(context.Background()); err != nil {
+				log.Printf("Reservation lock could not be released for partner %s: %v", partnerObjID.Hex(), err)
+			}
+		}
+	}
+
+	conflicts, err := h.findConflicts(ctx, partnerObjID, sched, candidate, ignore)
+	if err != nil {
+		release()
+		return nil, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Çakışma kontrolü yapılamadı",
+		})
+	}
+	// Kilit kontrol sırasında başka bir isteğe geçtiyse sonuç güvenilir değildir
+	if lease != nil && lease.Lost() {
+		release()
+		return nil, errReservationsBusy()
+	}
+	if len(conflicts) == 0 {
+		return release, nil
+	}
+
+	ids := make([]string, len(conflicts))
+	for i, conflict := range conflicts {
+		ids[i] = conflict.ID.Hex()
+	}
+
+	if policy == models.OverlapPolicyReject {
+		release()
+		return nil, failWrite(fiber.StatusConflict, fiber.Map{
+			"error":       "Bu zaman aralığında başka bir rezervasyon var",
+			"code":        "RESERVATION_CONFLICT",
+			"conflictIds": ids,
+			"conflicts":   conflicts,
+		})
+	}
+
+	// warn: kayıt yapılır, çakışanlar uyarılara eklenir
+	warnings.conflicts = append(warnings.conflicts, ids...)
+	return release, nil
+}
+
+// errReservationsBusy partner kilidi alınamadığında dönen, tekrar denenebilir hatadır
+func errReservationsBusy() *writeError {
+	err := failWrite(fiber.StatusServiceUnavailable, fiber.Map{
+		"error": "Rezervasyonlar şu anda başka bir istekle güncelleniyor, lütfen tekrar deneyin",
+	})
+	err.retryAfter = "1"
+	return err
+}
+
+// findConflicts adayın gerçekleşmeleriyle kesişen diğer rezervasyonları bulur
+func (h *ReservationHandler) findConflicts(ctx context.Context, partnerObjID primitive.ObjectID, sched *schedule.Schedule, candidate models.Reservation, ignore ignoreFunc) ([]reservationConflict, error) {
+	own := candidateOccurrences(sched, candidate)
+	if len(own) == 0 {
+		return nil, nil
+	}
+	windowStart, windowEnd := own[0].Start, own[0].End
+	for _, o := range own {
+		if o.End.After(windowEnd) {
+			windowEnd = o.End
+		}
+	}
+
+	cursor, err := h.db.Collection("reservations").Find(ctx, overlapFilter(partnerObjID, windowStart, windowEnd))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var existing []models.Reservation
+	if err := cursor.All(ctx, &existing); err != nil {
+		return nil, err
+	}
+
+	conflicts := []reservationConflict{}
+	for _, r := range existing {
+		if r.ID == candidate.ID {
+			continue
+		}
+		for _, o := range sched.Expand(r, windowStart, windowEnd) {
+			if ignore != nil && ignore(r, o) {
+				continue
+			}
+			if overlapsAny(own, o) {
+				conflicts = append(conflicts, reservationConflict{ID: r.ID, Name: r.Name, StartDate: o.Start, EndDate: o.End})
+				break
+			}
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].StartDate.Before(conflicts[j].StartDate) })
+	return conflicts, nil
+}
+
+// candidateOccurrences adayın kontrol edilecek gerçekleşmelerini döndürür. Sonu olmayan
+// seriler bugünden itibaren conflictHorizon kadar açılır.
+func candidateOccurrences(sched *schedule.Schedule, candidate models.Reservation) []recurrence.Occurrence {
+	from, to := candidate.StartDate, candidate.EndDate
+	if candidate.Recurrence.Enabled {
+		if now := time.Now(); from.Before(now) {
+			from = now
+		}
+		to = from.Add(conflictHorizon)
+	}
+	if !to.After(from) {
+		to = from.Add(time.Nanosecond)
+	}
+	return sched.Expand(candidate, from, to)
+}
+
+// overlapsAny o'nun başlangıca göre sıralı gerçekleşmelerden biriyle kesişip kesişmediğini
+// döndürür. Biri bitince diğeri başlayan gerçekleşmeler çakışmaz.
+func overlapsAny(sorted []recurrence.Occurrence, o recurrence.Occurrence) bool {
+	i := sort.Search(len(sorted), func(i int) bool { return sorted[i].End.After(o.Start) })
+	return i < len(sorted) && sorted[i].Start.Before(o.End)
+}
+
+// overlapFilter [start, end] aralığına gerçekleşmesi düşebilecek rezervasyonları seçer:
+// tekrar etmeyenler aralıkla kesişiyorsa, tekrar edenler aralık bitmeden başlamışsa
+func overlapFilter(partnerObjID primitive.ObjectID, start, end time.Time) bson.M {
+	return bson.M{
+		"partnerId": partnerObjID,
+		"$or": bson.A{
+			bson.M{
+				"recurrence.enabled": bson.M{"$ne": true},
+				"startDate":          bson.M{"$lte": end},
+				"endDate":            bson.M{"$gte": start},
+			},
+			bson.M{
+				"recurrence.enabled": true,
+				"startDate":          bson.M{"$lte": end},
+			},
+		},
+	}
+}
@@ -2,10 +2,22 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"sort"
 	"time"
 
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/booking"
+	"github.com/denizbarcak/planvia-partner-api/internal/events"
+	"github.com/denizbarcak/planvia-partner-api/internal/lock"
 	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/recurrence"
+	"github.com/denizbarcak/planvia-partner-api/internal/schedule"
+	"github.com/denizbarcak/planvia-partner-api/internal/sequence"
+	"github.com/denizbarcak/planvia-partner-api/internal/tombstones"
+	"github.com/denizbarcak/planvia-partner-api/internal/validation"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,12 +25,48 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// dayLayout tüm gün rezervasyonların yerel gün biçimidir
+const dayLayout = "2006-01-02"
+
+// partnerSettingsProjection rezervasyon ve müsaitlik hesaplarında kullanılan partner alanlarıdır
+var partnerSettingsProjection = bson.M{
+	"status":           1,
+	"company_name":     1,
+	"time_zone":        1,
+	"overlap_policy":   1,
+	"business_hours":   1,
+	"closures":         1,
+	"holiday_calendar": 1,
+	"open_holidays":    1,
+	"hours_policy":     1,
+	"skip_closed_days": 1,
+	"settings_version": 1,
+}
+
 type ReservationHandler struct {
-	db *mongo.Database
+	db         *mongo.Database
+	locks      *lock.Locker
+	seats      *booking.Seats
+	holds      *booking.Holds
+	events     *events.Outbox
+	feeds      *auth.CalendarFeedStore
+	tombstones *tombstones.Log
+	changes    *sequence.Counter
+	validate   *validator.Validate
 }
 
 func NewReservationHandler(db *mongo.Database) *ReservationHandler {
-	return &ReservationHandler{db: db}
+	return &ReservationHandler{
+		db:         db,
+		locks:      lock.NewLocker(db),
+		seats:      booking.NewSeats(db),
+		holds:      booking.NewHolds(db),
+		events:     events.NewOutbox(db),
+		feeds:      auth.NewCalendarFeedStore(db),
+		tombstones: tombstones.NewLog(db),
+		changes:    sequence.NewCounter(db, "reservations"),
+		validate:   validation.New(),
+	}
 }
 
 // CreateReservation yeni bir rezervasyon oluşturur
@@ -47,6 +95,19 @@ func (h *ReservationHandler) CreateReservation(c *fiber.Ctx) error {
 		})
 	}
 
+	// Saat dilimini ve tüm gün rezervasyonların yerel günlerini hazırla
+	partnerLoc, err := h.partnerLocation(context.Background(), partnerObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+	if err := prepareSchedule(&reservation, partnerLoc); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	// Tarihleri kontrol et
 	if reservation.StartDate.IsZero() {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -66,22 +127,75 @@ func (h *ReservationHandler) CreateReservation(c *fiber.Ctx) error {
 		})
 	}
 
-	// Rezervasyon nesnesini hazırla
-	now := time.Now()
+	// Tekrar desenini kontrol et
+	if err := prepareRecurrence(&reservation.Recurrence, reservation.Location(partnerLoc)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Tek gerçekleşme kayıtları yalnızca scope=this güncellemesiyle, takvim uygulaması kaynağı
+	// yalnızca .ics içe aktarması ve CalDAV ile belirlenir
+	reservation.SeriesID = nil
+	reservation.RecurrenceID = nil
+	reservation.ImportUID = ""
+	reservation.ResourceName = ""
 	reservation.ID = primitive.NewObjectID()
+
+	created, warnings, err := h.createReservation(context.Background(), partnerObjID, reservation)
+	warnings.setHeaders(c)
+	if err != nil {
+		return respondWriteError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// createReservation doğrulanmış rezervasyonu çakışma kontrolünden sonra kaydeder. API'den,
+// içe aktarmadan ve CalDAV'dan oluşturulan rezervasyonlar bu yoldan geçer.
+func (h *ReservationHandler) createReservation(ctx context.Context, partnerObjID primitive.ObjectID, reservation models.Reservation) (models.Reservation, writeWarnings, error) {
 	reservation.PartnerID = partnerObjID
+
+	var warnings writeWarnings
+	if err := h.setLastEnd(ctx, partnerObjID, &reservation); err != nil {
+		return reservation, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+
+	// Çakışmaları partner'ın politikasına göre kontrol et
+	release, err := h.guardConflicts(ctx, partnerObjID, reservation, nil, &warnings)
+	if release == nil {
+		return reservation, warnings, err
+	}
+	defer release()
+
+	// Değişiklik numarası ve zamanlar kayıttan hemen önce alınır
+	seq, done, err := h.stampChange(ctx, partnerObjID)
+	if err != nil {
+		return reservation, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon kaydedilemedi",
+		})
+	}
+	defer done()
+	now := time.Now()
 	reservation.CreatedAt = now
 	reservation.UpdatedAt = now
+	reservation.ChangeSeq = seq
 
 	// Veritabanına kaydet
-	_, err = h.db.Collection("reservations").InsertOne(context.Background(), reservation)
+	_, err = h.db.Collection("reservations").InsertOne(ctx, reservation)
+	if mongo.IsDuplicateKeyError(err) {
+		return reservation, warnings, failWrite(fiber.StatusConflict, fiber.Map{
+			"error": "Bu etkinlik zaten kayıtlı",
+		})
+	}
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		return reservation, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
 			"error": "Rezervasyon kaydedilemedi",
 		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(reservation)
+	return reservation, warnings, nil
 }
 
 // GetPartnerReservations partner'a ait rezervasyonları getirir
@@ -101,57 +215,334 @@ func (h *ReservationHandler) GetPartnerReservations(c *fiber.Ctx) error {
 
 	// Filtreleri oluştur
 	filter := bson.M{"partnerId": partnerObjID}
-	
+
 	// Tarih filtreleri varsa ekle
-	if startStr != "" && endStr != "" {
-		startDate, err := time.Parse(time.RFC3339, startStr)
+	var windowStart, windowEnd time.Time
+	hasWindow := startStr != "" && endStr != ""
+	if hasWindow {
+		windowStart, err = time.Parse(time.RFC3339, startStr)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "Geçersiz başlangıç tarihi formatı",
 			})
 		}
 
-		endDate, err := time.Parse(time.RFC3339, endStr)
+		windowEnd, err = time.Parse(time.RFC3339, endStr)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "Geçersiz bitiş tarihi formatı",
 			})
 		}
 
-		filter["$or"] = bson.A{
-			bson.M{
-				"startDate": bson.M{
-					"$gte": startDate,
-					"$lte": endDate,
-				},
-			},
-			bson.M{
-				"endDate": bson.M{
-					"$gte": startDate,
-					"$lte": endDate,
-				},
-			},
+		if !windowEnd.After(windowStart) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Bitiş tarihi başlangıç tarihinden sonra olmalıdır",
+			})
 		}
 	}
 
-	// Rezervasyonları getir
-	cursor, err := h.db.Collection("reservations").Find(context.Background(), filter)
+	// Sıralama, filtreler ve sayfa
+	query, err := parseReservationQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Sayfalanan listede seriler her istekte cursor'a kadar açıldığı için pencere sınırlıdır;
+	// eski istemcilerin sayfasız istekleri her pencereyle çalışmaya devam eder
+	if hasWindow && query.limit > 0 && windowEnd.Sub(windowStart) > maxPagedWindow {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "limit veya cursor verildiğinde aralık en fazla 62 gün olabilir",
+		})
+	}
+
+	sched, err := h.partnerSchedule(context.Background(), partnerObjID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Rezervasyonlar getirilemedi",
+			"error": "Partner bilgileri getirilemedi",
 		})
 	}
-	defer cursor.Close(context.Background())
 
+	// Seriler açılmıyorsa her kayıt bir sonuçtur; sıralama ve sayfalama veritabanında yapılır.
+	// Pencerede tekrar etmeyenler veritabanında sayfalanır, seriler açıldıktan sonra
+	// hepsi birlikte sıralanıp sayfalanır.
 	var reservations []models.Reservation
-	if err := cursor.All(context.Background(), &reservations); err != nil {
+	if hasWindow {
+		reservations, err = h.windowReservations(context.Background(), partnerObjID, query, sched, windowStart, windowEnd)
+	} else {
+		query.apply(filter)
+		findOptions := options.Find().SetSort(query.sortOrder())
+		if query.after != nil {
+			filter["$and"] = bson.A{query.keyset(query.after)}
+		}
+		if query.limit > 0 {
+			findOptions.SetLimit(int64(query.limit) + 1)
+		}
+		reservations, err = h.findReservations(context.Background(), filter, findOptions)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyonlar getirilemedi",
+		})
+	}
+
+	// Gerçekleşmelerin dolu yer sayıları
+	ids := make([]primitive.ObjectID, len(reservations))
+	for i, reservation := range reservations {
+		ids[i] = reservation.ID
+	}
+	counts, err := h.seats.Counts(context.Background(), ids)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Rezervasyonlar parse edilemedi",
+			"error": "Dolu yer sayıları getirilemedi",
 		})
 	}
 
-	return c.JSON(reservations)
-} 
+	instances := []models.ReservationInstance{}
+	for _, reservation := range reservations {
+		// Pencere verilmezse seriler açılmaz, yalnızca ilk gerçekleşme döner
+		if !hasWindow {
+			instances = append(instances, newReservationInstance(reservation, recurrence.Occurrence{
+				Start: reservation.StartDate,
+				End:   reservation.EndDate,
+			}, counts))
+			continue
+		}
+		for _, occurrence := range query.expand(sched, reservation, windowStart, windowEnd) {
+			instances = append(instances, newReservationInstance(reservation, occurrence, counts))
+		}
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return query.compare(instances[i], instances[j]) < 0
+	})
+
+	// limit veya cursor verilmezse eski istemciler için tüm sonuçlar dizi olarak döner
+	if query.limit == 0 {
+		return c.JSON(instances)
+	}
+	instances, next := query.page(instances)
+	return c.JSON(models.ReservationPage{
+		Reservations: instances,
+		NextCursor:   next,
+		HasMore:      next != "",
+	})
+}
+
+// windowReservations [from, to] penceresinde gerçekleşmesi olan rezervasyonları getirir.
+// Tekrar etmeyenler veritabanında sıralanır ve cursor'dan sonra yalnızca sayfayı dolduracak
+// kadarı okunur. Tekrar eden serilerden pencere bitmeden başlayan ve pencereden önce bitmemiş
+// olanların hepsi okunur; sonsuz seriler hiç bitmediği için bu okuma partner'ın seri sayısıyla
+// büyür. Her seriden açılan gerçekleşmeleri reservationQuery.expand sayfayla sınırlar.
+func (h *ReservationHandler) windowReservations(ctx context.Context, partnerObjID primitive.ObjectID, query *reservationQuery, sched *schedule.Schedule, from, to time.Time) ([]models.Reservation, error) {
+	matches := bson.M{}
+	query.apply(matches)
+
+	// Son bitişi kaydedilmemiş seriler (sonsuz seriler ve eski kayıtlar) her zaman okunur
+	series, err := h.findReservations(ctx, bson.M{"$and": bson.A{
+		bson.M{
+			"partnerId":          partnerObjID,
+			"recurrence.enabled": true,
+			"startDate":          bson.M{"$lte": to},
+			"recurrence.lastEnd": bson.M{"$not": bson.M{"$lt": from.Add(-lastEndSlack)}},
+		},
+		matches,
+	}}, options.Find())
+	if err != nil {
+		return nil, err
+	}
+
+	// Pencere sınırına değen kayıtlar açıldığında düşebilir; sayfa dolana veya kayıtlar
+	// bitene kadar kaldığı yerden okunmaya devam edilir.
+	oneOff := bson.M{
+		"partnerId":          partnerObjID,
+		"recurrence.enabled": bson.M{"$ne": true},
+		"startDate":          bson.M{"$lte": to},
+		"endDate":            bson.M{"$gte": from},
+	}
+	after := query.after
+	kept := 0
+	for {
+		clauses := bson.A{oneOff, matches}
+		if after != nil {
+			clauses = append(clauses, query.keyset(after))
+		}
+		want := query.limit + 1 - kept
+		findOptions := options.Find().SetSort(query.sortOrder())
+		if query.limit > 0 {
+			findOptions.SetLimit(int64(want))
+		}
+		batch, err := h.findReservations(ctx, bson.M{"$and": clauses}, findOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, reservation := range batch {
+			if len(sched.Expand(reservation, from, to)) > 0 {
+				series = append(series, reservation)
+				kept++
+			}
+		}
+		if query.limit == 0 || kept > query.limit || len(batch) < want {
+			return series, nil
+		}
+		after = query.rowCursor(batch[len(batch)-1])
+	}
+}
+
+// findReservations filtreye uyan rezervasyonları getirir
+func (h *ReservationHandler) findReservations(ctx context.Context, filter bson.M, findOptions *options.FindOptions) ([]models.Reservation, error) {
+	cursor, err := h.db.Collection("reservations").Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reservations []models.Reservation
+	if err := cursor.All(ctx, &reservations); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// prepareSchedule saat dilimini doğrular ve tüm gün rezervasyonları yerel günlere sabitler:
+// StartDate ilk günün, EndDate son günü izleyen günün yerel gece yarısı olur.
+func prepareSchedule(r *models.Reservation, partnerLoc *time.Location) error {
+	if r.TimeZone != "" {
+		if _, err := time.LoadLocation(r.TimeZone); err != nil {
+			return errors.New("Geçersiz saat dilimi; IANA biçiminde olmalıdır (ör. Europe/Istanbul)")
+		}
+	}
+
+	if !r.IsAllDay {
+		r.StartDay, r.EndDay = "", ""
+		return nil
+	}
+
+	loc := r.Location(partnerLoc)
+
+	// Gün gönderilmediyse eski istemcilerin gönderdiği zamanlardan yerel tarih çıkarılır
+	if r.StartDay == "" {
+		if r.StartDate.IsZero() {
+			return nil
+		}
+		r.StartDay = r.StartDate.In(loc).Format(dayLayout)
+	}
+	if r.EndDay == "" {
+		r.EndDay = r.StartDay
+		if !r.StartDate.IsZero() && r.EndDate.After(r.StartDate) {
+			r.EndDay = r.EndDate.Add(-time.Nanosecond).In(loc).Format(dayLayout)
+		}
+	}
+
+	startDay, err := time.ParseInLocation(dayLayout, r.StartDay, loc)
+	if err != nil {
+		return errors.New("Geçersiz başlangıç günü; YYYY-MM-DD biçiminde olmalıdır")
+	}
+	endDay, err := time.ParseInLocation(dayLayout, r.EndDay, loc)
+	if err != nil {
+		return errors.New("Geçersiz bitiş günü; YYYY-MM-DD biçiminde olmalıdır")
+	}
+	if endDay.Before(startDay) {
+		return errors.New("Bitiş günü başlangıç gününden önce olamaz")
+	}
+
+	y, m, d := endDay.Date()
+	r.StartDate = startDay
+	r.EndDate = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	return nil
+}
+
+// partnerSettings partner'ın rezervasyonları etkileyen ayarlarını (saat dilimi, çakışma politikası,
+// çalışma saatleri ve tatiller) getirir
+func (h *ReservationHandler) partnerSettings(ctx context.Context, partnerObjID primitive.ObjectID) (*models.Partner, error) {
+	var partner models.Partner
+	err := h.db.Collection("partners").FindOne(ctx,
+		bson.M{"_id": partnerObjID},
+		options.FindOne().SetProjection(partnerSettingsProjection),
+	).Decode(&partner)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	return &partner, nil
+}
+
+// partnerSchedule partner'ın çalışma takvimini döndürür
+func (h *ReservationHandler) partnerSchedule(ctx context.Context, partnerObjID primitive.ObjectID) (*schedule.Schedule, error) {
+	partner, err := h.partnerSettings(ctx, partnerObjID)
+	if err != nil {
+		return nil, err
+	}
+	return schedule.New(partner), nil
+}
+
+// setLastEnd tekrar eden rezervasyonun son gerçekleşmesinin bitişini desene yazar; liste
+// pencereden önce bitmiş serileri bununla eler
+func (h *ReservationHandler) setLastEnd(ctx context.Context, partnerObjID primitive.ObjectID, r *models.Reservation) error {
+	r.Recurrence.LastEnd = nil
+	if !r.Recurrence.Enabled {
+		return nil
+	}
+	partnerLoc, err := h.partnerLocation(ctx, partnerObjID)
+	if err != nil {
+		return err
+	}
+	r.Recurrence.LastEnd = seriesLastEnd(*r, partnerLoc)
+	return nil
+}
+
+// seriesLastEnd serinin partner'ın saat diliminde açıldığındaki son bitişidir; tekrar
+// etmeyen rezervasyonlarda ve sonsuz serilerde nil döner
+func seriesLastEnd(r models.Reservation, partnerLoc *time.Location) *time.Time {
+	if !r.Recurrence.Enabled {
+		return nil
+	}
+	return recurrence.LastEnd(r, partnerLoc)
+}
+
+// partnerLocation partner'ın saat dilimini döndürür
+func (h *ReservationHandler) partnerLocation(ctx context.Context, partnerObjID primitive.ObjectID) (*time.Location, error) {
+	partner, err := h.partnerSettings(ctx, partnerObjID)
+	if err != nil {
+		return nil, err
+	}
+	return partner.Location(), nil
+}
+
+// prepareRecurrence tekrar desenini doğrular ve RRULE metniyle yapılandırılmış alanları
+// eşitler. Yalnızca RRULE gönderilmişse desen ondan okunur. Yapılandırılmış alanlar da
+// gönderilmişse onlar geçerlidir; istemcinin geri gönderdiği eski RRULE değişikliği ezmez.
+// Saat dilimi belirtilmeyen UNTIL değerleri loc'ta okunur.
+func prepareRecurrence(p *models.RecurrencePattern, loc *time.Location) error {
+	if p.RRule != "" && p.Type == "" {
+		parsed, err := recurrence.Parse(p.RRule, loc)
+		if err != nil {
+			return err
+		}
+		*p = parsed
+		return nil
+	}
+
+	if err := recurrence.Validate(*p); err != nil {
+		return err
+	}
+	p.RRule = recurrence.Format(*p, loc)
+	return nil
+}
+
+// newReservationInstance rezervasyonun verilen gerçekleşmesini dolu ve kalan yerleriyle oluşturur
+func newReservationInstance(reservation models.Reservation, occurrence recurrence.Occurrence, counts booking.Counts) models.ReservationInstance {
+	return models.ReservationInstance{
+		Reservation:     reservation,
+		StartDate:       occurrence.Start,
+		EndDate:         occurrence.End,
+		SeriesStartDate: reservation.StartDate,
+		SeriesEndDate:   reservation.EndDate,
+		OccurrenceIndex: occurrence.Index,
+		BookedSeats:     counts.Booked(reservation.ID, occurrence.Start),
+		RemainingSeats:  counts.Remaining(reservation.ID, occurrence.Start, reservation.Capacity),
+	}
+}
 
 // UpdateReservation günceller bir rezervasyonu
 func (h *ReservationHandler) UpdateReservation(c *fiber.Ctx) error {
@@ -188,6 +579,19 @@ func (h *ReservationHandler) UpdateReservation(c *fiber.Ctx) error {
 		})
 	}
 
+	// Saat dilimini ve tüm gün rezervasyonların yerel günlerini hazırla
+	partnerLoc, err := h.partnerLocation(context.Background(), partnerObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+	if err := prepareSchedule(&updateData, partnerLoc); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	// Tarihleri kontrol et
 	if updateData.StartDate.IsZero() {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -207,29 +611,185 @@ func (h *ReservationHandler) UpdateReservation(c *fiber.Ctx) error {
 		})
 	}
 
+	// Tekrar desenini kontrol et
+	if err := prepareRecurrence(&updateData.Recurrence, updateData.Location(partnerLoc)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Tekrar eden serilerde tek bir gerçekleşme veya sonrakiler ayrıca güncellenebilir
+	scope, occurrence, err := seriesScope(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	ctx := context.Background()
+	var updated models.Reservation
+	var warnings writeWarnings
+	switch scope {
+	case scopeThis:
+		updated, warnings, err = h.updateOccurrence(ctx, partnerObjID, reservationObjID, occurrence, updateData)
+	case scopeFollowing:
+		updated, warnings, err = h.updateFollowing(ctx, partnerObjID, reservationObjID, occurrence, updateData)
+	default:
+		updated, warnings, err = h.updateSeries(ctx, partnerObjID, reservationObjID, updateData)
+	}
+	warnings.setHeaders(c)
+	if err != nil {
+		return respondWriteError(c, err)
+	}
+
+	// Seriyi bölen güncelleme yeni bir seri oluşturur
+	if scope == scopeFollowing && updated.ID != reservationObjID {
+		return c.Status(fiber.StatusCreated).JSON(updated)
+	}
+	return c.JSON(updated)
+}
+
+// DeleteReservation bir rezervasyonu siler
+func (h *ReservationHandler) DeleteReservation(c *fiber.Ctx) error {
+	// Partner ID'yi context'ten al
+	partnerID := c.Locals("partnerId").(string)
+	partnerObjID, err := primitive.ObjectIDFromHex(partnerID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	// Rezervasyon ID'yi URL'den al
+	reservationID := c.Params("id")
+	reservationObjID, err := primitive.ObjectIDFromHex(reservationID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz rezervasyon ID",
+		})
+	}
+
+	// Tekrar eden serilerde tek bir gerçekleşme veya sonrakiler ayrıca silinebilir
+	scope, occurrence, err := seriesScope(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	ctx := context.Background()
+	var message string
+	switch scope {
+	case scopeThis:
+		err = h.deleteOccurrence(ctx, partnerObjID, reservationObjID, occurrence)
+		message = "Rezervasyon gerçekleşmesi iptal edildi"
+	case scopeFollowing:
+		err = h.deleteFollowing(ctx, partnerObjID, reservationObjID, occurrence)
+		message = "Seçilen ve sonraki gerçekleşmeler silindi"
+	default:
+		err = h.deleteSeries(ctx, partnerObjID, reservationObjID)
+		message = "Rezervasyon başarıyla silindi"
+	}
+	if err != nil {
+		return respondWriteError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": message,
+	})
+}
+
+// updateSeries rezervasyonu (tekrar ediyorsa tüm seriyi) günceller
+func (h *ReservationHandler) updateSeries(ctx context.Context, partnerObjID, reservationObjID primitive.ObjectID, updateData models.Reservation) (models.Reservation, writeWarnings, error) {
 	// Rezervasyonun mevcut olduğunu ve bu partner'a ait olduğunu kontrol et
 	filter := bson.M{
 		"_id":       reservationObjID,
 		"partnerId": partnerObjID,
 	}
 
-	// Güncellenecek alanları hazırla
+	var existing models.Reservation
+	var warnings writeWarnings
+	if err := h.db.Collection("reservations").FindOne(ctx, filter).Decode(&existing); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return existing, warnings, failWrite(fiber.StatusNotFound, fiber.Map{
+				"error": "Rezervasyon bulunamadı veya bu partner'a ait değil",
+			})
+		}
+		return existing, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon getirilemedi",
+		})
+	}
+
+	// Tek bir gerçekleşmenin yerine geçen kayıt tekrar edemez
+	if existing.SeriesID != nil {
+		updateData.Recurrence = models.RecurrencePattern{}
+	}
+
+	// Kapasite onaylı müşteri rezervasyonlarının altına düşürülemez
+	if updateData.Capacity < existing.Capacity {
+		if err := h.checkBookedCapacity(ctx, reservationObjID, time.Time{}, updateData.Capacity); err != nil {
+			return existing, warnings, err
+		}
+	}
+
+	// Başlangıç kaydırıldıysa iptal edilen ve ayrı düzenlenmiş gerçekleşmeler de aynı kadar
+	// kayar; yoksa eski saatlerinde kalıp yeni gerçekleşmelerle eşleşmezler
+	shift := updateData.StartDate.Sub(existing.StartDate)
+	exceptions := existing.ExceptionDates
+	if shift != 0 && len(exceptions) > 0 {
+		exceptions = make([]time.Time, len(existing.ExceptionDates))
+		for i, ex := range existing.ExceptionDates {
+			exceptions[i] = ex.Add(shift)
+		}
+	}
+
+	// Çakışmaları kontrol et; serinin iptal edilen gerçekleşmeleri ve ayrı düzenlenmiş
+	// gerçekleşmeleri kendisiyle çakışmaz
+	candidate := updateData
+	candidate.ID = reservationObjID
+	candidate.ExceptionDates = exceptions
+	release, err := h.guardConflicts(ctx, partnerObjID, candidate, func(r models.Reservation, _ recurrence.Occurrence) bool {
+		return r.SeriesID != nil && *r.SeriesID == reservationObjID
+	}, &warnings)
+	if release == nil {
+		return existing, warnings, err
+	}
+	defer release()
+
+	if err := h.setLastEnd(ctx, partnerObjID, &updateData); err != nil {
+		return existing, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+
+	// Güncellenecek alanları hazırla; değişiklik numarası ve zaman yazmadan hemen önce alınır
+	seq, done, err := h.stampChange(ctx, partnerObjID)
+	if err != nil {
+		return existing, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon güncellenirken bir hata oluştu",
+		})
+	}
+	defer done()
+	now := time.Now()
 	update := bson.M{
 		"$set": bson.M{
-			"name":       updateData.Name,
-			"startDate":  updateData.StartDate,
-			"endDate":    updateData.EndDate,
-			"isAllDay":   updateData.IsAllDay,
-			"isMultiDay": updateData.IsMultiDay,
-			"capacity":   updateData.Capacity,
-			"recurrence": updateData.Recurrence,
-			"updatedAt":  time.Now(),
+			"name":           updateData.Name,
+			"startDate":      updateData.StartDate,
+			"endDate":        updateData.EndDate,
+			"isAllDay":       updateData.IsAllDay,
+			"startDay":       updateData.StartDay,
+			"endDay":         updateData.EndDay,
+			"timeZone":       updateData.TimeZone,
+			"isMultiDay":     updateData.IsMultiDay,
+			"capacity":       updateData.Capacity,
+			"recurrence":     updateData.Recurrence,
+			"exceptionDates": exceptions,
+			"updatedAt":      now,
+			"changeSeq":      seq,
 		},
 	}
 
 	// Güncelleme işlemini gerçekleştir
 	result := h.db.Collection("reservations").FindOneAndUpdate(
-		context.Background(),
+		ctx,
 		filter,
 		update,
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
@@ -237,11 +797,11 @@ func (h *ReservationHandler) UpdateReservation(c *fiber.Ctx) error {
 
 	if result.Err() != nil {
 		if result.Err() == mongo.ErrNoDocuments {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			return existing, warnings, failWrite(fiber.StatusNotFound, fiber.Map{
 				"error": "Rezervasyon bulunamadı veya bu partner'a ait değil",
 			})
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		return existing, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
 			"error": "Rezervasyon güncellenirken bir hata oluştu",
 		})
 	}
@@ -249,55 +809,113 @@ func (h *ReservationHandler) UpdateReservation(c *fiber.Ctx) error {
 	// Güncellenmiş rezervasyonu döndür
 	var updatedReservation models.Reservation
 	if err := result.Decode(&updatedReservation); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		return existing, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
 			"error": "Güncellenmiş rezervasyon alınamadı",
 		})
 	}
 
-	return c.JSON(updatedReservation)
-} 
+	if shift != 0 {
+		if _, err := h.db.Collection("reservations").UpdateMany(ctx,
+			bson.M{"seriesId": reservationObjID},
+			mongo.Pipeline{{{Key: "$set", Value: bson.M{
+				"recurrenceId": bson.M{"$add": bson.A{"$recurrenceId", shift.Milliseconds()}},
+				"updatedAt":    now,
+				"changeSeq":    seq,
+			}}}},
+		); err != nil {
+			return existing, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+				"error": "Rezervasyon güncellenirken bir hata oluştu",
+			})
+		}
+	}
 
-// DeleteReservation bir rezervasyonu siler
-func (h *ReservationHandler) DeleteReservation(c *fiber.Ctx) error {
-	// Partner ID'yi context'ten al
-	partnerID := c.Locals("partnerId").(string)
-	partnerObjID, err := primitive.ObjectIDFromHex(partnerID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Geçersiz partner ID",
+	// Başlangıç kaydırıldıysa müşteri rezervasyonları da gerçekleşmeleriyle birlikte kayar
+	if err := h.moveBookings(ctx, reservationObjID, reservationObjID, nil, shift); err != nil {
+		return existing, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Müşteri rezervasyonları güncellenemedi",
 		})
 	}
 
-	// Rezervasyon ID'yi URL'den al
-	reservationID := c.Params("id")
-	reservationObjID, err := primitive.ObjectIDFromHex(reservationID)
+	// Tekrar deseni değiştiyse artık var olmayan gerçekleşmelerin müşteri rezervasyonları iptal edilir
+	partnerLoc, err := h.partnerLocation(ctx, partnerObjID)
+	if err == nil {
+		err = h.cancelOrphanedBookings(ctx, updatedReservation, partnerLoc)
+	}
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Geçersiz rezervasyon ID",
+		return existing, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Müşteri rezervasyonları güncellenemedi",
 		})
 	}
 
+	// Kapasite artırıldıysa bekleme listesindekiler onaylanır
+	h.promoteAfterCapacityChange(ctx, reservationObjID, existing.Capacity, updatedReservation.Capacity)
+
+	return updatedReservation, warnings, nil
+}
+
+// deleteSeries rezervasyonu (tekrar ediyorsa tüm seriyi) siler
+func (h *ReservationHandler) deleteSeries(ctx context.Context, partnerObjID, reservationObjID primitive.ObjectID) error {
 	// Rezervasyonun mevcut olduğunu ve bu partner'a ait olduğunu kontrol et
 	filter := bson.M{
 		"_id":       reservationObjID,
 		"partnerId": partnerObjID,
 	}
 
+	// Serinin ayrı kayıtla değiştirilmiş gerçekleşmeleri de silinecek
+	overrides, err := h.overrideIDs(ctx, reservationObjID, nil)
+	if err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+
+	// Silme kayıtları silmeyle aynı değişiklik numarasını taşır
+	seq, done, err := h.stampChange(ctx, partnerObjID)
+	if err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+	defer done()
+
 	// Silme işlemini gerçekleştir
-	result, err := h.db.Collection("reservations").DeleteOne(context.Background(), filter)
+	result, err := h.db.Collection("reservations").DeleteOne(ctx, filter)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
 			"error": "Rezervasyon silinirken bir hata oluştu",
 		})
 	}
 
 	if result.DeletedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		return failWrite(fiber.StatusNotFound, fiber.Map{
 			"error": "Rezervasyon bulunamadı veya bu partner'a ait değil",
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"message": "Rezervasyon başarıyla silindi",
-	})
-} 
\ No newline at end of file
+	// Serinin ayrı kayıtla değiştirilmiş gerçekleşmeleri de silinir
+	if _, err := h.db.Collection("reservations").DeleteMany(ctx, bson.M{
+		"seriesId":  reservationObjID,
+		"partnerId": partnerObjID,
+	}); err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+
+	// Senkronize olan istemciler silinen kayıtları öğrenir
+	deleted := append(overrides, reservationObjID)
+	if err := h.tombstones.Record(ctx, partnerObjID, deleted, seq); err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+
+	// Silinen gerçekleşmelerin müşteri rezervasyonları iptal edilir
+	if err := h.cancelBookings(ctx, deleted, nil); err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Müşteri rezervasyonları iptal edilemedi",
+		})
+	}
+
+	return nil
+}
\ No newline at end of file
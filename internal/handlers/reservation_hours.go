@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/recurrence"
+	"github.com/denizbarcak/planvia-partner-api/internal/schedule"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// hoursHeader warn politikasında çalışma saatleri dışına düşen gerçekleşmelerin başlangıçlarının döndüğü başlıktır
+const hoursHeader = "X-Reservation-Outside-Hours"
+
+// uncoveredHolidaysHeader gerçekleşmelerin düştüğü, dini bayramları takvimde olmayan yılların döndüğü başlıktır
+const uncoveredHolidaysHeader = "X-Holiday-Calendar-Uncovered"
+
+// maxHoursViolations yanıtta listelenen en fazla uygunsuz gerçekleşme sayısıdır
+const maxHoursViolations = 10
+
+// hoursViolation çalışma saatleri dışına veya kapalı bir güne düşen gerçekleşmedir
+type hoursViolation struct {
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+	Reason    string    `json:"reason"`
+}
+
+// checkHours adayın gerçekleşmelerini partner'ın çalışma saatleri politikasına göre kontrol eder.
+// reject politikasında hata döner; warn politikasında uygunsuz gerçekleşmeler uyarılara eklenir.
+// Dini bayramları takvimde olmayan yıllara düşen gerçekleşmeler her politikada ayrıca bildirilir;
+// bu yıllarda bayram günleri açık sayılır.
+func checkHours(partner *models.Partner, sched *schedule.Schedule, candidate models.Reservation, warnings *writeWarnings) error {
+	occurrences := candidateOccurrences(sched, candidate)
+	warnUncoveredHolidays(sched, occurrences, warnings)
+
+	policy := partner.BusinessHoursPolicy()
+	if policy == models.HoursPolicyIgnore {
+		return nil
+	}
+
+	violations := []hoursViolation{}
+	for _, o := range occurrences {
+		if reason := sched.Violation(candidate, o); reason != "" {
+			violations = append(violations, hoursViolation{StartDate: o.Start, EndDate: o.End, Reason: reason})
+			if len(violations) == maxHoursViolations {
+				break
+			}
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	if policy == models.HoursPolicyReject {
+		return failWrite(fiber.StatusUnprocessableEntity, fiber.Map{
+			"error":      "Rezervasyon işletmenin çalışma saatleri dışında veya kapalı bir güne denk geliyor",
+			"code":       "OUTSIDE_BUSINESS_HOURS",
+			"violations": violations,
+		})
+	}
+
+	// warn: kayıt yapılır, uygunsuz gerçekleşmeler uyarılara eklenir
+	for _, v := range violations {
+		warnings.outsideHours = append(warnings.outsideHours, v.StartDate.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// warnUncoveredHolidays gerçekleşmelerin düştüğü, dini bayramları takvimde olmayan yılları uyarılara ekler
+func warnUncoveredHolidays(sched *schedule.Schedule, occurrences []recurrence.Occurrence, warnings *writeWarnings) {
+	seen := map[int]bool{}
+	for _, o := range occurrences {
+		year := o.Start.In(sched.Location()).Year()
+		if seen[year] || !sched.HolidaysUncovered(o.Start) {
+			continue
+		}
+		seen[year] = true
+		warnings.uncoveredHolidays = append(warnings.uncoveredHolidays, strconv.Itoa(year))
+	}
+}
@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/ical"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/recurrence"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxImportEvents tek bir dosyada içe aktarılabilecek en fazla etkinlik sayısıdır
+const maxImportEvents = 5000
+
+// İçe aktarmada bir etkinlik için yapılan (deneme modunda yapılacak) işlem
+const (
+	importActionCreate = "create"
+	importActionUpdate = "update"
+	importActionSkip   = "skip"
+	importActionFailed = "failed"
+)
+
+// importItem içe aktarma raporundaki bir etkinliktir
+type importItem struct {
+	UID           string              `json:"uid"`
+	RecurrenceID  *time.Time          `json:"recurrenceId,omitempty"`
+	Name          string              `json:"name,omitempty"`
+	StartDate     *time.Time          `json:"startDate,omitempty"`
+	Action        string              `json:"action"`
+	Reason        string              `json:"reason,omitempty"`
+	ReservationID *primitive.ObjectID `json:"reservationId,omitempty"`
+	Conflicts     []string            `json:"conflicts,omitempty"`    // warn politikasında çakışan rezervasyonlar
+	OutsideHours  []string            `json:"outsideHours,omitempty"` // warn politikasında çalışma saatleri dışındaki gerçekleşmeler
+}
+
+// importOp kaydedilecek bir rezervasyondur; existing doluysa güncellemedir. cancel, mevcut
+// serinin dosyada yeni iptal edilen gerçekleşmeleridir.
+type importOp struct {
+	item        *importItem
+	reservation *models.Reservation
+	existing    *models.Reservation
+	cancel      []time.Time
+}
+
+// ImportCalendar bir .ics dosyasındaki etkinlikleri rezervasyon olarak içe aktarır. Etkinlikler
+// UID'leriyle eşleştirilir; aynı dosyayı yeniden yüklemek yeni kayıt oluşturmaz, yalnızca
+// değişenleri günceller. Her etkinlik API'deki ve CalDAV'daki oluşturma/güncelleme yolundan
+// kaydedilir; kaydedilemeyen etkinlikler raporda failed olarak döner, diğerleri kaydedilir.
+// dryRun=true ile hiçbir şey kaydedilmeden rapor döner.
+func (h *ReservationHandler) ImportCalendar(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	dryRun := c.QueryBool("dryRun")
+	capacity := c.QueryInt("capacity", 1)
+	if capacity < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Kapasite en az 1 olmalıdır",
+		})
+	}
+
+	// Dosya multipart "file" alanında veya doğrudan istek gövdesinde gönderilebilir
+	data := c.Body()
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Dosya okunamadı",
+			})
+		}
+		defer f.Close()
+		if data, err = io.ReadAll(f); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Dosya okunamadı",
+			})
+		}
+	}
+	if len(data) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "İçe aktarılacak .ics dosyası gönderilmedi",
+		})
+	}
+
+	partnerLoc, err := h.partnerLocation(ctx, partnerObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+
+	events, err := ical.Parse(data, partnerLoc)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if len(events) > maxImportEvents {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Bir dosyada en fazla 5000 etkinlik içe aktarılabilir",
+		})
+	}
+
+	items, ops, err := h.planImport(ctx, partnerObjID, partnerLoc, events, capacity)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Mevcut rezervasyonlar getirilemedi",
+		})
+	}
+
+	if !dryRun {
+		h.applyImport(context.Background(), partnerObjID, ops)
+	}
+
+	summary := fiber.Map{importActionCreate: 0, importActionUpdate: 0, importActionSkip: 0, importActionFailed: 0}
+	for _, item := range items {
+		summary[item.Action] = summary[item.Action].(int) + 1
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"dryRun":  dryRun,
+		"summary": summary,
+		"items":   items,
+	})
+}
+
+// planImport etkinlikleri mevcut kayıtlarla karşılaştırır ve her biri için yapılacak işlemi
+// belirler. Önce seriler, sonra ayrı düzenlenmiş gerçekleşmeleri (RECURRENCE-ID) işlenir.
+func (h *ReservationHandler) planImport(ctx context.Context, partnerObjID primitive.ObjectID, partnerLoc *time.Location, events []ical.Event, capacity int) ([]*importItem, []importOp, error) {
+	collection := h.db.Collection("reservations")
+
+	uids := make([]string, 0, len(events))
+	for _, e := range events {
+		uids = append(uids, e.UID)
+	}
+	cursor, err := collection.Find(ctx, bson.M{
+		"partnerId": partnerObjID,
+		"importUid": bson.M{"$in": uids},
+		"seriesId":  bson.M{"$exists": false},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	var found []models.Reservation
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, nil, err
+	}
+	existing := map[string]*models.Reservation{}
+	var existingIDs []primitive.ObjectID
+	for i := range found {
+		existing[found[i].ImportUID] = &found[i]
+		existingIDs = append(existingIDs, found[i].ID)
+	}
+
+	// Mevcut serilerin ayrı düzenlenmiş gerçekleşmeleri; Planvia'da düzenlenenler de dahil
+	existingOverrides := map[string]*models.Reservation{}
+	overrideStarts := map[primitive.ObjectID][]time.Time{}
+	if len(existingIDs) > 0 {
+		cursor, err := collection.Find(ctx, bson.M{"partnerId": partnerObjID, "seriesId": bson.M{"$in": existingIDs}})
+		if err != nil {
+			return nil, nil, err
+		}
+		var overrides []models.Reservation
+		if err := cursor.All(ctx, &overrides); err != nil {
+			return nil, nil, err
+		}
+		for i := range overrides {
+			o := &overrides[i]
+			if o.RecurrenceID == nil {
+				continue
+			}
+			existingOverrides[overrideKey(*o.SeriesID, *o.RecurrenceID)] = o
+			overrideStarts[*o.SeriesID] = append(overrideStarts[*o.SeriesID], *o.RecurrenceID)
+		}
+	}
+
+	var items []*importItem
+	var seriesOps, overrideOps []importOp
+	// Dosyada iptal edilen gerçekleşmeler: EXDATE'ler ve iptal edilmiş RECURRENCE-ID'ler
+	cancelled := map[string][]time.Time{}
+	seenUIDs := map[string]bool{}
+	skip := func(item *importItem, reason string) {
+		item.Action, item.Reason = importActionSkip, reason
+	}
+
+	for _, e := range events {
+		if e.RecurrenceID != nil {
+			continue
+		}
+		item := newImportItem(e)
+		items = append(items, item)
+
+		if e.Invalid != "" {
+			skip(item, e.Invalid)
+			continue
+		}
+		if e.Cancelled {
+			skip(item, "Etkinlik iptal edilmiş")
+			continue
+		}
+		if seenUIDs[e.UID] {
+			skip(item, "Dosyada aynı UID'ye sahip başka bir etkinlik var")
+			continue
+		}
+		seenUIDs[e.UID] = true
+
+		r, err := reservationFromEvent(e, partnerLoc)
+		if err != nil {
+			skip(item, err.Error())
+			continue
+		}
+		r.PartnerID = partnerObjID
+		cancelled[e.UID] = append([]time.Time(nil), r.ExceptionDates...)
+
+		op := importOp{item: item, reservation: &r}
+		if prev, ok := existing[e.UID]; ok {
+			r.ID, r.Capacity, r.CreatedAt = prev.ID, prev.Capacity, prev.CreatedAt
+			op.existing = prev
+			// Planvia'da iptal edilen gerçekleşmeler yeniden açılmaz
+			r.ExceptionDates = append(r.ExceptionDates, prev.ExceptionDates...)
+			r.ExceptionDates = append(r.ExceptionDates, overrideStarts[prev.ID]...)
+		} else {
+			r.ID, r.Capacity = primitive.NewObjectID(), capacity
+		}
+		item.ReservationID = &r.ID
+		seriesOps = append(seriesOps, op)
+	}
+
+	series := map[string]*models.Reservation{}
+	for _, op := range seriesOps {
+		series[op.reservation.ImportUID] = op.reservation
+	}
+
+	seen := map[string]bool{}
+	for _, e := range events {
+		if e.RecurrenceID == nil {
+			continue
+		}
+		item := newImportItem(e)
+		items = append(items, item)
+
+		if e.Invalid != "" {
+			skip(item, e.Invalid)
+			continue
+		}
+		s, ok := series[e.UID]
+		if !ok || !s.Recurrence.Enabled {
+			skip(item, "Gerçekleşmenin ait olduğu tekrar eden etkinlik içe aktarılmadı")
+			continue
+		}
+		key := overrideKey(s.ID, *e.RecurrenceID)
+		if seen[key] {
+			skip(item, "Dosyada aynı gerçekleşme birden fazla kez düzenlenmiş")
+			continue
+		}
+		seen[key] = true
+		if _, ok := recurrence.IndexOf(*s, partnerLoc, *e.RecurrenceID); !ok {
+			skip(item, "Serinin bu tarihte bir gerçekleşmesi yok")
+			continue
+		}
+
+		// İptal edilen gerçekleşme seriden çıkarılır. Düzenlenen gerçekleşme de seride iptal
+		// sayılır; yerine geçen kayıt eklenirken işaretlenir.
+		s.ExceptionDates = append(s.ExceptionDates, *e.RecurrenceID)
+		if e.Cancelled {
+			cancelled[e.UID] = append(cancelled[e.UID], *e.RecurrenceID)
+			skip(item, "Gerçekleşme iptal edilmiş; seride iptal olarak işaretlendi")
+			continue
+		}
+
+		r, err := reservationFromEvent(e, partnerLoc)
+		if err != nil {
+			skip(item, err.Error())
+			continue
+		}
+		r.PartnerID = partnerObjID
+		r.Recurrence = models.RecurrencePattern{}
+		r.ExceptionDates = nil
+		r.SeriesID = &s.ID
+		r.RecurrenceID = e.RecurrenceID
+
+		op := importOp{item: item, reservation: &r}
+		if prev, ok := existingOverrides[key]; ok {
+			r.ID, r.Capacity, r.CreatedAt = prev.ID, prev.Capacity, prev.CreatedAt
+			op.existing = prev
+		} else {
+			r.ID, r.Capacity = primitive.NewObjectID(), s.Capacity
+		}
+		item.ReservationID = &r.ID
+		overrideOps = append(overrideOps, op)
+	}
+
+	// Yeni serilerde iptaller kayıtla birlikte yazılır; mevcut serilerde Planvia'da iptal
+	// edilmemiş olanlar ayrıca iptal edilir. Seri kaydırılıyorsa mevcut iptaller de kayar.
+	for i, op := range seriesOps {
+		if op.existing == nil {
+			op.reservation.ExceptionDates = uniqueTimes(cancelled[op.reservation.ImportUID])
+			seriesOps[i] = op
+			continue
+		}
+		shift := op.reservation.StartDate.Sub(op.existing.StartDate)
+		known := map[int64]bool{}
+		for _, t := range append(append([]time.Time{}, op.existing.ExceptionDates...), overrideStarts[op.existing.ID]...) {
+			known[t.Add(shift).UnixNano()] = true
+		}
+		for _, t := range uniqueTimes(cancelled[op.reservation.ImportUID]) {
+			if !known[t.UnixNano()] {
+				seriesOps[i].cancel = append(seriesOps[i].cancel, t)
+			}
+		}
+	}
+
+	var ops []importOp
+	for _, op := range append(seriesOps, overrideOps...) {
+		op.reservation.ExceptionDates = uniqueTimes(op.reservation.ExceptionDates)
+		switch {
+		case op.existing == nil:
+			op.item.Action = importActionCreate
+		case importChanged(*op.existing, *op.reservation):
+			op.item.Action = importActionUpdate
+		default:
+			skip(op.item, "Değişiklik yok")
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return items, ops, nil
+}
+
+// applyImport planlanan işlemleri API'deki ve CalDAV'daki oluşturma/güncelleme yolundan
+// kaydeder; böylece çakışma, çalışma saatleri ve müşteri rezervasyonu kuralları içe aktarmada
+// da geçerlidir. Bir etkinlik kaydedilemezse raporda failed olarak işaretlenir ve diğerleriyle
+// devam edilir; serisi kaydedilemeyen gerçekleşmeler atlanır.
+func (h *ReservationHandler) applyImport(ctx context.Context, partnerObjID primitive.ObjectID, ops []importOp) {
+	failed := map[primitive.ObjectID]bool{}
+
+	for _, op := range ops {
+		r, item := *op.reservation, op.item
+		if r.SeriesID != nil && failed[*r.SeriesID] {
+			item.Action, item.Reason, item.ReservationID = importActionSkip, "Seri kaydedilemedi", nil
+			continue
+		}
+
+		var warnings writeWarnings
+		var err error
+		switch {
+		case r.SeriesID == nil && op.existing == nil:
+			_, warnings, err = h.createReservation(ctx, partnerObjID, r)
+		case r.SeriesID == nil:
+			// Seri alanları değiştiyse seri güncellenir; iptaller ayrıca uygulanır
+			fields := r
+			fields.ExceptionDates = uniqueTimes(op.existing.ExceptionDates)
+			if importChanged(*op.existing, fields) {
+				_, warnings, err = h.updateSeries(ctx, partnerObjID, r.ID, fields)
+			}
+			for _, t := range op.cancel {
+				if err != nil {
+					break
+				}
+				err = h.deleteOccurrence(ctx, partnerObjID, r.ID, t)
+			}
+		case op.existing == nil:
+			_, warnings, err = h.updateOccurrence(ctx, partnerObjID, *r.SeriesID, *r.RecurrenceID, r)
+		default:
+			_, warnings, err = h.updateSeries(ctx, partnerObjID, r.ID, r)
+		}
+
+		item.Conflicts = append(item.Conflicts, warnings.conflicts...)
+		item.OutsideHours = append(item.OutsideHours, warnings.outsideHours...)
+		if err != nil {
+			importFailed(item, err)
+			failed[r.ID] = true
+			if op.existing == nil {
+				item.ReservationID = nil
+			}
+		}
+	}
+}
+
+// importFailed etkinliği kaydedilemedi olarak işaretler; reddedilen yazmanın hata mesajı neden olur
+func importFailed(item *importItem, err error) {
+	item.Action, item.Reason = importActionFailed, "Etkinlik kaydedilemedi"
+	var rejected *writeError
+	if errors.As(err, &rejected) {
+		item.Reason = rejected.Error()
+		return
+	}
+	log.Printf("Imported event %s could not be saved: %v", item.UID, err)
+}
+
+// reservationFromEvent etkinliği rezervasyona çevirir ve API'den gelen rezervasyonlar gibi doğrular
+func reservationFromEvent(e ical.Event, partnerLoc *time.Location) (models.Reservation, error) {
+	r := models.Reservation{
+		Name:           e.Summary,
+		StartDate:      e.Start,
+		EndDate:        e.End,
+		IsAllDay:       e.AllDay,
+		Recurrence:     e.Recurrence,
+		ExceptionDates: e.ExDates,
+		ImportUID:      e.UID,
+	}
+	if r.Name == "" {
+		r.Name = "(Başlıksız)"
+	}
+	// Tekrar eden ve tüm gün etkinliklerde yerel saat önemlidir; saat dilimi partner'ınkinden
+	// farklıysa rezervasyonda saklanır
+	if (e.AllDay || e.Recurrence.Enabled) && e.Location.String() != partnerLoc.String() {
+		r.TimeZone = e.Location.String()
+	}
+
+	if e.AllDay {
+		r.StartDay = e.Start.Format(dayLayout)
+		r.EndDay = e.End.AddDate(0, 0, -1).Format(dayLayout)
+		if r.EndDay < r.StartDay {
+			r.EndDay = r.StartDay
+		}
+	}
+	if err := prepareSchedule(&r, partnerLoc); err != nil {
+		return r, err
+	}
+	if r.Recurrence.Enabled {
+		if err := prepareRecurrence(&r.Recurrence, r.Location(partnerLoc)); err != nil {
+			return r, err
+		}
+	}
+
+	loc := r.Location(partnerLoc)
+	last := r.EndDate
+	if last.After(r.StartDate) {
+		last = last.Add(-time.Nanosecond)
+	}
+	r.IsMultiDay = last.In(loc).Format(dayLayout) != r.StartDate.In(loc).Format(dayLayout)
+	return r, nil
+}
+
+// importChanged içe aktarılan rezervasyonun mevcut kayıttan farklı olup olmadığını döndürür
+func importChanged(existing, r models.Reservation) bool {
+	if existing.Name != r.Name || !existing.StartDate.Equal(r.StartDate) || !existing.EndDate.Equal(r.EndDate) ||
+		existing.IsAllDay != r.IsAllDay || existing.StartDay != r.StartDay || existing.EndDay != r.EndDay ||
+		existing.TimeZone != r.TimeZone || existing.IsMultiDay != r.IsMultiDay ||
+		existing.Recurrence.Enabled != r.Recurrence.Enabled || existing.Recurrence.RRule != r.Recurrence.RRule {
+		return true
+	}
+
+	a, b := uniqueTimes(existing.ExceptionDates), r.ExceptionDates
+	if len(a) != len(b) {
+		return true
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func newImportItem(e ical.Event) *importItem {
+	item := &importItem{UID: e.UID, RecurrenceID: e.RecurrenceID, Name: e.Summary}
+	if !e.Start.IsZero() {
+		start := e.Start
+		item.StartDate = &start
+	}
+	return item
+}
+
+func overrideKey(seriesID primitive.ObjectID, recurrenceID time.Time) string {
+	return seriesID.Hex() + "|" + recurrenceID.UTC().Format(time.RFC3339Nano)
+}
+
+// uniqueTimes zamanları sıralar ve tekrar edenleri çıkarır
+func uniqueTimes(ts []time.Time) []time.Time {
+	sorted := append([]time.Time(nil), ts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	var out []time.Time
+	for _, t := range sorted {
+		if len(out) == 0 || !out[len(out)-1].Equal(t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
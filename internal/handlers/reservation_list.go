@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/recurrence"
+	"github.com/denizbarcak/planvia-partner-api/internal/schedule"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Rezervasyon listesinde varsayılan ve en fazla sayfa boyutu
+const (
+	defaultReservationLimit = 50
+	maxReservationLimit     = 500
+)
+
+// maxPagedWindow sayfalanan listede pencerenin en fazla uzunluğudur; her seriden cursor'a
+// kadar açılan gerçekleşmeleri sınırlar
+const maxPagedWindow = 62 * 24 * time.Hour
+
+// lastEndSlack serilerin kayıtlı son bitişiyle elenirken bırakılan paydır. Saat dilimi
+// olmayan seriler partner'ın saat diliminde açılır; partner saat dilimini değiştirirse
+// serinin sonu en fazla bir günden biraz fazla kayabilir.
+const lastEndSlack = 2 * 24 * time.Hour
+
+// Rezervasyon listesinin sıralanabileceği alanlar
+const (
+	sortStartDate = "startDate"
+	sortCreatedAt = "createdAt"
+	sortName      = "name"
+)
+
+// reservationQuery rezervasyon listesinin sıralama, filtre ve sayfa parametreleridir
+type reservationQuery struct {
+	sort   string
+	desc   bool
+	limit  int // 0 ise sayfalanmaz, tüm sonuçlar döner
+	after  *reservationCursor
+	filter bson.M
+}
+
+// reservationCursor bir önceki sayfanın son kaydının sıralamadaki yeridir. İstemciye opak
+// bir belirteç olarak verilir; sıralama değişirse geçersizdir.
+type reservationCursor struct {
+	Sort      string             `json:"s"`
+	Desc      bool               `json:"d,omitempty"`
+	StartDate time.Time          `json:"t"`
+	CreatedAt time.Time          `json:"c"`
+	Name      string             `json:"n,omitempty"`
+	ID        primitive.ObjectID `json:"id"`
+	Index     int                `json:"i,omitempty"`
+}
+
+// parseReservationQuery listenin sorgu parametrelerini okur
+func parseReservationQuery(c *fiber.Ctx) (*reservationQuery, error) {
+	q := &reservationQuery{sort: sortStartDate, filter: bson.M{}}
+
+	switch s := c.Query("sort"); s {
+	case "", sortStartDate, sortCreatedAt, sortName:
+		if s != "" {
+			q.sort = s
+		}
+	default:
+		return nil, errors.New("sort startDate, createdAt veya name olmalıdır")
+	}
+	switch c.Query("order") {
+	case "", "asc":
+	case "desc":
+		q.desc = true
+	default:
+		return nil, errors.New("order asc veya desc olmalıdır")
+	}
+
+	limitStr, cursorStr := c.Query("limit"), c.Query("cursor")
+	if limitStr != "" || cursorStr != "" {
+		q.limit = defaultReservationLimit
+	}
+	if limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxReservationLimit {
+			return nil, errors.New("limit 1 ile " + strconv.Itoa(maxReservationLimit) + " arasında olmalıdır")
+		}
+		q.limit = limit
+	}
+	if cursorStr != "" {
+		cursor, err := parseReservationCursor(cursorStr)
+		if err != nil || cursor.Sort != q.sort || cursor.Desc != q.desc {
+			return nil, errors.New("Geçersiz cursor; sıralama değiştiyse ilk sayfadan başlayın")
+		}
+		q.after = cursor
+	}
+
+	// Ad içinde büyük/küçük harf duyarsız arama
+	if name := strings.TrimSpace(c.Query("q")); name != "" {
+		q.filter["name"] = bson.M{"$regex": regexp.QuoteMeta(name), "$options": "i"}
+	}
+	if s := c.Query("allDay"); s != "" {
+		allDay, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.New("allDay true veya false olmalıdır")
+		}
+		q.filter["isAllDay"] = allDay
+	}
+	if s := c.Query("recurring"); s != "" {
+		recurring, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.New("recurring true veya false olmalıdır")
+		}
+		if recurring {
+			q.filter["recurrence.enabled"] = true
+		} else {
+			q.filter["recurrence.enabled"] = bson.M{"$ne": true}
+		}
+	}
+
+	capacity := bson.M{}
+	minCapacity, maxCapacity := -1, -1
+	if s := c.Query("minCapacity"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			return nil, errors.New("minCapacity 0 veya daha büyük bir sayı olmalıdır")
+		}
+		minCapacity = v
+		capacity["$gte"] = v
+	}
+	if s := c.Query("maxCapacity"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			return nil, errors.New("maxCapacity 0 veya daha büyük bir sayı olmalıdır")
+		}
+		maxCapacity = v
+		capacity["$lte"] = v
+	}
+	if minCapacity >= 0 && maxCapacity >= 0 && minCapacity > maxCapacity {
+		return nil, errors.New("minCapacity maxCapacity'den büyük olamaz")
+	}
+	if len(capacity) > 0 {
+		q.filter["capacity"] = capacity
+	}
+
+	return q, nil
+}
+
+// apply filtreleri listenin temel filtresine ekler
+func (q *reservationQuery) apply(filter bson.M) {
+	for key, value := range q.filter {
+		filter[key] = value
+	}
+}
+
+// keyset cursor'dan sonraki kayıtları veritabanında seçer
+func (q *reservationQuery) keyset(after *reservationCursor) bson.M {
+	op := "$gt"
+	if q.desc {
+		op = "$lt"
+	}
+	var value interface{}
+	switch q.sort {
+	case sortCreatedAt:
+		value = after.CreatedAt
+	case sortName:
+		value = after.Name
+	default:
+		value = after.StartDate
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{q.sort: bson.M{op: value}},
+		bson.M{q.sort: value, "_id": bson.M{op: after.ID}},
+	}}
+}
+
+// expand r'nin pencerede listelenecek gerçekleşmelerini döndürür. Sayfalanan listede
+// cursor'dan önceki gerçekleşmeler atlanır ve bir seriden sayfayı dolduracak kadarından
+// fazlası tutulmaz: artan sıralamada genişletme sayfa dolunca durur, azalan sıralamada son
+// limit+1 gerçekleşme tutulur. Sayfalanmayan listede Schedule.Expand'in sınırı geçerlidir.
+func (q *reservationQuery) expand(sched *schedule.Schedule, r models.Reservation, from, to time.Time) []recurrence.Occurrence {
+	if q.limit == 0 {
+		return sched.Expand(r, from, to)
+	}
+
+	var position models.ReservationInstance
+	if q.after != nil {
+		position = q.after.instance()
+	}
+	var kept []recurrence.Occurrence
+	sched.Each(r, from, to, func(o recurrence.Occurrence) bool {
+		if q.after != nil && q.compare(occurrenceKey(r, o), position) <= 0 {
+			return true
+		}
+		kept = append(kept, o)
+		if len(kept) > q.limit+1 {
+			kept = kept[1:]
+		}
+		return q.desc || len(kept) <= q.limit
+	})
+	return kept
+}
+
+// occurrenceKey gerçekleşmenin sıralamada karşılaştırılan alanlarıdır
+func occurrenceKey(r models.Reservation, o recurrence.Occurrence) models.ReservationInstance {
+	instance := models.ReservationInstance{StartDate: o.Start, OccurrenceIndex: o.Index}
+	instance.ID = r.ID
+	instance.CreatedAt = r.CreatedAt
+	instance.Name = r.Name
+	return instance
+}
+
+// rowCursor veritabanında r kaydından sonrasını okumak için cursor'dır
+func (q *reservationQuery) rowCursor(r models.Reservation) *reservationCursor {
+	return &reservationCursor{
+		Sort:      q.sort,
+		Desc:      q.desc,
+		StartDate: r.StartDate,
+		CreatedAt: r.CreatedAt,
+		Name:      r.Name,
+		ID:        r.ID,
+	}
+}
+
+// sortOrder veritabanı sıralamasıdır; eşit değerler ID ile sıralanır
+func (q *reservationQuery) sortOrder() bson.D {
+	dir := 1
+	if q.desc {
+		dir = -1
+	}
+	return bson.D{{Key: q.sort, Value: dir}, {Key: "_id", Value: dir}}
+}
+
+// compare iki gerçekleşmenin sıralamadaki yerini karşılaştırır. Sıralama alanı eşitse
+// rezervasyon ID'si ve gerçekleşme sırası kullanılır; böylece sayfalar kararlıdır.
+func (q *reservationQuery) compare(a, b models.ReservationInstance) int {
+	var result int
+	switch q.sort {
+	case sortCreatedAt:
+		result = a.CreatedAt.Compare(b.CreatedAt)
+	case sortName:
+		result = strings.Compare(a.Name, b.Name)
+	default:
+		result = a.StartDate.Compare(b.StartDate)
+	}
+	if result == 0 {
+		result = bytes.Compare(a.ID[:], b.ID[:])
+	}
+	if result == 0 {
+		result = cmp.Compare(a.OccurrenceIndex, b.OccurrenceIndex)
+	}
+	if q.desc {
+		return -result
+	}
+	return result
+}
+
+// page sıralanmış gerçekleşmelerden cursor'dan sonraki sayfayı ve sonraki sayfanın cursor'ını döndürür
+func (q *reservationQuery) page(instances []models.ReservationInstance) ([]models.ReservationInstance, string) {
+	if q.after != nil {
+		position := q.after.instance()
+		start := len(instances)
+		for i, instance := range instances {
+			if q.compare(instance, position) > 0 {
+				start = i
+				break
+			}
+		}
+		instances = instances[start:]
+	}
+	if q.limit == 0 || len(instances) <= q.limit {
+		return instances, ""
+	}
+	instances = instances[:q.limit]
+	return instances, q.cursor(instances[len(instances)-1])
+}
+
+// cursor gerçekleşmenin sıralamadaki yerini opak bir belirtece çevirir
+func (q *reservationQuery) cursor(last models.ReservationInstance) string {
+	cursor := reservationCursor{Sort: q.sort, Desc: q.desc, ID: last.ID, Index: last.OccurrenceIndex}
+	switch q.sort {
+	case sortCreatedAt:
+		cursor.CreatedAt = last.CreatedAt
+	case sortName:
+		cursor.Name = last.Name
+	default:
+		cursor.StartDate = last.StartDate
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func parseReservationCursor(s string) (*reservationCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cursor reservationCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// instance cursor'ı karşılaştırılabilecek bir gerçekleşmeye çevirir
+func (cursor *reservationCursor) instance() models.ReservationInstance {
+	instance := models.ReservationInstance{
+		StartDate:       cursor.StartDate,
+		OccurrenceIndex: cursor.Index,
+	}
+	instance.ID = cursor.ID
+	instance.CreatedAt = cursor.CreatedAt
+	instance.Name = cursor.Name
+	return instance
+}
@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/recurrence"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Tekrar eden bir seride güncelleme/silme kapsamları
+const (
+	scopeAll       = "all"       // tüm seri
+	scopeThis      = "this"      // yalnızca seçilen gerçekleşme
+	scopeFollowing = "following" // seçilen ve sonraki gerçekleşmeler
+)
+
+// seriesScope scope ve occurrence sorgu parametrelerini okur. occurrence, seçilen
+// gerçekleşmenin serideki asıl başlangıç zamanıdır.
+func seriesScope(c *fiber.Ctx) (string, time.Time, error) {
+	scope := c.Query("scope", scopeAll)
+	switch scope {
+	case scopeAll:
+		return scope, time.Time{}, nil
+	case scopeThis, scopeFollowing:
+	default:
+		return "", time.Time{}, errors.New("scope all, this veya following olmalıdır")
+	}
+
+	occurrenceStr := c.Query("occurrence")
+	if occurrenceStr == "" {
+		return "", time.Time{}, errors.New("Bu kapsam için occurrence (gerçekleşmenin başlangıç zamanı) zorunludur")
+	}
+	occurrence, err := time.Parse(time.RFC3339, occurrenceStr)
+	if err != nil {
+		return "", time.Time{}, errors.New("Geçersiz occurrence tarihi formatı")
+	}
+	return scope, occurrence, nil
+}
+
+// findOccurrence seriyi getirir ve occurrence'ta başlayan gerçekleşmeyi serinin saat diliminde
+// döndürür. Tüm gün serilerde gerçekleşme günün başına sabitlenir.
+func (h *ReservationHandler) findOccurrence(ctx context.Context, partnerObjID, seriesObjID primitive.ObjectID, occurrence time.Time) (*models.Reservation, recurrence.Occurrence, error) {
+	var series models.Reservation
+	err := h.db.Collection("reservations").FindOne(ctx, bson.M{
+		"_id":       seriesObjID,
+		"partnerId": partnerObjID,
+	}).Decode(&series)
+	if err == mongo.ErrNoDocuments {
+		return nil, recurrence.Occurrence{}, failWrite(fiber.StatusNotFound, fiber.Map{
+			"error": "Rezervasyon bulunamadı veya bu partner'a ait değil",
+		})
+	}
+	if err != nil {
+		return nil, recurrence.Occurrence{}, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon getirilemedi",
+		})
+	}
+
+	if series.SeriesID != nil || !series.Recurrence.Enabled {
+		return nil, recurrence.Occurrence{}, failWrite(fiber.StatusBadRequest, fiber.Map{
+			"error": "Tek gerçekleşme veya sonrakiler yalnızca tekrar eden serilerde seçilebilir",
+		})
+	}
+
+	partnerLoc, err := h.partnerLocation(ctx, partnerObjID)
+	if err != nil {
+		return nil, recurrence.Occurrence{}, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+
+	o, ok := recurrence.At(series, partnerLoc, occurrence)
+	if !ok {
+		return nil, recurrence.Occurrence{}, failWrite(fiber.StatusNotFound, fiber.Map{
+			"error": "Serinin bu tarihte bir gerçekleşmesi yok",
+		})
+	}
+
+	return &series, o, nil
+}
+
+// updateOccurrence serinin tek bir gerçekleşmesini, seriye bağlı ayrı bir kayıtla değiştirir
+func (h *ReservationHandler) updateOccurrence(ctx context.Context, partnerObjID, seriesObjID primitive.ObjectID, occurrence time.Time, updateData models.Reservation) (models.Reservation, writeWarnings, error) {
+	var warnings writeWarnings
+
+	series, o, err := h.findOccurrence(ctx, partnerObjID, seriesObjID, occurrence)
+	if err != nil {
+		return updateData, warnings, err
+	}
+	occurrence = o.Start
+	// Gerçekleşme serinin saat dilimindedir
+	if recurrence.IsException(*series, occurrence.Location(), occurrence) {
+		return updateData, warnings, failWrite(fiber.StatusConflict, fiber.Map{
+			"error": "Bu gerçekleşme iptal edilmiş veya zaten ayrıca düzenlenmiş",
+		})
+	}
+
+	override := updateData
+	override.ID = primitive.NewObjectID()
+	override.PartnerID = partnerObjID
+	override.Recurrence = models.RecurrencePattern{}
+	override.ExceptionDates = nil
+	override.SeriesID = &series.ID
+	override.RecurrenceID = &occurrence
+	override.ImportUID = ""
+
+	// Yerine geçilen gerçekleşme çakışma sayılmaz
+	release, err := h.guardConflicts(ctx, partnerObjID, override, func(r models.Reservation, o recurrence.Occurrence) bool {
+		return r.ID == series.ID && o.Start.Equal(occurrence)
+	}, &warnings)
+	if release == nil {
+		return override, warnings, err
+	}
+	defer release()
+
+	// Değişiklik numarası ve zamanlar kayıtlardan hemen önce alınır
+	seq, done, err := h.stampChange(ctx, partnerObjID)
+	if err != nil {
+		return override, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon kaydedilemedi",
+		})
+	}
+	defer done()
+	now := time.Now()
+	override.CreatedAt = now
+	override.UpdatedAt = now
+	override.ChangeSeq = seq
+
+	collection := h.db.Collection("reservations")
+	if _, err := collection.InsertOne(ctx, override); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return override, warnings, failWrite(fiber.StatusConflict, fiber.Map{
+				"error": "Bu gerçekleşme iptal edilmiş veya zaten ayrıca düzenlenmiş",
+			})
+		}
+		return override, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon kaydedilemedi",
+		})
+	}
+
+	// Seri bu gerçekleşmeyi artık kendisi üretmez
+	if _, err := collection.UpdateOne(ctx,
+		bson.M{"_id": series.ID},
+		bson.M{
+			"$addToSet": bson.M{"exceptionDates": occurrence},
+			"$set":      bson.M{"updatedAt": now, "changeSeq": seq},
+		},
+	); err != nil {
+		_, _ = collection.DeleteOne(ctx, bson.M{"_id": override.ID})
+		return override, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon güncellenirken bir hata oluştu",
+		})
+	}
+
+	// Gerçekleşmenin müşteri rezervasyonları yerine geçen kayda taşınır
+	if err := h.moveBookings(ctx, series.ID, override.ID, occurrence, override.StartDate.Sub(occurrence)); err != nil {
+		return override, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Müşteri rezervasyonları güncellenemedi",
+		})
+	}
+	h.promoteAfterCapacityChange(ctx, override.ID, series.Capacity, override.Capacity)
+
+	return override, warnings, nil
+}
+
+// updateFollowing seriyi seçilen gerçekleşmeden böler: asıl seri bir önceki gerçekleşmede
+// biter, seçilen gerçekleşmeden itibaren güncel verilerle yeni bir seri başlar
+func (h *ReservationHandler) updateFollowing(ctx context.Context, partnerObjID, seriesObjID primitive.ObjectID, occurrence time.Time, updateData models.Reservation) (models.Reservation, writeWarnings, error) {
+	var warnings writeWarnings
+
+	series, o, err := h.findOccurrence(ctx, partnerObjID, seriesObjID, occurrence)
+	if err != nil {
+		return updateData, warnings, err
+	}
+	occurrence, index := o.Start, o.Index
+	// İlk gerçekleşmeden itibaren güncellemek tüm seriyi güncellemektir
+	if index == 0 {
+		return h.updateSeries(ctx, partnerObjID, seriesObjID, updateData)
+	}
+
+	// Kapasite taşınacak onaylı müşteri rezervasyonlarının altına düşürülemez
+	if updateData.Capacity < series.Capacity {
+		if err := h.checkBookedCapacity(ctx, series.ID, occurrence, updateData.Capacity); err != nil {
+			return updateData, warnings, err
+		}
+	}
+
+	partnerLoc, err := h.partnerLocation(ctx, partnerObjID)
+	if err != nil {
+		return updateData, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Partner bilgileri getirilemedi",
+		})
+	}
+
+	next := updateData
+	next.ID = primitive.NewObjectID()
+	next.PartnerID = partnerObjID
+	next.SeriesID = nil
+	next.RecurrenceID = nil
+	next.ImportUID = ""
+
+	// Asıl seri adetle bitiyorsa ve adet değiştirilmediyse yeni seri kalan adetle devam eder
+	if next.Recurrence.Enabled && next.Recurrence.EndType == recurrence.EndAfter &&
+		series.Recurrence.EndType == recurrence.EndAfter && next.Recurrence.EndAfter == series.Recurrence.EndAfter {
+		next.Recurrence.EndAfter = max(series.Recurrence.EndAfter-index, 1)
+		next.Recurrence.RRule = recurrence.Format(next.Recurrence, next.Location(partnerLoc))
+	}
+
+	// Sonraki iptaller ve değiştirilmiş gerçekleşmeler yeni seriye, saat kaydırıldıysa o kadar kaydırılarak taşınır
+	shift := next.StartDate.Sub(occurrence)
+	var kept []time.Time
+	for _, ex := range series.ExceptionDates {
+		if ex.Before(occurrence) {
+			kept = append(kept, ex)
+		} else {
+			next.ExceptionDates = append(next.ExceptionDates, ex.Add(shift))
+		}
+	}
+
+	// Asıl serinin kesilecek gerçekleşmeleri ve yeni seriye taşınacak kayıtlar çakışma sayılmaz
+	release, err := h.guardConflicts(ctx, partnerObjID, next, func(r models.Reservation, o recurrence.Occurrence) bool {
+		if r.ID == series.ID {
+			return !o.Start.Before(occurrence)
+		}
+		return r.SeriesID != nil && *r.SeriesID == series.ID && r.RecurrenceID != nil && !r.RecurrenceID.Before(occurrence)
+	}, &warnings)
+	if release == nil {
+		return next, warnings, err
+	}
+	defer release()
+
+	next.Recurrence.LastEnd = seriesLastEnd(next, partnerLoc)
+
+	// Değişiklik numarası ve zamanlar kayıtlardan hemen önce alınır
+	seq, done, err := h.stampChange(ctx, partnerObjID)
+	if err != nil {
+		return next, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon kaydedilemedi",
+		})
+	}
+	defer done()
+	now := time.Now()
+	next.CreatedAt = now
+	next.UpdatedAt = now
+	next.ChangeSeq = seq
+
+	collection := h.db.Collection("reservations")
+	if _, err := collection.InsertOne(ctx, next); err != nil {
+		return next, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon kaydedilemedi",
+		})
+	}
+
+	if err := h.endSeriesBefore(ctx, series, index, kept, seq); err != nil {
+		_, _ = collection.DeleteOne(ctx, bson.M{"_id": next.ID})
+		return next, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon güncellenirken bir hata oluştu",
+		})
+	}
+
+	if _, err := collection.UpdateMany(ctx,
+		bson.M{"seriesId": series.ID, "recurrenceId": bson.M{"$gte": occurrence}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"seriesId":     next.ID,
+			"recurrenceId": bson.M{"$add": bson.A{"$recurrenceId", shift.Milliseconds()}},
+			"updatedAt":    now,
+			"changeSeq":    seq,
+		}}}},
+	); err != nil {
+		return next, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon güncellenirken bir hata oluştu",
+		})
+	}
+
+	if err := h.moveBookings(ctx, series.ID, next.ID, bson.M{"$gte": occurrence}, shift); err != nil {
+		return next, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Müşteri rezervasyonları güncellenemedi",
+		})
+	}
+	// Yeni desende karşılığı olmayan gerçekleşmelerin müşteri rezervasyonları iptal edilir
+	if err := h.cancelOrphanedBookings(ctx, next, partnerLoc); err != nil {
+		return next, warnings, failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Müşteri rezervasyonları güncellenemedi",
+		})
+	}
+	h.promoteAfterCapacityChange(ctx, next.ID, series.Capacity, next.Capacity)
+
+	return next, warnings, nil
+}
+
+// deleteOccurrence serinin tek bir gerçekleşmesini iptal eder
+func (h *ReservationHandler) deleteOccurrence(ctx context.Context, partnerObjID, seriesObjID primitive.ObjectID, occurrence time.Time) error {
+
+	series, o, err := h.findOccurrence(ctx, partnerObjID, seriesObjID, occurrence)
+	if err != nil {
+		return err
+	}
+	occurrence = o.Start
+
+	overrides, err := h.overrideIDs(ctx, series.ID, occurrence)
+	if err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+
+	// Seri ve silme kaydı aynı değişiklik numarasını taşır
+	seq, done, err := h.stampChange(ctx, partnerObjID)
+	if err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+	defer done()
+
+	collection := h.db.Collection("reservations")
+	if _, err := collection.UpdateOne(ctx,
+		bson.M{"_id": series.ID},
+		bson.M{
+			"$addToSet": bson.M{"exceptionDates": occurrence},
+			"$set":      bson.M{"updatedAt": time.Now(), "changeSeq": seq},
+		},
+	); err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+
+	// Gerçekleşme ayrıca düzenlenmişse o kayıt da silinir
+	if _, err := collection.DeleteOne(ctx, bson.M{"seriesId": series.ID, "recurrenceId": occurrence}); err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+
+	if err := h.tombstones.Record(ctx, partnerObjID, overrides, seq); err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+
+	// İptal edilen gerçekleşmenin müşteri rezervasyonları da iptal edilir
+	if err := h.cancelBookings(ctx, []primitive.ObjectID{series.ID}, occurrence); err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Müşteri rezervasyonları iptal edilemedi",
+		})
+	}
+	if len(overrides) > 0 {
+		if err := h.cancelBookings(ctx, overrides, nil); err != nil {
+			return failWrite(fiber.StatusInternalServerError, fiber.Map{
+				"error": "Müşteri rezervasyonları iptal edilemedi",
+			})
+		}
+	}
+
+	return nil
+}
+
+// deleteFollowing seriyi seçilen gerçekleşmeden önce bitirir
+func (h *ReservationHandler) deleteFollowing(ctx context.Context, partnerObjID, seriesObjID primitive.ObjectID, occurrence time.Time) error {
+
+	series, o, err := h.findOccurrence(ctx, partnerObjID, seriesObjID, occurrence)
+	if err != nil {
+		return err
+	}
+	occurrence, index := o.Start, o.Index
+	// İlk gerçekleşmeden itibaren silmek tüm seriyi silmektir
+	if index == 0 {
+		return h.deleteSeries(ctx, partnerObjID, seriesObjID)
+	}
+
+	var kept []time.Time
+	for _, ex := range series.ExceptionDates {
+		if ex.Before(occurrence) {
+			kept = append(kept, ex)
+		}
+	}
+
+	following := bson.M{"$gte": occurrence}
+	overrides, err := h.overrideIDs(ctx, series.ID, following)
+	if err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+
+	// Seri ve silme kayıtları aynı değişiklik numarasını taşır
+	seq, done, err := h.stampChange(ctx, partnerObjID)
+	if err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+	defer done()
+
+	if err := h.endSeriesBefore(ctx, series, index, kept, seq); err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+
+	if _, err := h.db.Collection("reservations").DeleteMany(ctx, bson.M{
+		"seriesId":     series.ID,
+		"recurrenceId": following,
+	}); err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+
+	if err := h.tombstones.Record(ctx, partnerObjID, overrides, seq); err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Rezervasyon silinirken bir hata oluştu",
+		})
+	}
+
+	// Silinen gerçekleşmelerin müşteri rezervasyonları iptal edilir
+	if err := h.cancelBookings(ctx, []primitive.ObjectID{series.ID}, following); err != nil {
+		return failWrite(fiber.StatusInternalServerError, fiber.Map{
+			"error": "Müşteri rezervasyonları iptal edilemedi",
+		})
+	}
+	if len(overrides) > 0 {
+		if err := h.cancelBookings(ctx, overrides, nil); err != nil {
+			return failWrite(fiber.StatusInternalServerError, fiber.Map{
+				"error": "Müşteri rezervasyonları iptal edilemedi",
+			})
+		}
+	}
+
+	return nil
+}
+
+// endSeriesBefore seriyi ilk count gerçekleşmeyle sınırlar. Adetle bitirmek, tarihle
+// bitirmenin aksine aynı gün içindeki sonraki gerçekleşmeleri de doğru keser. seq yazmanın
+// değişiklik numarasıdır.
+func (h *ReservationHandler) endSeriesBefore(ctx context.Context, series *models.Reservation, count int, exceptions []time.Time, seq int64) error {
+	partnerLoc, err := h.partnerLocation(ctx, series.PartnerID)
+	if err != nil {
+		return err
+	}
+
+	cut := *series
+	cut.Recurrence.EndType = recurrence.EndAfter
+	cut.Recurrence.EndAfter = count
+	cut.Recurrence.EndDate = nil
+	cut.Recurrence.RRule = recurrence.Format(cut.Recurrence, series.Location(partnerLoc))
+	cut.Recurrence.LastEnd = seriesLastEnd(cut, partnerLoc)
+
+	_, err = h.db.Collection("reservations").UpdateOne(ctx,
+		bson.M{"_id": series.ID},
+		bson.M{"$set": bson.M{
+			"recurrence":     cut.Recurrence,
+			"exceptionDates": exceptions,
+			"updatedAt":      time.Now(),
+			"changeSeq":      seq,
+		}},
+	)
+	return err
+}
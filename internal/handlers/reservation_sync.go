@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/recurrence"
+	"github.com/denizbarcak/planvia-partner-api/internal/tombstones"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SyncReservations rezervasyonları artımlı olarak senkronize eder. Belirteç gönderilmezse tüm
+// rezervasyonlar döner; syncToken ile yalnızca o zamandan beri oluşturulan, güncellenen ve
+// silinen rezervasyonlar döner. Değişiklikler zamana göre değil, her yazmanın kayda işlediği
+// partner sıra numarasına (changeSeq) göre okunur; geç biten bir yazma atlanmaz. Her kayıt
+// serinin açılmamış hali, yani listelemedeki pencere verilmemiş biçimidir; silinenler yalnızca
+// ID ve silinme zamanıyla gelir.
+func (h *ReservationHandler) SyncReservations(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	var since int64
+	full := true
+	if token := c.Query("syncToken"); token != "" {
+		var issuedAt time.Time
+		since, issuedAt, err = parseSyncToken(token)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Geçersiz senkronizasyon belirteci",
+			})
+		}
+		// Silme kayıtları saklama süresinden sonra silindiği için eski belirteçlerle silinenler kaçabilir
+		if time.Since(issuedAt) > tombstones.Retention {
+			return c.Status(fiber.StatusGone).JSON(fiber.Map{
+				"error": "Senkronizasyon belirtecinin süresi dolmuş, belirteç olmadan tüm rezervasyonları yeniden alın",
+				"code":  "SYNC_TOKEN_EXPIRED",
+			})
+		}
+		full = false
+	}
+
+	// Belirteç sorgulardan önce alınır: yalnızca kendisinden önceki bütün yazmaları bitmiş
+	// numaraya kadar okunur, sonrası bir sonraki çağrıda gelir
+	issuedAt := time.Now()
+	visible, err := h.changes.Visible(ctx, partnerObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyonlar getirilemedi",
+		})
+	}
+
+	filter := bson.M{"partnerId": partnerObjID}
+	if !full {
+		filter["changeSeq"] = bson.M{"$gt": since, "$lte": visible}
+	}
+	cursor, err := h.db.Collection("reservations").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "changeSeq", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyonlar getirilemedi",
+		})
+	}
+	defer cursor.Close(ctx)
+
+	var reservations []models.Reservation
+	if err := cursor.All(ctx, &reservations); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Rezervasyonlar parse edilemedi",
+		})
+	}
+
+	deleted := []models.ReservationTombstone{}
+	if !full {
+		if deleted, err = h.tombstones.Since(ctx, partnerObjID, since, visible); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Silinen rezervasyonlar getirilemedi",
+			})
+		}
+	}
+
+	// İlk gerçekleşmelerin dolu yer sayıları
+	ids := make([]primitive.ObjectID, len(reservations))
+	for i, reservation := range reservations {
+		ids[i] = reservation.ID
+	}
+	counts, err := h.seats.Counts(ctx, ids)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Dolu yer sayıları getirilemedi",
+		})
+	}
+
+	changes := models.ReservationChanges{
+		Reservations: []models.ReservationInstance{},
+		Deleted:      deleted,
+		SyncToken:    formatSyncToken(visible, issuedAt),
+		Full:         full,
+	}
+	for _, reservation := range reservations {
+		changes.Reservations = append(changes.Reservations, newReservationInstance(reservation, recurrence.Occurrence{
+			Start: reservation.StartDate,
+			End:   reservation.EndDate,
+		}, counts))
+	}
+
+	return c.JSON(changes)
+}
+
+// formatSyncToken sıra numarasını ve belirtecin verildiği zamanı istemcinin içeriğine bağlı
+// kalmaması gereken opak bir belirtece çevirir
+func formatSyncToken(seq int64, issuedAt time.Time) string {
+	raw := strconv.FormatInt(seq, 10) + "." + strconv.FormatInt(issuedAt.UnixMilli(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseSyncToken(token string) (int64, time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	seqPart, msPart, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return 0, time.Time{}, errors.New("invalid sync token")
+	}
+	seq, err := strconv.ParseInt(seqPart, 10, 64)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	ms, err := strconv.ParseInt(msPart, 10, 64)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return seq, time.UnixMilli(ms), nil
+}
+
+// stampChange yazma için partner'ın bir sonraki değişiklik numarasını alır. Numara yazılan
+// kayıtların changeSeq alanına (silmelerde silme kaydına) aynı yazmada işlenir; yazma bitince,
+// başarısız olsa da, dönen done çağrılır. Bitmemiş numaralar senkronizasyonda beklenir.
+func (h *ReservationHandler) stampChange(ctx context.Context, partnerObjID primitive.ObjectID) (int64, func(), error) {
+	seq, err := h.changes.Next(ctx, partnerObjID)
+	if err != nil {
+		return 0, nil, err
+	}
+	return seq, func() {
+		if err := h.changes.Done(context.Background(), partnerObjID, seq); err != nil {
+			log.Printf("Reservation change %d could not be marked done for partner %s: %v", seq, partnerObjID.Hex(), err)
+		}
+	}, nil
+}
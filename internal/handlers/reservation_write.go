@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// writeError bir rezervasyon yazmasının reddedilme veya başarısız olma nedenidir. Yazma yolları
+// yanıt yazmaz; HTTP uçları, içe aktarma ve CalDAV hatayı kendi biçiminde döndürür.
+type writeError struct {
+	status     int
+	body       fiber.Map
+	retryAfter string
+}
+
+func (e *writeError) Error() string {
+	message, _ := e.body["error"].(string)
+	return message
+}
+
+// failWrite verilen durum kodu ve JSON gövdesiyle bir yazma hatası döndürür
+func failWrite(status int, body fiber.Map) *writeError {
+	return &writeError{status: status, body: body}
+}
+
+// writeWarnings warn politikalarında kaydı engellemeyen uyarılardır
+type writeWarnings struct {
+	conflicts         []string // çakışan rezervasyonların ID'leri
+	outsideHours      []string // çalışma saatleri dışına düşen gerçekleşmelerin başlangıçları
+	uncoveredHolidays []string // dini bayramları takvimde olmayan yıllar
+}
+
+// add başka bir yazmanın uyarılarını ekler
+func (w *writeWarnings) add(other writeWarnings) {
+	w.conflicts = appendNew(w.conflicts, other.conflicts)
+	w.outsideHours = appendNew(w.outsideHours, other.outsideHours)
+	w.uncoveredHolidays = appendNew(w.uncoveredHolidays, other.uncoveredHolidays)
+}
+
+// setHeaders uyarıları yanıt başlıklarına yazar
+func (w writeWarnings) setHeaders(c *fiber.Ctx) {
+	if len(w.conflicts) > 0 {
+		c.Set(conflictHeader, strings.Join(w.conflicts, ","))
+	}
+	if len(w.outsideHours) > 0 {
+		c.Set(hoursHeader, strings.Join(w.outsideHours, ","))
+	}
+	if len(w.uncoveredHolidays) > 0 {
+		c.Set(uncoveredHolidaysHeader, strings.Join(w.uncoveredHolidays, ","))
+	}
+}
+
+// respondWriteError yazma hatasını JSON yanıtı olarak yazar
+func respondWriteError(c *fiber.Ctx, err error) error {
+	var failed *writeError
+	if !errors.As(err, &failed) {
+		log.Printf("Reservation write failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+	if failed.retryAfter != "" {
+		c.Set(fiber.HeaderRetryAfter, failed.retryAfter)
+	}
+	return c.Status(failed.status).JSON(failed.body)
+}
+
+func appendNew(list, values []string) []string {
+	for _, v := range values {
+		found := false
+		for _, existing := range list {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/booking"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/validation"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateSeatHold bir gerçekleşmede yerleri kısa süreliğine ayırır. Ayırma süresi içinde
+// onaylanmazsa yerler otomatik olarak serbest bırakılır.
+func (h *ReservationHandler) CreateSeatHold(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	reservationObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz rezervasyon ID",
+		})
+	}
+
+	var req models.CreateSeatHoldRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	reservation, occurrence, err := h.bookableOccurrence(ctx, c, partnerObjID, reservationObjID, req.OccurrenceStart)
+	if reservation == nil {
+		return err
+	}
+
+	if err := h.reserveSeats(ctx, reservation, occurrence, req.Seats); err != nil {
+		if err == booking.ErrCapacityExceeded {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Bu rezervasyonda yeterli boş yer yok",
+				"code":  "CAPACITY_EXCEEDED",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	ttl := booking.DefaultHoldTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+
+	now := time.Now()
+	hold := models.SeatHold{
+		ID:              primitive.NewObjectID(),
+		PartnerID:       partnerObjID,
+		ReservationID:   reservation.ID,
+		OccurrenceStart: occurrence,
+		Seats:           req.Seats,
+		Status:          models.SeatHoldStatusHeld,
+		ExpiresAt:       now.Add(ttl),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := h.holds.Create(ctx, &hold); err != nil {
+		_ = h.seats.Release(ctx, reservation.ID, occurrence, req.Seats)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Yer ayrılamadı",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(hold)
+}
+
+// ConfirmSeatHold süresi dolmamış ayırmayı, ayrılan yerlerle onaylı bir müşteri rezervasyonuna çevirir
+func (h *ReservationHandler) ConfirmSeatHold(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	reservationObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz rezervasyon ID",
+		})
+	}
+
+	holdObjID, err := primitive.ObjectIDFromHex(c.Params("holdId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz yer ayırma ID",
+		})
+	}
+
+	var req models.ConfirmSeatHoldRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	req.CustomerName = strings.TrimSpace(req.CustomerName)
+	req.CustomerEmail = strings.ToLower(strings.TrimSpace(req.CustomerEmail))
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+	if req.CustomerPhone != "" {
+		req.CustomerPhone, _ = validation.NormalizePhone(req.CustomerPhone)
+	}
+
+	// Ayırma tek işlemle onaylanır; süresi dolmuşsa veya başka bir istek onayladıysa geçiş olmaz
+	bookingID := primitive.NewObjectID()
+	hold, err := h.holds.Transition(ctx, partnerObjID, reservationObjID, holdObjID, models.SeatHoldStatusConfirmed, bson.M{"bookingId": bookingID})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Yer ayırma onaylanamadı",
+		})
+	}
+	if hold == nil {
+		return h.seatHoldUnavailable(ctx, c, partnerObjID, reservationObjID, holdObjID)
+	}
+
+	now := time.Now()
+	b := models.Booking{
+		ID:              bookingID,
+		PartnerID:       partnerObjID,
+		ReservationID:   hold.ReservationID,
+		OccurrenceStart: hold.OccurrenceStart,
+		CustomerName:    req.CustomerName,
+		CustomerEmail:   req.CustomerEmail,
+		CustomerPhone:   req.CustomerPhone,
+		PartySize:       hold.Seats,
+		Status:          models.BookingStatusConfirmed,
+		Notes:           req.Notes,
+		HoldID:          &hold.ID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if _, err := h.db.Collection("bookings").InsertOne(ctx, b); err != nil {
+		if err := h.holds.Reopen(ctx, hold.ID); err != nil {
+			log.Printf("Seat hold %s could not be reopened: %v", hold.ID.Hex(), err)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Müşteri rezervasyonu kaydedilemedi",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(b)
+}
+
+// ReleaseSeatHold ayırmayı süresi dolmadan bırakır ve yerleri serbest bırakır
+func (h *ReservationHandler) ReleaseSeatHold(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	reservationObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz rezervasyon ID",
+		})
+	}
+
+	holdObjID, err := primitive.ObjectIDFromHex(c.Params("holdId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz yer ayırma ID",
+		})
+	}
+
+	hold, err := h.holds.Transition(ctx, partnerObjID, reservationObjID, holdObjID, models.SeatHoldStatusReleased, nil)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Yer ayırma bırakılamadı",
+		})
+	}
+	if hold == nil {
+		return h.seatHoldUnavailable(ctx, c, partnerObjID, reservationObjID, holdObjID)
+	}
+
+	if err := h.seats.Release(ctx, hold.ReservationID, hold.OccurrenceStart, hold.Seats); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Yerler serbest bırakılamadı",
+		})
+	}
+	if _, err := h.promoteWaitlist(ctx, hold.ReservationID, hold.OccurrenceStart); err != nil {
+		log.Printf("Waitlist promotion failed for reservation %s: %v", hold.ReservationID.Hex(), err)
+	}
+
+	return c.JSON(hold)
+}
+
+// RunHoldSweeper süresi dolan ayırmaların yerlerini interval aralıklarla serbest bırakır ve
+// yayınlanamamış booking.promoted olaylarını yeniden yayınlar. ctx iptal edilene kadar çalışır.
+func (h *ReservationHandler) RunHoldSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := h.expireHolds(ctx, nil); err != nil {
+				log.Printf("Expired seat holds could not be released: %v", err)
+			}
+			if err := h.publishPendingEvents(ctx); err != nil {
+				log.Printf("Pending booking events could not be published: %v", err)
+			}
+		}
+	}
+}
+
+// reserveSeats gerçekleşmede yer ayırır. Yer yetmezse önce o gerçekleşmenin süresi dolmuş
+// ama henüz süpürülmemiş ayırmaları bırakılır ve bir kez daha denenir.
+func (h *ReservationHandler) reserveSeats(ctx context.Context, reservation *models.Reservation, occurrence time.Time, count int) error {
+	err := h.seats.Reserve(ctx, reservation.PartnerID, reservation.ID, occurrence, count, reservation.Capacity)
+	if err != booking.ErrCapacityExceeded {
+		return err
+	}
+
+	expired, err := h.expireHolds(ctx, bson.M{"reservationId": reservation.ID, "occurrenceStart": occurrence})
+	if err != nil {
+		return err
+	}
+	if expired == 0 {
+		return booking.ErrCapacityExceeded
+	}
+	return h.seats.Reserve(ctx, reservation.PartnerID, reservation.ID, occurrence, count, reservation.Capacity)
+}
+
+// expireHolds filter'a uyan süresi dolmuş ayırmaların yerlerini bırakır, açılan yerler için
+// bekleme listelerini işler ve bırakılan ayırma sayısını döndürür
+func (h *ReservationHandler) expireHolds(ctx context.Context, filter bson.M) (int, error) {
+	type slot struct {
+		reservationID primitive.ObjectID
+		occurrence    int64
+	}
+	freed := map[slot]time.Time{}
+
+	count := 0
+	for {
+		hold, err := h.holds.ExpireNext(ctx, filter)
+		if err != nil {
+			return count, err
+		}
+		if hold == nil {
+			break
+		}
+		if err := h.seats.Release(ctx, hold.ReservationID, hold.OccurrenceStart, hold.Seats); err != nil {
+			return count, err
+		}
+		freed[slot{hold.ReservationID, hold.OccurrenceStart.UnixMilli()}] = hold.OccurrenceStart
+		count++
+	}
+
+	for s, occurrence := range freed {
+		if _, err := h.promoteWaitlist(ctx, s.reservationID, occurrence); err != nil {
+			log.Printf("Waitlist promotion failed for reservation %s: %v", s.reservationID.Hex(), err)
+		}
+	}
+	return count, nil
+}
+
+// seatHoldUnavailable onaylanamayan veya bırakılamayan ayırmanın nedenini yanıtlar
+func (h *ReservationHandler) seatHoldUnavailable(ctx context.Context, c *fiber.Ctx, partnerObjID, reservationObjID, holdObjID primitive.ObjectID) error {
+	hold, err := h.holds.Get(ctx, partnerObjID, reservationObjID, holdObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Yer ayırma getirilemedi",
+		})
+	}
+	if hold == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Yer ayırma bulunamadı",
+		})
+	}
+	if hold.Status == models.SeatHoldStatusConfirmed {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":     "Yer ayırma zaten onaylanmış",
+			"bookingId": hold.BookingID,
+		})
+	}
+	return c.Status(fiber.StatusGone).JSON(fiber.Map{
+		"error": "Yer ayırmanın süresi dolmuş veya bırakılmış",
+	})
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/validation"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type StaffHandler struct {
+	collection *mongo.Collection
+	partners   *mongo.Collection
+	validate   *validator.Validate
+	sessions   *auth.SessionStore
+	throttle   *auth.LoginThrottle
+}
+
+func NewStaffHandler(db *mongo.Database, sessions *auth.SessionStore, throttle *auth.LoginThrottle) *StaffHandler {
+	return &StaffHandler{
+		collection: db.Collection("staff"),
+		partners:   db.Collection("partners"),
+		validate:   validation.New(),
+		sessions:   sessions,
+		throttle:   throttle,
+	}
+}
+
+// ListStaff partner'a ait personel hesaplarını getirir
+func (h *StaffHandler) ListStaff(c *fiber.Ctx) error {
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	cursor, err := h.collection.Find(c.Context(),
+		bson.M{"partner_id": partnerObjID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Personel listesi getirilemedi",
+		})
+	}
+	defer cursor.Close(c.Context())
+
+	staff := []models.StaffUser{}
+	if err := cursor.All(c.Context(), &staff); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Personel listesi parse edilemedi",
+		})
+	}
+
+	return c.JSON(staff)
+}
+
+// CreateStaff yeni bir personel hesabı oluşturur
+func (h *StaffHandler) CreateStaff(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	var req models.CreateStaffRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	// Girişte e-posta ile hesap bulunduğu için adres partner ve personel arasında benzersiz olmalı
+	if h.partners.FindOne(ctx, bson.M{"email": req.Email}).Err() != mongo.ErrNoDocuments ||
+		h.collection.FindOne(ctx, bson.M{"email": req.Email}).Err() != mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Bu e-posta adresi zaten kullanımda",
+		})
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Şifre işlenirken bir hata oluştu",
+		})
+	}
+
+	now := time.Now()
+	staff := models.StaffUser{
+		ID:        primitive.NewObjectID(),
+		PartnerID: partnerObjID,
+		Name:      req.Name,
+		Email:     req.Email,
+		Password:  string(hashedPassword),
+		Role:      req.Role,
+		Status:    models.StaffStatusActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if _, err := h.collection.InsertOne(ctx, staff); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Bu e-posta adresi zaten kullanımda",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Personel kaydedilemedi",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(staff)
+}
+
+// UpdateStaff personel hesabının adını, rolünü, durumunu veya şifresini günceller.
+// Rol, durum veya şifre değişirse personelin açık oturumları kapatılır.
+func (h *StaffHandler) UpdateStaff(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	staffObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz personel ID",
+		})
+	}
+
+	var req models.UpdateStaffRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if req.Name != nil {
+		set["name"] = *req.Name
+	}
+	if req.Role != nil {
+		set["role"] = *req.Role
+	}
+	if req.Status != nil {
+		set["status"] = *req.Status
+	}
+	if req.Password != nil {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Şifre işlenirken bir hata oluştu",
+			})
+		}
+		set["password"] = string(hashedPassword)
+	}
+
+	var staff models.StaffUser
+	err = h.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": staffObjID, "partner_id": partnerObjID},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&staff)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Personel bulunamadı",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Personel güncellenemedi",
+		})
+	}
+
+	if req.Role != nil || req.Status != nil || req.Password != nil {
+		if err := h.sessions.RevokeUser(ctx, partnerObjID, staff.ID); err != nil {
+			log.Printf("Sessions could not be revoked for staff %s: %v", staff.ID.Hex(), err)
+		}
+	}
+	// Şifresi yenilenen personelin giriş kilidi kaldırılır
+	if req.Password != nil {
+		if err := h.throttle.Reset(ctx, staff.Email); err != nil {
+			log.Printf("Login lockout could not be cleared for %s: %v", staff.Email, err)
+		}
+	}
+
+	return c.JSON(staff)
+}
+
+// DeleteStaff personel hesabını siler ve oturumlarını kapatır
+func (h *StaffHandler) DeleteStaff(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+	staffObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz personel ID",
+		})
+	}
+
+	result, err := h.collection.DeleteOne(ctx, bson.M{"_id": staffObjID, "partner_id": partnerObjID})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Personel silinirken bir hata oluştu",
+		})
+	}
+	if result.DeletedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Personel bulunamadı",
+		})
+	}
+
+	if err := h.sessions.RevokeUser(ctx, partnerObjID, staffObjID); err != nil {
+		log.Printf("Sessions could not be revoked for staff %s: %v", staffObjID.Hex(), err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Personel başarıyla silindi",
+	})
+}
\ No newline at end of file
@@ -0,0 +1,445 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// mfaChallengeTTL şifre doğrulandıktan sonra TOTP kodu için tanınan süredir
+	mfaChallengeTTL   = 5 * time.Minute
+	totpIssuer        = "Planvia"
+	recoveryCodeCount = 10
+)
+
+// SetupTwoFactor creates a pending TOTP secret and returns it with an otpauth URI.
+// 2FA is not active until EnableTwoFactor confirms a code from the app. Like
+// DisableTwoFactor it requires the current password, so a stolen session alone
+// cannot bind an attacker's authenticator to the account. Password attempts count
+// towards login throttling.
+func (h *PartnerHandler) SetupTwoFactor(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.SetupTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	partner, err := h.currentPartner(ctx, c)
+	if partner == nil {
+		return err
+	}
+	if partner.TwoFactor.Enabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "İki adımlı doğrulama zaten etkin",
+		})
+	}
+
+	if ok, err := h.checkPassword(ctx, c, partner.Email, partner.Password, req.Password, fiber.Map{
+		"error": "Şifre hatalı",
+	}); !ok {
+		return err
+	}
+	h.passwordChecked(ctx, c, partner.Email)
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "İki adımlı doğrulama anahtarı oluşturulamadı",
+		})
+	}
+
+	if _, err := h.collection.UpdateOne(ctx,
+		bson.M{"_id": partner.ID},
+		bson.M{"$set": bson.M{"two_factor.pending_secret": secret, "updated_at": time.Now()}},
+	); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"secret":     secret,
+		"otpauthUri": auth.TOTPURI(totpIssuer, partner.Email, secret),
+	})
+}
+
+// EnableTwoFactor activates 2FA after the first code from the app checks out
+// and returns the one-time recovery codes. They are shown only once.
+func (h *PartnerHandler) EnableTwoFactor(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	partner, err := h.currentPartner(ctx, c)
+	if partner == nil {
+		return err
+	}
+	if partner.TwoFactor.Enabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "İki adımlı doğrulama zaten etkin",
+		})
+	}
+	if partner.TwoFactor.PendingSecret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Önce iki adımlı doğrulama kurulumunu başlatın",
+		})
+	}
+
+	step, ok := auth.ValidateTOTP(partner.TwoFactor.PendingSecret, req.Code, time.Now())
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Doğrulama kodu hatalı",
+			"code":  "INVALID_2FA_CODE",
+		})
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kurtarma kodları oluşturulamadı",
+		})
+	}
+
+	now := time.Now()
+	result, err := h.collection.UpdateOne(ctx,
+		bson.M{"_id": partner.ID, "two_factor.pending_secret": partner.TwoFactor.PendingSecret},
+		bson.M{
+			"$set": bson.M{
+				"two_factor.enabled":        true,
+				"two_factor.secret":         partner.TwoFactor.PendingSecret,
+				"two_factor.recovery_codes": hashes,
+				"two_factor.last_used_step": step,
+				"two_factor.enabled_at":     now,
+				"updated_at":                now,
+			},
+			"$unset": bson.M{"two_factor.pending_secret": ""},
+		},
+	)
+	if err != nil || result.MatchedCount == 0 {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "İki adımlı doğrulama etkinleştirilemedi",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":       "İki adımlı doğrulama etkinleştirildi",
+		"recoveryCodes": codes,
+	})
+}
+
+// DisableTwoFactor turns 2FA off. It requires the current password and a TOTP or recovery
+// code. Both failures get the same answer and count towards login throttling.
+func (h *PartnerHandler) DisableTwoFactor(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.DisableTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	partner, err := h.currentPartner(ctx, c)
+	if partner == nil {
+		return err
+	}
+	if !partner.TwoFactor.Enabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "İki adımlı doğrulama etkin değil",
+		})
+	}
+
+	failure := fiber.Map{
+		"error": "Şifre veya doğrulama kodu hatalı",
+		"code":  "INVALID_2FA_CODE",
+	}
+	if ok, err := h.checkPassword(ctx, c, partner.Email, partner.Password, req.Password, failure); !ok {
+		return err
+	}
+
+	ok, err := h.verifySecondFactor(ctx, partner, req.Code, req.RecoveryCode)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+	if !ok {
+		h.passwordFailed(ctx, c, partner.Email)
+		return c.Status(fiber.StatusUnauthorized).JSON(failure)
+	}
+	h.passwordChecked(ctx, c, partner.Email)
+
+	if _, err := h.collection.UpdateOne(ctx,
+		bson.M{"_id": partner.ID},
+		bson.M{"$set": bson.M{"two_factor": models.TwoFactor{}, "updated_at": time.Now()}},
+	); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "İki adımlı doğrulama kapatıldı",
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after a valid TOTP code
+func (h *PartnerHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	partner, err := h.currentPartner(ctx, c)
+	if partner == nil {
+		return err
+	}
+	if !partner.TwoFactor.Enabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "İki adımlı doğrulama etkin değil",
+		})
+	}
+
+	ok, err := h.verifySecondFactor(ctx, partner, req.Code, "")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Doğrulama kodu hatalı",
+			"code":  "INVALID_2FA_CODE",
+		})
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kurtarma kodları oluşturulamadı",
+		})
+	}
+
+	if _, err := h.collection.UpdateOne(ctx,
+		bson.M{"_id": partner.ID},
+		bson.M{"$set": bson.M{"two_factor.recovery_codes": hashes, "updated_at": time.Now()}},
+	); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"recoveryCodes": codes,
+	})
+}
+
+// LoginTwoFactor is the second login step: it exchanges the challenge token
+// from Login and a TOTP or recovery code for session tokens.
+func (h *PartnerHandler) LoginTwoFactor(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.TwoFactorLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz istek formatı",
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return validationErrorResponse(c, err)
+	}
+
+	claims, err := h.keys.Parse(req.ChallengeToken)
+	if err != nil || claims[auth.ClaimTokenType] != auth.TokenTypeMFAChallenge {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Doğrulama süresi doldu, lütfen tekrar giriş yapın",
+		})
+	}
+	partnerID, _ := claims["partnerId"].(string)
+	partnerObjID, err := primitive.ObjectIDFromHex(partnerID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Doğrulama süresi doldu, lütfen tekrar giriş yapın",
+		})
+	}
+
+	var partner models.Partner
+	if err := h.collection.FindOne(ctx, bson.M{"_id": partnerObjID}).Decode(&partner); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Doğrulama süresi doldu, lütfen tekrar giriş yapın",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	if ok, err := checkAccountStatus(c, &partner); !ok {
+		return err
+	}
+
+	// 6 haneli kodlar da kaba kuvvete karşı giriş sayaçlarıyla korunur
+	ip := c.IP()
+	throttle, err := h.throttle.Reserve(ctx, partner.Email, ip)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+	if !throttle.Allowed {
+		return throttledResponse(c, throttle)
+	}
+
+	ok, err := h.verifySecondFactor(ctx, &partner, req.Code, req.RecoveryCode)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+	if !ok {
+		if err := h.throttle.RecordFailure(ctx, partner.Email, ip); err != nil {
+			log.Printf("Failed 2FA attempt could not be recorded for %s: %v", partner.Email, err)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Doğrulama kodu hatalı",
+			"code":  "INVALID_2FA_CODE",
+		})
+	}
+
+	if err := h.throttle.RecordSuccess(ctx, partner.Email, ip); err != nil {
+		log.Printf("Login attempts could not be reset for %s: %v", partner.Email, err)
+	}
+
+	return h.completeLogin(c, &partner)
+}
+
+// mfaChallenge answers the password step of Login for partners with 2FA enabled
+func (h *PartnerHandler) mfaChallenge(c *fiber.Ctx, partner *models.Partner) error {
+	token, err := h.keys.Sign(jwt.MapClaims{
+		auth.ClaimTokenType: auth.TokenTypeMFAChallenge,
+		"partnerId":         partner.ID.Hex(),
+		"exp":               time.Now().Add(mfaChallengeTTL).Unix(),
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Token oluşturulamadı",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "İki adımlı doğrulama kodu gerekli",
+		"mfaRequired":    true,
+		"challengeToken": token,
+		"expiresIn":      int(mfaChallengeTTL.Seconds()),
+	})
+}
+
+// verifySecondFactor checks a TOTP code or consumes a recovery code. A TOTP
+// time step can be used only once, so an intercepted code can't be replayed.
+func (h *PartnerHandler) verifySecondFactor(ctx context.Context, partner *models.Partner, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, ok := auth.ValidateTOTP(partner.TwoFactor.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		result, err := h.collection.UpdateOne(ctx,
+			bson.M{"_id": partner.ID, "two_factor.last_used_step": bson.M{"$not": bson.M{"$gte": step}}},
+			bson.M{"$set": bson.M{"two_factor.last_used_step": step}},
+		)
+		if err != nil {
+			return false, err
+		}
+		return result.MatchedCount == 1, nil
+	}
+
+	if recoveryCode == "" {
+		return false, nil
+	}
+	hash := auth.HashToken(auth.NormalizeRecoveryCode(recoveryCode))
+	result, err := h.collection.UpdateOne(ctx,
+		bson.M{"_id": partner.ID, "two_factor.recovery_codes": hash},
+		bson.M{"$pull": bson.M{"two_factor.recovery_codes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// currentPartner loads the authenticated partner. On failure it returns a nil
+// partner and the result of writing the error response.
+func (h *PartnerHandler) currentPartner(ctx context.Context, c *fiber.Ctx) (*models.Partner, error) {
+	partnerObjID, err := primitive.ObjectIDFromHex(c.Locals("partnerId").(string))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geçersiz partner ID",
+		})
+	}
+
+	var partner models.Partner
+	if err := h.collection.FindOne(ctx, bson.M{"_id": partnerObjID}).Decode(&partner); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Partner bulunamadı",
+			})
+		}
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	return &partner, nil
+}
+
+// newRecoveryCodes returns plain codes for the user and their hashes for storage
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashToken(code)
+	}
+	return codes, hashes, nil
+}
\ No newline at end of file
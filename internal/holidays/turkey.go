@@ -0,0 +1,104 @@
+package holidays
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Holiday resmi tatil olan tek bir gündür
+type Holiday struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	Name string `json:"name"`
+}
+
+// fixedTurkey her yıl aynı tarihte olan resmi tatillerdir (ay-gün)
+var fixedTurkey = []struct {
+	month time.Month
+	day   int
+	name  string
+	since int // tatilin ilk uygulandığı yıl
+}{
+	{time.January, 1, "Yılbaşı", 0},
+	{time.April, 23, "Ulusal Egemenlik ve Çocuk Bayramı", 0},
+	{time.May, 1, "Emek ve Dayanışma Günü", 0},
+	{time.May, 19, "Atatürk'ü Anma, Gençlik ve Spor Bayramı", 0},
+	{time.July, 15, "Demokrasi ve Milli Birlik Günü", 2017},
+	{time.August, 30, "Zafer Bayramı", 0},
+	{time.October, 29, "Cumhuriyet Bayramı", 0},
+}
+
+// religiousTurkey dini bayramların ilk günleridir. Tarihler Hicri takvime göre her yıl
+// değiştiği için Diyanet takviminden alınarak yıl yıl eklenir.
+var religiousTurkey = map[int][2]string{
+	2024: {"2024-04-10", "2024-06-16"},
+	2025: {"2025-03-30", "2025-06-06"},
+	2026: {"2026-03-20", "2026-05-27"},
+	2027: {"2027-03-09", "2027-05-16"},
+}
+
+// Bayramların gün sayıları
+const (
+	ramadanFeastDays   = 3
+	sacrificeFeastDays = 4
+)
+
+// Turkey yılın resmi tatillerini tarih sırasıyla döndürür. Arife günlerinin yarım günleri
+// dahil değildir. Dini bayramları eklenmemiş yıllar için yalnızca sabit tatiller döner.
+func Turkey(year int) []Holiday {
+	var list []Holiday
+	for _, f := range fixedTurkey {
+		if year < f.since {
+			continue
+		}
+		list = append(list, Holiday{
+			Date: time.Date(year, f.month, f.day, 0, 0, 0, 0, time.UTC).Format(dateLayout),
+			Name: f.name,
+		})
+	}
+
+	if feasts, ok := religiousTurkey[year]; ok {
+		list = append(list, feastDays(feasts[0], ramadanFeastDays, "Ramazan Bayramı")...)
+		list = append(list, feastDays(feasts[1], sacrificeFeastDays, "Kurban Bayramı")...)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Date < list[j].Date })
+	return list
+}
+
+// TurkeyCovers dini bayramların verilen yıl için takvimde olup olmadığını döndürür
+func TurkeyCovers(year int) bool {
+	_, ok := religiousTurkey[year]
+	return ok
+}
+
+// TurkeyOn verilen günün (YYYY-MM-DD) resmi tatil olup olmadığını döndürür
+func TurkeyOn(date string) (Holiday, bool) {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return Holiday{}, false
+	}
+	for _, h := range Turkey(t.Year()) {
+		if h.Date == date {
+			return h, true
+		}
+	}
+	return Holiday{}, false
+}
+
+func feastDays(first string, days int, name string) []Holiday {
+	start, err := time.Parse(dateLayout, first)
+	if err != nil {
+		panic(fmt.Sprintf("holidays: invalid date %q", first))
+	}
+	list := make([]Holiday, days)
+	for i := range list {
+		list[i] = Holiday{
+			Date: start.AddDate(0, 0, i).Format(dateLayout),
+			Name: fmt.Sprintf("%s %d. gün", name, i+1),
+		}
+	}
+	return list
+}
@@ -0,0 +1,384 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/recurrence"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ContentType iCalendar belgelerinin MIME tipidir
+const ContentType = "text/calendar; charset=utf-8"
+
+// MethodPublish dışa aktarılan ve abone olunan takvimlerin METHOD değeridir. CalDAV'daki
+// takvim nesneleri METHOD taşımaz.
+const MethodPublish = "PUBLISH"
+
+const (
+	dateLayout      = "20060102"
+	localLayout     = "20060102T150405"
+	utcLayout       = "20060102T150405Z"
+	maxLineOctets   = 75
+	uidDomain       = "planvia"
+	productID       = "-//Planvia//Partner API//TR"
+	firstObservance = "19700101T000000"
+)
+
+// Calendar rezervasyonlardan oluşturulan bir VCALENDAR belgesidir. Tekrar eden seriler tek
+// bir VEVENT ve RRULE olarak, ayrı düzenlenmiş gerçekleşmeler aynı UID ile RECURRENCE-ID
+// taşıyan VEVENT'ler olarak yazılır.
+type Calendar struct {
+	Name         string
+	Method       string         // boşsa METHOD yazılmaz
+	Location     *time.Location // rezervasyonda saat dilimi yoksa kullanılan partner saat dilimi
+	Reservations []models.Reservation
+	// Until saat dilimi geçişlerinin yazılacağı son andır (ör. dışa aktarılan aralığın sonu)
+	Until time.Time
+	// Skipped tekrar eden serinin ayrıca atlanan gerçekleşmelerini (ör. kapalı günler) döndürür
+	Skipped func(r models.Reservation) []time.Time
+}
+
+// Encode takvimi RFC 5545 biçiminde yazar. Aynı veriler her zaman aynı çıktıyı verir;
+// böylece çıktının özeti ETag olarak kullanılabilir.
+func (cal *Calendar) Encode() []byte {
+	series := map[primitive.ObjectID]models.Reservation{}
+	var masters []models.Reservation
+	overrides := map[primitive.ObjectID][]models.Reservation{}
+	for _, r := range cal.Reservations {
+		if r.SeriesID == nil {
+			series[r.ID] = r
+			masters = append(masters, r)
+		}
+	}
+	for _, r := range cal.Reservations {
+		if r.SeriesID == nil {
+			continue
+		}
+		if _, ok := series[*r.SeriesID]; ok && r.RecurrenceID != nil {
+			overrides[*r.SeriesID] = append(overrides[*r.SeriesID], r)
+		} else {
+			// Serisi takvimde olmayan gerçekleşme bağımsız bir etkinlik olarak yazılır
+			masters = append(masters, r)
+		}
+	}
+
+	sort.Slice(masters, func(i, j int) bool {
+		if !masters[i].StartDate.Equal(masters[j].StartDate) {
+			return masters[i].StartDate.Before(masters[j].StartDate)
+		}
+		return masters[i].ID.Hex() < masters[j].ID.Hex()
+	})
+
+	w := &writer{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + productID)
+	w.line("CALSCALE:GREGORIAN")
+	if cal.Method != "" {
+		w.line("METHOD:" + cal.Method)
+	}
+	if cal.Name != "" {
+		w.line("X-WR-CALNAME:" + escape(cal.Name))
+	}
+	w.line("X-WR-TIMEZONE:" + cal.Location.String())
+
+	// Kullanılan saat dilimleri, geçişleri ilk etkinlikten itibaren kapsanacak şekilde yazılır
+	zones := map[string]time.Time{}
+	var zoneNames []string
+	for _, r := range cal.Reservations {
+		loc := r.Location(cal.Location)
+		if r.IsAllDay || isUTC(loc) {
+			continue
+		}
+		first, ok := zones[loc.String()]
+		if !ok {
+			zoneNames = append(zoneNames, loc.String())
+		}
+		if !ok || r.StartDate.Before(first) {
+			zones[loc.String()] = r.StartDate
+		}
+	}
+	sort.Strings(zoneNames)
+	for _, name := range zoneNames {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			continue
+		}
+		until := cal.Until
+		if until.Before(zones[name]) {
+			until = zones[name]
+		}
+		writeTimezone(w, loc, zones[name], until)
+	}
+
+	for _, r := range masters {
+		loc := r.Location(cal.Location)
+		isSeries := r.SeriesID == nil && r.Recurrence.Enabled && recurrence.Validate(r.Recurrence) == nil
+
+		edited := overrides[r.ID]
+		sort.Slice(edited, func(i, j int) bool { return edited[i].RecurrenceID.Before(*edited[j].RecurrenceID) })
+
+		w.line("BEGIN:VEVENT")
+		writeCommon(w, r, cal.Location, UID(r))
+		if isSeries {
+			w.line("RRULE:" + rrule(r, loc))
+			if exdates := cal.exdates(r, edited); len(exdates) > 0 {
+				w.prop("EXDATE", r, loc, exdates...)
+			}
+		}
+		w.line("END:VEVENT")
+
+		for _, o := range edited {
+			w.line("BEGIN:VEVENT")
+			if isSeries {
+				writeCommon(w, o, cal.Location, UID(r))
+				w.prop("RECURRENCE-ID", r, loc, *o.RecurrenceID)
+			} else {
+				// Seri artık tekrar etmiyorsa gerçekleşme bağımsız bir etkinliktir
+				writeCommon(w, o, cal.Location, planviaUID(o.ID))
+			}
+			w.line("END:VEVENT")
+		}
+	}
+
+	w.line("END:VCALENDAR")
+	return []byte(w.String())
+}
+
+// exdates serinin iptal edilen ve atlanan gerçekleşmelerini döndürür. ExceptionDates ayrı
+// düzenlenmiş gerçekleşmeleri de içerir; bunlar RECURRENCE-ID ile yazıldığı için EXDATE'e
+// eklenmez, aksi halde bazı takvim uygulamaları düzenlenmiş gerçekleşmeyi de gizler.
+func (cal *Calendar) exdates(r models.Reservation, edited []models.Reservation) []time.Time {
+	seen := map[int64]bool{}
+	for _, o := range edited {
+		seen[o.RecurrenceID.UnixNano()] = true
+	}
+
+	var dates []time.Time
+	add := func(t time.Time) {
+		if !seen[t.UnixNano()] {
+			seen[t.UnixNano()] = true
+			dates = append(dates, t)
+		}
+	}
+	for _, ex := range r.ExceptionDates {
+		add(ex)
+	}
+	if cal.Skipped != nil {
+		for _, t := range cal.Skipped(r) {
+			add(t)
+		}
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// UID rezervasyonun takvimdeki kimliğidir. Takvim uygulamalarından gelen (.ics ile içe
+// aktarılan veya CalDAV ile kaydedilen) etkinlikler kendi UID'lerini korur.
+func UID(r models.Reservation) string {
+	if r.ImportUID != "" {
+		return r.ImportUID
+	}
+	return planviaUID(r.ID)
+}
+
+// ReservationID Planvia'nın ürettiği bir UID'deki rezervasyon ID'sini döndürür
+func ReservationID(uid string) (primitive.ObjectID, bool) {
+	hex, ok := strings.CutSuffix(uid, "@"+uidDomain)
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	return id, err == nil
+}
+
+func planviaUID(id primitive.ObjectID) string {
+	return id.Hex() + "@" + uidDomain
+}
+
+// writeCommon VEVENT'in kimlik, zaman ve başlık satırlarını yazar
+func writeCommon(w *writer, r models.Reservation, fallback *time.Location, uid string) {
+	loc := r.Location(fallback)
+
+	stamp := r.UpdatedAt
+	if stamp.IsZero() {
+		stamp = r.CreatedAt
+	}
+	w.line("UID:" + escape(uid))
+	w.line("DTSTAMP:" + stamp.UTC().Format(utcLayout))
+	if !r.CreatedAt.IsZero() {
+		w.line("CREATED:" + r.CreatedAt.UTC().Format(utcLayout))
+	}
+	if !r.UpdatedAt.IsZero() {
+		w.line("LAST-MODIFIED:" + r.UpdatedAt.UTC().Format(utcLayout))
+	}
+
+	if r.IsAllDay {
+		start, end := allDayBounds(r, loc)
+		w.line("DTSTART;VALUE=DATE:" + start)
+		w.line("DTEND;VALUE=DATE:" + end)
+	} else {
+		w.prop("DTSTART", r, loc, r.StartDate)
+		w.prop("DTEND", r, loc, r.EndDate)
+	}
+
+	w.line("SUMMARY:" + escape(r.Name))
+	if r.Capacity > 0 {
+		w.line("DESCRIPTION:" + escape(fmt.Sprintf("Kapasite: %d", r.Capacity)))
+	}
+}
+
+// allDayBounds tüm gün rezervasyonun ilk gününü ve son günü izleyen günü döndürür
+func allDayBounds(r models.Reservation, loc *time.Location) (string, string) {
+	start := r.StartDate.In(loc)
+	if d, err := time.ParseInLocation("2006-01-02", r.StartDay, loc); err == nil {
+		start = d
+	}
+	end := r.EndDate.In(loc)
+	if d, err := time.ParseInLocation("2006-01-02", r.EndDay, loc); err == nil {
+		end = d.AddDate(0, 0, 1)
+	}
+	if !end.After(start) {
+		end = start.AddDate(0, 0, 1)
+	}
+	return start.Format(dateLayout), end.Format(dateLayout)
+}
+
+// rrule serinin RRULE değerini döndürür. UNTIL, DTSTART ile aynı türde olmalıdır: tüm gün
+// serilerde son günün tarihi, diğerlerinde UTC an olarak yazılır. Gün olarak yorumlanan
+// bitiş tarihi o günün son saniyesi, tam bir bitiş anı kendisi olur.
+func rrule(r models.Reservation, loc *time.Location) string {
+	p := r.Recurrence
+	until := ""
+	if (p.EndType == recurrence.EndOn || p.EndType == recurrence.EndUntil) && p.EndDate != nil {
+		d := p.EndDate.In(loc)
+		switch {
+		case r.IsAllDay:
+			until = d.Format(dateLayout)
+		case p.EndType == recurrence.EndOn:
+			until = time.Date(d.Year(), d.Month(), d.Day(), 23, 59, 59, 0, loc).UTC().Format(utcLayout)
+		default:
+			until = d.UTC().Format(utcLayout)
+		}
+		p.EndType = recurrence.EndNever
+	}
+
+	rule := recurrence.Format(p, loc)
+	if until != "" {
+		rule += ";UNTIL=" + until
+	}
+	return rule
+}
+
+// writeTimezone saat diliminin from ile until arasındaki geçişlerini VTIMEZONE olarak yazar
+func writeTimezone(w *writer, loc *time.Location, from, until time.Time) {
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + loc.String())
+
+	t := from.In(loc)
+	for {
+		name, offset := t.Zone()
+		start, end := t.ZoneBounds()
+
+		onset, fromOffset := firstObservance, offset
+		if !start.IsZero() {
+			_, fromOffset = start.Add(-time.Second).Zone()
+			onset = start.In(time.FixedZone("", fromOffset)).Format(localLayout)
+		}
+
+		kind := "STANDARD"
+		if t.IsDST() {
+			kind = "DAYLIGHT"
+		}
+		w.line("BEGIN:" + kind)
+		w.line("DTSTART:" + onset)
+		w.line("TZOFFSETFROM:" + formatOffset(fromOffset))
+		w.line("TZOFFSETTO:" + formatOffset(offset))
+		w.line("TZNAME:" + escape(name))
+		w.line("END:" + kind)
+
+		if end.IsZero() || end.After(until) {
+			break
+		}
+		t = end.In(loc)
+	}
+
+	w.line("END:VTIMEZONE")
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	offset := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+	if s := seconds % 60; s != 0 {
+		offset += fmt.Sprintf("%02d", s)
+	}
+	return offset
+}
+
+func isUTC(loc *time.Location) bool {
+	return loc == time.UTC || loc.String() == "UTC"
+}
+
+// escape TEXT değerlerindeki özel karakterleri kaçışlar
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// writer satırları CRLF ile bitirir ve 75 baytı aşan satırları katlar
+type writer struct {
+	strings.Builder
+}
+
+func (w *writer) line(s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		// Çok baytlı karakterler bölünmez
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// Devam satırları boşlukla başladığı için bir bayt kısadır
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// prop rezervasyonun saat diliminde bir veya daha fazla zaman değeri yazar; tüm gün
+// rezervasyonlarda değerler yerel gün olarak yazılır
+func (w *writer) prop(name string, r models.Reservation, loc *time.Location, times ...time.Time) {
+	values := make([]string, len(times))
+	switch {
+	case r.IsAllDay:
+		for i, t := range times {
+			values[i] = t.In(loc).Format(dateLayout)
+		}
+		name += ";VALUE=DATE"
+	case isUTC(loc):
+		for i, t := range times {
+			values[i] = t.UTC().Format(utcLayout)
+		}
+	default:
+		for i, t := range times {
+			values[i] = t.In(loc).Format(localLayout)
+		}
+		name += ";TZID=" + loc.String()
+	}
+	w.line(name + ":" + strings.Join(values, ","))
+}
@@ -0,0 +1,348 @@
+package ical
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/recurrence"
+)
+
+var ErrNotCalendar = errors.New("Dosya geçerli bir iCalendar (VCALENDAR) belgesi değil")
+
+// Event .ics dosyasından okunan bir VEVENT'tir. Okunamayan etkinliklerde Invalid nedeni taşır.
+type Event struct {
+	UID          string
+	Summary      string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	Location     *time.Location // başlangıcın saat dilimi
+	Recurrence   models.RecurrencePattern
+	ExDates      []time.Time
+	RecurrenceID *time.Time
+	Cancelled    bool
+	Invalid      string
+}
+
+// windowsZones Outlook ve Exchange'in yaygın Windows saat dilimi adlarının IANA karşılıklarıdır
+var windowsZones = map[string]string{
+	"Turkey Standard Time":           "Europe/Istanbul",
+	"GTB Standard Time":              "Europe/Bucharest",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"FLE Standard Time":              "Europe/Kiev",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Central European Standard Time": "Europe/Warsaw",
+	"Romance Standard Time":          "Europe/Paris",
+	"GMT Standard Time":              "Europe/London",
+	"Russian Standard Time":          "Europe/Moscow",
+	"Arabian Standard Time":          "Asia/Dubai",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"UTC":                            "UTC",
+	"Coordinated Universal Time":     "UTC",
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// property içerik satırının adı, parametreleri ve değeridir
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse .ics belgesindeki VEVENT'leri okur. Saat dilimi belirtilmeyen yerel zamanlar ve
+// tüm gün tarihler belgenin X-WR-TIMEZONE'unda, o da yoksa fallback'te yorumlanır.
+func Parse(data []byte, fallback *time.Location) ([]Event, error) {
+	lines := unfold(data)
+
+	var events []Event
+	var current []property
+	inCalendar, depth := false, 0
+	for _, line := range lines {
+		p, ok := parseProperty(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VCALENDAR"):
+			inCalendar = true
+		case p.name == "X-WR-TIMEZONE" && depth == 0:
+			if loc, ok := resolveZone(p.value); ok {
+				fallback = loc
+			}
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			current, depth = []property{}, 1
+		case p.name == "BEGIN" && current != nil:
+			// Etkinlik içindeki VALARM gibi alt bileşenler atlanır
+			depth++
+		case p.name == "END" && current != nil:
+			depth--
+			if depth == 0 {
+				events = append(events, newEvent(current, fallback))
+				current = nil
+			}
+		case current != nil && depth == 1:
+			current = append(current, p)
+		}
+	}
+
+	if !inCalendar {
+		return nil, ErrNotCalendar
+	}
+	return events, nil
+}
+
+func newEvent(props []property, fallback *time.Location) Event {
+	e := Event{Location: fallback}
+	var rrule string
+	var dtstart, dtend, duration *property
+
+	for i := range props {
+		p := &props[i]
+		switch p.name {
+		case "UID":
+			e.UID = unescape(p.value)
+		case "SUMMARY":
+			e.Summary = unescape(p.value)
+		case "STATUS":
+			e.Cancelled = strings.EqualFold(p.value, "CANCELLED")
+		case "DTSTART":
+			dtstart = p
+		case "DTEND":
+			dtend = p
+		case "DURATION":
+			duration = p
+		case "RRULE":
+			rrule = p.value
+		}
+	}
+
+	if dtstart == nil {
+		e.Invalid = "DTSTART eksik"
+		return e
+	}
+	start, allDay, loc, err := parseTime(*dtstart, fallback)
+	if err != nil {
+		e.Invalid = err.Error()
+		return e
+	}
+	e.Start, e.AllDay, e.Location = start, allDay, loc
+
+	switch {
+	case dtend != nil:
+		if e.End, _, _, err = parseTime(*dtend, fallback); err != nil {
+			e.Invalid = err.Error()
+			return e
+		}
+	case duration != nil:
+		d, days, err := parseDuration(duration.value)
+		if err != nil {
+			e.Invalid = err.Error()
+			return e
+		}
+		// Gün ve hafta süreleri yerel takvim günü olarak eklenir (RFC 5545 3.3.6)
+		e.End = start.AddDate(0, 0, days).Add(d)
+	case allDay:
+		e.End = start.AddDate(0, 0, 1)
+	default:
+		e.End = start
+	}
+	if e.End.Before(e.Start) {
+		e.Invalid = "DTEND, DTSTART'tan önce olamaz"
+		return e
+	}
+
+	for i := range props {
+		p := props[i]
+		switch p.name {
+		case "EXDATE":
+			for _, v := range strings.Split(p.value, ",") {
+				p.value = v
+				t, _, _, err := parseTime(p, fallback)
+				if err != nil {
+					e.Invalid = err.Error()
+					return e
+				}
+				e.ExDates = append(e.ExDates, t)
+			}
+		case "RECURRENCE-ID":
+			t, _, _, err := parseTime(p, fallback)
+			if err != nil {
+				e.Invalid = err.Error()
+				return e
+			}
+			e.RecurrenceID = &t
+		}
+	}
+
+	if rrule != "" {
+		pattern, err := recurrence.Parse(rrule, loc)
+		if err != nil {
+			e.Invalid = err.Error()
+			return e
+		}
+		e.Recurrence = pattern
+	}
+
+	// UID'siz etkinlikler için içerikten kararlı bir UID türetilir; böylece yeniden içe
+	// aktarmada da aynı etkinlik olarak tanınır
+	if e.UID == "" {
+		sum := sha256.Sum256([]byte(e.Summary + "|" + e.Start.UTC().Format(utcLayout) + "|" + rrule))
+		e.UID = "planvia-import-" + hex.EncodeToString(sum[:12])
+	}
+	return e
+}
+
+// parseTime DATE veya DATE-TIME değerini okur; tüm gün olup olmadığını ve saat dilimini de döndürür
+func parseTime(p property, fallback *time.Location) (time.Time, bool, *time.Location, error) {
+	value := strings.TrimSpace(p.value)
+	loc := fallback
+	if tzid, ok := p.params["TZID"]; ok {
+		resolved, ok := resolveZone(tzid)
+		if !ok {
+			return time.Time{}, false, nil, fmt.Errorf("Tanınmayan saat dilimi: %s", tzid)
+		}
+		loc = resolved
+	}
+
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(value) == len(dateLayout) {
+		t, err := time.ParseInLocation(dateLayout, value, loc)
+		if err != nil {
+			return time.Time{}, false, nil, fmt.Errorf("Geçersiz tarih: %s", value)
+		}
+		return t, true, loc, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcLayout, value)
+		if err != nil {
+			return time.Time{}, false, nil, fmt.Errorf("Geçersiz zaman: %s", value)
+		}
+		return t, false, time.UTC, nil
+	}
+	t, err := time.ParseInLocation(localLayout, value, loc)
+	if err != nil {
+		return time.Time{}, false, nil, fmt.Errorf("Geçersiz zaman: %s", value)
+	}
+	return t, false, loc, nil
+}
+
+// resolveZone TZID'yi IANA saat dilimine çevirir. "/mozilla.org/.../Europe/Berlin" gibi
+// önekli adlar ve yaygın Windows adları da tanınır.
+func resolveZone(tzid string) (*time.Location, bool) {
+	tzid = strings.Trim(strings.TrimSpace(tzid), `"`)
+	if tzid == "" {
+		return nil, false
+	}
+	if name, ok := windowsZones[tzid]; ok {
+		tzid = name
+	}
+	if loc, err := time.LoadLocation(tzid); err == nil && !strings.HasPrefix(tzid, "/") {
+		return loc, true
+	}
+
+	parts := strings.Split(strings.Trim(tzid, "/"), "/")
+	for n := 3; n >= 2; n-- {
+		if len(parts) < n {
+			continue
+		}
+		if loc, err := time.LoadLocation(strings.Join(parts[len(parts)-n:], "/")); err == nil {
+			return loc, true
+		}
+	}
+	return nil, false
+}
+
+// parseDuration RFC 5545 DURATION değerini saat süresi ve takvim günü olarak ayırır
+func parseDuration(value string) (time.Duration, int, error) {
+	m := durationPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(value)))
+	if m == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, 0, fmt.Errorf("Geçersiz süre: %s", value)
+	}
+	num := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	days := num(m[2])*7 + num(m[3])
+	d := time.Duration(num(m[4]))*time.Hour + time.Duration(num(m[5]))*time.Minute + time.Duration(num(m[6]))*time.Second
+	if m[1] == "-" {
+		return 0, 0, fmt.Errorf("Negatif süre kabul edilmez: %s", value)
+	}
+	return d, days, nil
+}
+
+// unfold katlanmış satırları birleştirir
+func unfold(data []byte) []string {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseProperty "AD;PARAM=değer:değer" biçimindeki içerik satırını ayrıştırır
+func parseProperty(line string) (property, bool) {
+	// Değer, tırnak dışındaki ilk iki nokta üst üsteden sonra başlar
+	inQuotes, colon := false, -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, false
+	}
+
+	head := line[:colon]
+	p := property{value: line[colon+1:], params: map[string]string{}}
+	parts := splitUnquoted(head, ';')
+	p.name = strings.ToUpper(strings.TrimSpace(parts[0]))
+	for _, param := range parts[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			p.params[strings.ToUpper(strings.TrimSpace(k))] = strings.Trim(v, `"`)
+		}
+	}
+	return p, p.name != ""
+}
+
+func splitUnquoted(s string, sep rune) []string {
+	var parts []string
+	inQuotes, last := false, 0
+	for i, r := range s {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == sep && !inQuotes {
+			parts = append(parts, s[last:i])
+			last = i + 1
+		}
+	}
+	return append(parts, s[last:])
+}
+
+var unescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
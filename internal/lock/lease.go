@@ -0,0 +1,113 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// retryInterval kilit başkasındayken yeniden deneme aralığıdır
+const retryInterval = 50 * time.Millisecond
+
+var ErrTimeout = errors.New("kilit beklenen sürede alınamadı")
+
+// Locker birden fazla API örneği arasında geçerli, süreli kilitleri (lease) MongoDB'de tutar.
+// Kilidi alan örnek çökse bile kilit süresi dolunca başkası tarafından alınabilir.
+type Locker struct {
+	collection *mongo.Collection
+}
+
+func NewLocker(db *mongo.Database) *Locker {
+	return &Locker{collection: db.Collection("locks")}
+}
+
+// Lease alınmış bir kilittir; iş bitince Release çağrılmalıdır. Kilit tutulduğu sürece
+// arka planda yenilenir, böylece süresinden uzun süren işlerde başkasına geçmez.
+type Lease struct {
+	locker *Locker
+	key    string
+	owner  string
+	ttl    time.Duration
+	stop   chan struct{}
+	lost   atomic.Bool
+}
+
+// Acquire key için ttl süreli bir kilit alır. Kilit başkasındaysa wait süresi boyunca yeniden dener,
+// süre dolarsa ErrTimeout döner.
+func (l *Locker) Acquire(ctx context.Context, key string, ttl, wait time.Duration) (*Lease, error) {
+	owner := primitive.NewObjectID().Hex()
+	deadline := time.Now().Add(wait)
+
+	for {
+		// Kilit yoksa eklenir, süresi dolmuşsa devralınır. Geçerli bir kilit varsa filtre eşleşmez,
+		// upsert aynı _id ile eklemeye çalışır ve duplicate key hatası alır.
+		now := time.Now()
+		_, err := l.collection.UpdateOne(ctx,
+			bson.M{"_id": key, "expires_at": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(ttl)}},
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			lease := &Lease{locker: l, key: key, owner: owner, ttl: ttl, stop: make(chan struct{})}
+			go lease.keepAlive()
+			return lease, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+
+		if time.Now().Add(retryInterval).After(deadline) {
+			return nil, ErrTimeout
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// keepAlive kilidin süresini her ttl'in üçte birinde uzatır. Kilit başkasına geçtiyse
+// (ör. yenileme uzun süre başarısız olduysa) kilit kaybedilmiş sayılır ve yenileme durur.
+func (lease *Lease) keepAlive() {
+	ticker := time.NewTicker(lease.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lease.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), lease.ttl/3)
+		result, err := lease.locker.collection.UpdateOne(ctx,
+			bson.M{"_id": lease.key, "owner": lease.owner},
+			bson.M{"$set": bson.M{"expires_at": time.Now().Add(lease.ttl)}},
+		)
+		cancel()
+		if err == nil && result.MatchedCount == 0 {
+			lease.lost.Store(true)
+			return
+		}
+	}
+}
+
+// Lost kilidin süresi dolup başkasına geçip geçmediğini döndürür. Kilidin koruduğu
+// yazmadan önce bakılmalıdır.
+func (lease *Lease) Lost() bool {
+	return lease.lost.Load()
+}
+
+// Release kilidi bırakır ve yenilemeyi durdurur. Kilit süresi dolup başkasına geçtiyse dokunulmaz.
+func (lease *Lease) Release(ctx context.Context) error {
+	close(lease.stop)
+	_, err := lease.locker.collection.DeleteOne(ctx, bson.M{"_id": lease.key, "owner": lease.owner})
+	return err
+}
@@ -0,0 +1,68 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/config"
+)
+
+// Message gönderilecek bir e-postayı temsil eder
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer e-posta gönderim altyapısı için takılabilir arayüzdür
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New config'deki sürücüye göre bir Mailer oluşturur
+func New(cfg config.MailerConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return &LogMailer{from: cfg.From}, nil
+	case "file":
+		return &FileMailer{from: cfg.From, path: cfg.FilePath}, nil
+	default:
+		return nil, fmt.Errorf("desteklenmeyen mailer sürücüsü %q", cfg.Driver)
+	}
+}
+
+// LogMailer e-postaları uygulama loguna yazar, yerel geliştirme içindir
+type LogMailer struct {
+	from string
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail from=%s to=%s subject=%q\n%s", m.from, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer e-postaları bir dosyaya ekler, yerel geliştirme içindir
+type FileMailer struct {
+	from string
+	path string
+	mu   sync.Mutex
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC1123Z), m.from, msg.To, msg.Subject, msg.Body)
+	return err
+}
\ No newline at end of file
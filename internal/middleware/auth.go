@@ -3,52 +3,177 @@ package middleware
 import (
 	"strings"
 
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// AuthMiddleware JWT token'ı doğrular ve partner ID'yi context'e ekler
-func AuthMiddleware(c *fiber.Ctx) error {
-	// Authorization header'ı kontrol et
-	authHeader := c.Get("Authorization")
-	if authHeader == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Authorization header eksik",
-		})
+// AuthConfig AuthMiddleware ve BasicAuthMiddleware'in bağımlılıklarını tutar. Staff, Throttle
+// ve AppPasswords yalnızca BasicAuthMiddleware tarafından kullanılır.
+type AuthConfig struct {
+	Keys         *auth.KeySet
+	Sessions     *auth.SessionStore
+	APIKeys      *auth.APIKeyStore
+	Partners     *mongo.Collection
+	Staff        *mongo.Collection
+	Throttle     *auth.LoginThrottle
+	AppPasswords *auth.AppPasswordStore
+}
+
+// APIKeyHeader sunucudan sunucuya entegrasyonların API key gönderdiği header'dır
+const APIKeyHeader = "X-API-Key"
+
+// AuthMiddleware Bearer JWT veya API key'i doğrular; partner ID, kullanıcı ID ve
+// rolü context'e ekler. İki yöntem de aynı partnerId local'ine çözülür.
+func AuthMiddleware(cfg AuthConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Authorization header'ı kontrol et
+		authHeader := c.Get("Authorization")
+		if authHeader == "" {
+			if apiKey := c.Get(APIKeyHeader); apiKey != "" {
+				return authenticateAPIKey(c, cfg, apiKey)
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Authorization header eksik",
+			})
+		}
+
+		// Bearer token'ı ayır
+		tokenParts := strings.Split(authHeader, " ")
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Geçersiz token formatı",
+			})
+		}
+
+		// Token'ı kid header'ındaki anahtarla doğrula
+		claims, err := cfg.Keys.Parse(tokenParts[1])
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token doğrulanamadı",
+			})
+		}
+
+		// Sadece access token'lar kabul edilir
+		partnerID, _ := claims["partnerId"].(string)
+		sessionID, _ := claims["sid"].(string)
+		if claims[auth.ClaimTokenType] != auth.TokenTypeAccess || partnerID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Geçersiz token",
+			})
+		}
+
+		// Oturumun iptal edilmediğini kontrol et
+		sessionObjID, err := primitive.ObjectIDFromHex(sessionID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Geçersiz token",
+			})
+		}
+		active, err := cfg.Sessions.IsActive(c.Context(), sessionObjID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Veritabanı hatası",
+			})
+		}
+		if !active {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Oturum sonlandırılmış",
+			})
+		}
+
+		// Partner hesabının durumunu kontrol et
+		if ok, err := checkPartnerStatus(c, cfg.Partners, partnerID); !ok {
+			return err
+		}
+
+		// Rol içermeyen token'lar personel hesaplarından önce verilmiştir ve partner'ın kendisine aittir
+		userID, _ := claims["userId"].(string)
+		role, _ := claims["role"].(string)
+		if role == "" {
+			userID = partnerID
+			role = auth.RoleOwner
+		}
+
+		// Partner, kullanıcı, rol ve oturum ID'sini context'e ekle
+		c.Locals("partnerId", partnerID)
+		c.Locals("userId", userID)
+		c.Locals("role", role)
+		c.Locals("sessionId", sessionID)
+		return c.Next()
 	}
+}
 
-	// Bearer token'ı ayır
-	tokenParts := strings.Split(authHeader, " ")
-	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+// authenticateAPIKey API key ile gelen isteği doğrular. Key'in scope'ları
+// RequirePermission tarafından kontrol edilir.
+func authenticateAPIKey(c *fiber.Ctx, cfg AuthConfig, rawKey string) error {
+	key, err := cfg.APIKeys.Authenticate(c.Context(), rawKey)
+	if err == auth.ErrAPIKeyInvalid {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Geçersiz token formatı",
+			"error": "API key geçersiz",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
 		})
 	}
 
-	// Token'ı doğrula
-	token, err := jwt.Parse(tokenParts[1], func(token *jwt.Token) (interface{}, error) {
-		// Token'ın signing method'unu kontrol et
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fiber.NewError(fiber.StatusUnauthorized, "Geçersiz token")
-		}
-		// TODO: JWT secret key'i config'den al
-		return []byte("your-secret-key"), nil
-	})
+	partnerID := key.PartnerID.Hex()
+	if ok, err := checkPartnerStatus(c, cfg.Partners, partnerID); !ok {
+		return err
+	}
 
+	c.Locals("partnerId", partnerID)
+	c.Locals("userId", "")
+	c.Locals("role", auth.RoleAPIKey)
+	c.Locals("apiKeyId", key.ID.Hex())
+	c.Locals("scopes", key.Scopes)
+	return c.Next()
+}
+
+// checkPartnerStatus askıya alınmış veya doğrulanmamış hesapların isteklerini reddeder.
+// false dönerse hata yanıtı yazılmıştır.
+func checkPartnerStatus(c *fiber.Ctx, partners *mongo.Collection, partnerID string) (bool, error) {
+	partnerObjID, err := primitive.ObjectIDFromHex(partnerID)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Token doğrulanamadı",
+		return false, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Geçersiz token",
 		})
 	}
 
-	// Token claims'i kontrol et
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// Partner ID'yi context'e ekle
-		c.Locals("partnerId", claims["partnerId"])
-		return c.Next()
+	var partner models.Partner
+	err = partners.FindOne(c.Context(), bson.M{"_id": partnerObjID},
+		options.FindOne().SetProjection(bson.M{"status": 1}),
+	).Decode(&partner)
+	if err == mongo.ErrNoDocuments {
+		return false, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Geçersiz token",
+		})
+	}
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Veritabanı hatası",
+		})
+	}
+
+	switch partner.AccountStatus() {
+	case models.PartnerStatusPending:
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "E-posta adresiniz henüz doğrulanmadı",
+			"code":  "EMAIL_NOT_VERIFIED",
+		})
+	case models.PartnerStatusSuspended:
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Hesabınız askıya alınmış",
+			"code":  "ACCOUNT_SUSPENDED",
+		})
 	}
 
-	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-		"error": "Geçersiz token",
-	})
-} 
\ No newline at end of file
+	return true, nil
+}
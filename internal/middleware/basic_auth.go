@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// basicRealm takvim uygulamalarına gösterilen kimlik doğrulama alanıdır
+const basicRealm = `Basic realm="Planvia", charset="UTF-8"`
+
+// basicAccount HTTP Basic ile giriş yapan partner veya personel hesabıdır
+type basicAccount struct {
+	partnerID primitive.ObjectID
+	userID    primitive.ObjectID
+	role      string
+	password  string
+	twoFactor bool
+	status    string
+}
+
+// BasicAuthMiddleware Bearer token gönderemeyen takvim uygulamaları (CalDAV) için HTTP Basic
+// kimlik doğrulaması yapar. Kullanıcı adı hesabın e-posta adresi, şifre hesap şifresi veya
+// uygulama şifresidir; iki adımlı doğrulaması açık hesaplar yalnızca uygulama şifresi
+// kullanabilir. Başarısız denemeler girişle aynı sayaçlara yazılır. Partner ID, kullanıcı ID
+// ve rol AuthMiddleware'deki gibi context'e eklenir.
+func BasicAuthMiddleware(cfg AuthConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		email, password, ok := basicCredentials(c.Get(fiber.HeaderAuthorization))
+		if !ok {
+			return basicUnauthorized(c, "Kullanıcı adı ve şifre gerekli")
+		}
+
+		ip := c.IP()
+		throttle, err := cfg.Throttle.Reserve(c.Context(), email, ip)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Veritabanı hatası",
+			})
+		}
+		if !throttle.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(throttle.RetryAfter.Seconds()))))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Çok fazla başarısız deneme, lütfen daha sonra tekrar deneyin",
+				"code":  "TOO_MANY_ATTEMPTS",
+			})
+		}
+
+		account, err := findBasicAccount(c, cfg, email)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Veritabanı hatası",
+			})
+		}
+
+		authenticated := false
+		if account != nil {
+			_, err := cfg.AppPasswords.Authenticate(c.Context(), account.userID, password)
+			if err != nil && err != auth.ErrAppPasswordInvalid {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Veritabanı hatası",
+				})
+			}
+			authenticated = err == nil
+			if !authenticated && !account.twoFactor {
+				authenticated = bcrypt.CompareHashAndPassword([]byte(account.password), []byte(password)) == nil
+			}
+		}
+		if !authenticated {
+			if err := cfg.Throttle.RecordFailure(c.Context(), email, ip); err != nil {
+				log.Printf("Failed login could not be recorded for %s: %v", email, err)
+			}
+			return basicUnauthorized(c, "Geçersiz email veya şifre; iki adımlı doğrulama açıksa uygulama şifresi kullanın")
+		}
+
+		if err := cfg.Throttle.RecordSuccess(c.Context(), email, ip); err != nil {
+			log.Printf("Login attempts could not be reset for %s: %v", email, err)
+		}
+
+		if account.status == models.StaffStatusDisabled {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Personel hesabı devre dışı",
+				"code":  "ACCOUNT_DISABLED",
+			})
+		}
+		partnerID := account.partnerID.Hex()
+		if ok, err := checkPartnerStatus(c, cfg.Partners, partnerID); !ok {
+			return err
+		}
+
+		c.Locals("partnerId", partnerID)
+		c.Locals("userId", account.userID.Hex())
+		c.Locals("role", account.role)
+		return c.Next()
+	}
+}
+
+// findBasicAccount e-posta adresine ait partner veya personel hesabını getirir; yoksa nil döner
+func findBasicAccount(c *fiber.Ctx, cfg AuthConfig, email string) (*basicAccount, error) {
+	var partner models.Partner
+	err := cfg.Partners.FindOne(c.Context(), bson.M{"email": email}).Decode(&partner)
+	if err == nil {
+		return &basicAccount{
+			partnerID: partner.ID,
+			userID:    partner.ID,
+			role:      auth.RoleOwner,
+			password:  partner.Password,
+			twoFactor: partner.TwoFactor.Enabled,
+		}, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	var staff models.StaffUser
+	err = cfg.Staff.FindOne(c.Context(), bson.M{"email": email}).Decode(&staff)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &basicAccount{
+		partnerID: staff.PartnerID,
+		userID:    staff.ID,
+		role:      staff.Role,
+		password:  staff.Password,
+		status:    staff.Status,
+	}, nil
+}
+
+// basicCredentials "Basic base64(kullanıcı:şifre)" başlığını çözer
+func basicCredentials(header string) (string, string, bool) {
+	scheme, encoded, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Basic") {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return "", "", false
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok || username == "" || password == "" {
+		return "", "", false
+	}
+	return username, password, true
+}
+
+// basicUnauthorized takvim uygulamasının kimlik bilgisi istemesi için 401 döndürür
+func basicUnauthorized(c *fiber.Ctx, message string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, basicRealm)
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": message,
+	})
+}
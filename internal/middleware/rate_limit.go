@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rateLimitTimeout sayaç artırmanın en uzun süresidir
+const rateLimitTimeout = 5 * time.Second
+
+// RateLimit her IP adresinin window süresinde en fazla max istek yapmasına izin verir.
+// Sayaçlar sabit pencerelerle MongoDB'de tutulur ve her istekte tek bir $inc ile artırılır;
+// böylece bütün API örnekleri aynı sayaçları paylaşır ve eşzamanlı istekler kaybolmaz.
+func RateLimit(collection *mongo.Collection, max int, window time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		now := time.Now()
+		windowStart := now.Truncate(window)
+		resetAt := windowStart.Add(window)
+
+		count, err := incrementRate(collection, c.IP()+":"+strconv.FormatInt(windowStart.Unix(), 10), resetAt)
+		if err != nil {
+			log.Printf("Rate limit counter could not be updated: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Veritabanı hatası",
+			})
+		}
+
+		remaining := int64(max) - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		retryAfter := int(math.Ceil(resetAt.Sub(now).Seconds()))
+		c.Set("X-RateLimit-Limit", strconv.Itoa(max))
+		c.Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+		c.Set("X-RateLimit-Reset", strconv.Itoa(retryAfter))
+		if count > int64(max) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Çok fazla istek gönderildi, lütfen biraz sonra tekrar deneyin",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// incrementRate pencerenin sayacını artırır ve yeni değerini döndürür. Aynı sayacı ilk kez
+// oluşturan iki istekten biri benzersiz anahtar hatası alır; o istek tekrar denenir.
+func incrementRate(collection *mongo.Collection, key string, expiresAt time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rateLimitTimeout)
+	defer cancel()
+
+	var counter struct {
+		Count int64 `bson:"count"`
+	}
+	update := bson.M{
+		"$inc":         bson.M{"count": 1},
+		"$setOnInsert": bson.M{"expires_at": expiresAt},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&counter)
+	if mongo.IsDuplicateKeyError(err) {
+		err = collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&counter)
+	}
+	return counter.Count, err
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"github.com/denizbarcak/planvia-partner-api/internal/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequirePermission AuthMiddleware'in context'e eklediği rolün (API key için
+// key'in scope'larının) verilen izne sahip olmasını ister
+func RequirePermission(perm auth.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		allowed := auth.HasPermission(role, perm)
+		if role == auth.RoleAPIKey {
+			scopes, _ := c.Locals("scopes").([]string)
+			allowed = auth.APIKeyAllows(scopes, perm)
+		}
+		if !allowed {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Bu işlem için yetkiniz yok",
+				"code":  "FORBIDDEN",
+			})
+		}
+		return c.Next()
+	}
+}
\ No newline at end of file
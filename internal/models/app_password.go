@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AppPassword lets a partner or staff user sign in to CalDAV clients without
+// their account password. Only the hash of the password is stored.
+type AppPassword struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PartnerID    primitive.ObjectID `bson:"partner_id" json:"partnerId"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"userId"`
+	Name         string             `bson:"name" json:"name"`
+	PasswordHash string             `bson:"password_hash" json:"-"`
+	CreatedAt    time.Time          `bson:"created_at" json:"createdAt"`
+	LastUsedAt   *time.Time         `bson:"last_used_at,omitempty" json:"lastUsedAt,omitempty"`
+}
+
+// CreateAppPasswordRequest represents the data for a new app password
+type CreateAppPasswordRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Müşteri rezervasyonu durumları
+const (
+	BookingStatusConfirmed  = "confirmed"
+	BookingStatusWaitlisted = "waitlisted"
+	BookingStatusCancelled  = "cancelled"
+)
+
+// Booking bir müşterinin rezervasyonun (tekrar ediyorsa bir gerçekleşmesinin) kapasitesinden
+// ayırdığı yerlerdir. OccurrenceStart tekrar etmeyen rezervasyonlarda başlangıç tarihidir.
+// Bekleme listesindeki kayıtlar yer tutmaz; yer açıldığında sırayla onaylanır.
+type Booking struct {
+	ID              primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	PartnerID       primitive.ObjectID  `json:"partnerId" bson:"partnerId"`
+	ReservationID   primitive.ObjectID  `json:"reservationId" bson:"reservationId"`
+	OccurrenceStart time.Time           `json:"occurrenceStart" bson:"occurrenceStart"`
+	CustomerName    string              `json:"customerName" bson:"customerName"`
+	CustomerEmail   string              `json:"customerEmail,omitempty" bson:"customerEmail,omitempty"`
+	CustomerPhone   string              `json:"customerPhone,omitempty" bson:"customerPhone,omitempty"`
+	PartySize       int                 `json:"partySize" bson:"partySize"`
+	Status          string              `json:"status" bson:"status"`
+	Notes           string              `json:"notes,omitempty" bson:"notes,omitempty"`
+	HoldID          *primitive.ObjectID `json:"holdId,omitempty" bson:"holdId,omitempty"`
+	CreatedAt       time.Time           `json:"createdAt" bson:"createdAt"`
+	UpdatedAt       time.Time           `json:"updatedAt" bson:"updatedAt"`
+	PromotedAt      *time.Time          `json:"promotedAt,omitempty" bson:"promotedAt,omitempty"`
+	CancelledAt     *time.Time          `json:"cancelledAt,omitempty" bson:"cancelledAt,omitempty"`
+	PendingEventID  *primitive.ObjectID `json:"-" bson:"pendingEventId,omitempty"` // onaylandı ama booking.promoted olayı henüz yayınlanmadı
+}
+
+// CreateBookingRequest yeni bir müşteri rezervasyonunun verileridir. Tekrar eden
+// rezervasyonlarda OccurrenceStart hangi gerçekleşmenin ayrıldığını belirtir. Waitlist
+// seçilirse yer kalmadığında istek reddedilmez, bekleme listesine eklenir.
+type CreateBookingRequest struct {
+	OccurrenceStart *time.Time `json:"occurrenceStart"`
+	CustomerName    string     `json:"customerName" validate:"required"`
+	CustomerEmail   string     `json:"customerEmail" validate:"required_without=CustomerPhone,omitempty,email"`
+	CustomerPhone   string     `json:"customerPhone" validate:"required_without=CustomerEmail,omitempty,phone"`
+	PartySize       int        `json:"partySize" validate:"required,min=1"`
+	Notes           string     `json:"notes" validate:"max=500"`
+	Waitlist        bool       `json:"waitlist"`
+}
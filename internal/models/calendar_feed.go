@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CalendarFeed is a partner's secret iCalendar subscription URL. A partner has at
+// most one feed; rotating it replaces the token. Only the hash of the token is stored.
+type CalendarFeed struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PartnerID      primitive.ObjectID `bson:"partner_id" json:"partnerId"`
+	TokenHash      string             `bson:"token_hash" json:"-"`
+	CreatedBy      primitive.ObjectID `bson:"created_by" json:"createdBy"`
+	CreatedAt      time.Time          `bson:"created_at" json:"createdAt"`
+	LastAccessedAt *time.Time         `bson:"last_accessed_at,omitempty" json:"lastAccessedAt,omitempty"`
+}
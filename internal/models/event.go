@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Partner'ın işlem yapabileceği olay türleri
+const (
+	EventBookingPromoted = "booking.promoted"
+)
+
+// Event partner'a bildirilen bir olaydır. Olaylar events koleksiyonunda (outbox) birikir,
+// partner bunları sırayla çeker.
+type Event struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PartnerID primitive.ObjectID `json:"partnerId" bson:"partnerId"`
+	Seq       int64              `json:"seq" bson:"seq"` // partner'ın olayları arasındaki sırası
+	Type      string             `json:"type" bson:"type"`
+	Data      interface{}        `json:"data" bson:"data"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
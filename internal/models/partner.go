@@ -6,20 +6,89 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Partner account states
+const (
+	PartnerStatusPending   = "pending"
+	PartnerStatusActive    = "active"
+	PartnerStatusSuspended = "suspended"
+)
+
+// Overlap policies decide what happens when a reservation clashes with another one
+const (
+	OverlapPolicyReject = "reject"
+	OverlapPolicyWarn   = "warn"
+	OverlapPolicyAllow  = "allow"
+)
+
+// Hours policies decide what happens when a reservation falls outside business hours
+// or on a closed day
+const (
+	HoursPolicyReject = "reject"
+	HoursPolicyWarn   = "warn"
+	HoursPolicyIgnore = "ignore"
+)
+
+// Holiday calendars a partner can follow
+const (
+	HolidayCalendarTurkey = "tr"
+	HolidayCalendarNone   = "none"
+)
+
+// DefaultTimeZone is used for partners that have not chosen a time zone
+const DefaultTimeZone = "Europe/Istanbul"
+
 // Partner represents a business partner in the system
 type Partner struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	CompanyName   string            `bson:"company_name" json:"companyName" validate:"required"`
-	Email         string            `bson:"email" json:"email" validate:"required,email"`
-	Password      string            `bson:"password" json:"password" validate:"required,min=6"`
-	PhoneNumber   string            `bson:"phone_number" json:"phoneNumber" validate:"required"`
-	Address       string            `bson:"address" json:"address" validate:"required"`
-	City          string            `bson:"city" json:"city" validate:"required"`
-	BusinessType  string            `bson:"business_type" json:"businessType" validate:"required"`
-	TaxNumber     string            `bson:"tax_number" json:"taxNumber" validate:"required"`
-	ContactPerson string            `bson:"contact_person" json:"contactPerson" validate:"required"`
-	CreatedAt     time.Time         `bson:"created_at" json:"createdAt,omitempty"`
-	UpdatedAt     time.Time         `bson:"updated_at" json:"updatedAt,omitempty"`
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	CompanyName     string             `bson:"company_name" json:"companyName" validate:"required"`
+	Email           string             `bson:"email" json:"email" validate:"required,email"`
+	Password        string             `bson:"password" json:"password" validate:"required,min=6"`
+	PhoneNumber     string             `bson:"phone_number" json:"phoneNumber" validate:"required,phone"`
+	Address         string             `bson:"address" json:"address" validate:"required"`
+	City            string             `bson:"city" json:"city" validate:"required"`
+	BusinessType    string             `bson:"business_type" json:"businessType" validate:"required"`
+	TaxNumber       string             `bson:"tax_number" json:"taxNumber" validate:"required,taxnumber"`
+	ContactPerson   string             `bson:"contact_person" json:"contactPerson" validate:"required"`
+	TimeZone        string             `bson:"time_zone,omitempty" json:"timeZone" validate:"omitempty,timezone"`
+	OverlapPolicy   string             `bson:"overlap_policy,omitempty" json:"overlapPolicy" validate:"omitempty,oneof=reject warn allow"`
+	BusinessHours   []BusinessHours    `bson:"business_hours,omitempty" json:"businessHours"`
+	Closures        []Closure          `bson:"closures,omitempty" json:"closures"`
+	HolidayCalendar string             `bson:"holiday_calendar,omitempty" json:"holidayCalendar"`
+	OpenHolidays    []string           `bson:"open_holidays,omitempty" json:"openHolidays"`
+	HoursPolicy     string             `bson:"hours_policy,omitempty" json:"hoursPolicy"`
+	SkipClosedDays  bool               `bson:"skip_closed_days,omitempty" json:"skipClosedDays"`
+	SettingsVersion int64              `bson:"settings_version,omitempty" json:"-"` // takvim çıktısını değiştiren ayarlarda artar
+	EmailVerified   bool               `bson:"email_verified" json:"emailVerified"`
+	Status          string             `bson:"status" json:"status"`
+	TwoFactor       TwoFactor          `bson:"two_factor,omitempty" json:"-"`
+	CreatedAt       time.Time          `bson:"created_at" json:"createdAt,omitempty"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updatedAt,omitempty"`
+}
+
+// BusinessHours is one opening interval on a weekday (0 = Sunday). Open and Close
+// are local "HH:MM" times; Close may be "24:00". A day can have several intervals.
+type BusinessHours struct {
+	Day   int    `bson:"day" json:"day" validate:"min=0,max=6"`
+	Open  string `bson:"open" json:"open" validate:"required"`
+	Close string `bson:"close" json:"close" validate:"required"`
+}
+
+// Closure closes the business from StartDay to EndDay (inclusive local dates).
+// EndDay defaults to StartDay.
+type Closure struct {
+	StartDay string `bson:"start_day" json:"startDay" validate:"required,datetime=2006-01-02"`
+	EndDay   string `bson:"end_day,omitempty" json:"endDay,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Reason   string `bson:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// TwoFactor holds the partner's TOTP settings. Recovery codes are stored hashed.
+type TwoFactor struct {
+	Enabled       bool       `bson:"enabled"`
+	Secret        string     `bson:"secret,omitempty"`
+	PendingSecret string     `bson:"pending_secret,omitempty"`
+	RecoveryCodes []string   `bson:"recovery_codes,omitempty"`
+	LastUsedStep  int64      `bson:"last_used_step,omitempty"`
+	EnabledAt     *time.Time `bson:"enabled_at,omitempty"`
 }
 
 // RegisterRequest represents the registration request data
@@ -27,12 +96,75 @@ type RegisterRequest struct {
 	CompanyName   string `json:"companyName" validate:"required"`
 	Email         string `json:"email" validate:"required,email"`
 	Password      string `json:"password" validate:"required,min=6"`
-	PhoneNumber   string `json:"phoneNumber" validate:"required"`
+	PhoneNumber   string `json:"phoneNumber" validate:"required,phone"`
 	Address       string `json:"address" validate:"required"`
 	City          string `json:"city" validate:"required"`
 	BusinessType  string `json:"businessType" validate:"required"`
-	TaxNumber     string `json:"taxNumber" validate:"required"`
+	TaxNumber     string `json:"taxNumber" validate:"required,taxnumber"`
 	ContactPerson string `json:"contactPerson" validate:"required"`
+	TimeZone      string `json:"timeZone" validate:"omitempty,timezone"`
+}
+
+// UpdateProfileRequest is a partial update of the partner profile. Only the
+// fields that are present in the request are changed.
+type UpdateProfileRequest struct {
+	CompanyName   *string `json:"companyName" validate:"omitnil,min=1"`
+	Email         *string `json:"email" validate:"omitnil,email"`
+	PhoneNumber   *string `json:"phoneNumber" validate:"omitnil,phone"`
+	Address       *string `json:"address" validate:"omitnil,min=1"`
+	City          *string `json:"city" validate:"omitnil,min=1"`
+	BusinessType  *string `json:"businessType" validate:"omitnil,min=1"`
+	TaxNumber     *string `json:"taxNumber" validate:"omitnil,taxnumber"`
+	ContactPerson *string `json:"contactPerson" validate:"omitnil,min=1"`
+	TimeZone      *string `json:"timeZone" validate:"omitnil,timezone"`
+	OverlapPolicy *string `json:"overlapPolicy" validate:"omitnil,oneof=reject warn allow"`
+
+	BusinessHours   *[]BusinessHours `json:"businessHours" validate:"omitnil,dive"`
+	Closures        *[]Closure       `json:"closures" validate:"omitnil,dive"`
+	HolidayCalendar *string          `json:"holidayCalendar" validate:"omitnil,oneof=tr none"`
+	OpenHolidays    *[]string        `json:"openHolidays" validate:"omitnil,dive,datetime=2006-01-02"`
+	HoursPolicy     *string          `json:"hoursPolicy" validate:"omitnil,oneof=reject warn ignore"`
+	SkipClosedDays  *bool            `json:"skipClosedDays"`
+}
+
+// ChangePasswordRequest requires the current password to set a new one
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=6"`
+}
+
+// VerifyEmailRequest carries the token from the verification email
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ResendVerificationRequest asks for a new verification email
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// SetupTwoFactorRequest requires the current password to start 2FA setup
+type SetupTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// TwoFactorCodeRequest carries a TOTP code from an authenticator app
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// DisableTwoFactorRequest requires the password and a TOTP or recovery code
+type DisableTwoFactorRequest struct {
+	Password     string `json:"password" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// TwoFactorLoginRequest exchanges a login challenge for session tokens
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recoveryCode"`
 }
 
 // LoginRequest represents the login request data
@@ -43,39 +175,124 @@ type LoginRequest struct {
 
 // PartnerResponse represents the partner data that is safe to send to the client
 type PartnerResponse struct {
-	ID            primitive.ObjectID `json:"id"`
-	CompanyName   string            `json:"companyName"`
-	Email         string            `json:"email"`
-	PhoneNumber   string            `json:"phoneNumber"`
-	Address       string            `json:"address"`
-	City          string            `json:"city"`
-	BusinessType  string            `json:"businessType"`
-	TaxNumber     string            `json:"taxNumber"`
-	ContactPerson string            `json:"contactPerson"`
-	CreatedAt     time.Time         `json:"createdAt"`
-	UpdatedAt     time.Time         `json:"updatedAt"`
+	ID               primitive.ObjectID `json:"id"`
+	CompanyName      string             `json:"companyName"`
+	Email            string             `json:"email"`
+	PhoneNumber      string             `json:"phoneNumber"`
+	Address          string             `json:"address"`
+	City             string             `json:"city"`
+	BusinessType     string             `json:"businessType"`
+	TaxNumber        string             `json:"taxNumber"`
+	ContactPerson    string             `json:"contactPerson"`
+	TimeZone         string             `json:"timeZone"`
+	OverlapPolicy    string             `json:"overlapPolicy"`
+	BusinessHours    []BusinessHours    `json:"businessHours"`
+	Closures         []Closure          `json:"closures"`
+	HolidayCalendar  string             `json:"holidayCalendar"`
+	OpenHolidays     []string           `json:"openHolidays"`
+	HoursPolicy      string             `json:"hoursPolicy"`
+	SkipClosedDays   bool               `json:"skipClosedDays"`
+	EmailVerified    bool               `json:"emailVerified"`
+	Status           string             `json:"status"`
+	TwoFactorEnabled bool               `json:"twoFactorEnabled"`
+	CreatedAt        time.Time          `json:"createdAt"`
+	UpdatedAt        time.Time          `json:"updatedAt"`
 }
 
 // ToResponse converts a Partner to a PartnerResponse
 func (p *Partner) ToResponse() PartnerResponse {
 	return PartnerResponse{
-		ID:            p.ID,
-		CompanyName:   p.CompanyName,
-		Email:         p.Email,
-		PhoneNumber:   p.PhoneNumber,
-		Address:       p.Address,
-		City:          p.City,
-		BusinessType:  p.BusinessType,
-		TaxNumber:     p.TaxNumber,
-		ContactPerson: p.ContactPerson,
-		CreatedAt:     p.CreatedAt,
-		UpdatedAt:     p.UpdatedAt,
+		ID:               p.ID,
+		CompanyName:      p.CompanyName,
+		Email:            p.Email,
+		PhoneNumber:      p.PhoneNumber,
+		Address:          p.Address,
+		City:             p.City,
+		BusinessType:     p.BusinessType,
+		TaxNumber:        p.TaxNumber,
+		ContactPerson:    p.ContactPerson,
+		TimeZone:         p.Location().String(),
+		OverlapPolicy:    p.ReservationOverlapPolicy(),
+		BusinessHours:    nonNil(p.BusinessHours),
+		Closures:         nonNil(p.Closures),
+		HolidayCalendar:  p.PublicHolidayCalendar(),
+		OpenHolidays:     nonNil(p.OpenHolidays),
+		HoursPolicy:      p.BusinessHoursPolicy(),
+		SkipClosedDays:   p.SkipClosedDays,
+		EmailVerified:    p.EmailVerified,
+		Status:           p.AccountStatus(),
+		TwoFactorEnabled: p.TwoFactor.Enabled,
+		CreatedAt:        p.CreatedAt,
+		UpdatedAt:        p.UpdatedAt,
+	}
+}
+
+// AccountStatus returns the partner's status. Partners registered before
+// email verification existed have no status and are treated as active.
+func (p *Partner) AccountStatus() string {
+	if p.Status == "" {
+		return PartnerStatusActive
+	}
+	return p.Status
+}
+
+// Location returns the partner's time zone, falling back to DefaultTimeZone
+// for partners registered before time zones existed
+func (p *Partner) Location() *time.Location {
+	if p.TimeZone != "" {
+		if loc, err := time.LoadLocation(p.TimeZone); err == nil {
+			return loc
+		}
+	}
+	loc, err := time.LoadLocation(DefaultTimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ReservationOverlapPolicy returns the partner's overlap policy. Partners that
+// have not chosen one are warned about overlaps, matching the old behaviour of
+// accepting them.
+func (p *Partner) ReservationOverlapPolicy() string {
+	if p.OverlapPolicy == "" {
+		return OverlapPolicyWarn
 	}
+	return p.OverlapPolicy
+}
+
+// PublicHolidayCalendar returns the holiday calendar the partner follows.
+// Partners that have not chosen one follow the Turkish calendar.
+func (p *Partner) PublicHolidayCalendar() string {
+	if p.HolidayCalendar == "" {
+		return HolidayCalendarTurkey
+	}
+	return p.HolidayCalendar
+}
+
+// BusinessHoursPolicy returns the partner's business hours policy. Partners that
+// have not chosen one are warned, so existing integrations keep working.
+func (p *Partner) BusinessHoursPolicy() string {
+	if p.HoursPolicy == "" {
+		return HoursPolicyWarn
+	}
+	return p.HoursPolicy
+}
+
+// nonNil returns an empty slice instead of nil so that JSON responses contain []
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
 }
 
 // ToPartner converts a RegisterRequest to a Partner
 func (r *RegisterRequest) ToPartner() Partner {
 	now := time.Now()
+	if r.TimeZone == "" {
+		r.TimeZone = DefaultTimeZone
+	}
 	return Partner{
 		CompanyName:   r.CompanyName,
 		Email:         r.Email,
@@ -86,7 +303,10 @@ func (r *RegisterRequest) ToPartner() Partner {
 		BusinessType:  r.BusinessType,
 		TaxNumber:     r.TaxNumber,
 		ContactPerson: r.ContactPerson,
+		TimeZone:      r.TimeZone,
+		EmailVerified: false,
+		Status:        PartnerStatusPending,
 		CreatedAt:     now,
 		UpdatedAt:     now,
 	}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PasswordReset tek kullanımlık bir şifre sıfırlama isteğidir. Token'ın kendisi
+// değil yalnızca özeti saklanır.
+type PasswordReset struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	PartnerID primitive.ObjectID `bson:"partner_id"`
+	TokenHash string             `bson:"token_hash"`
+	IP        string             `bson:"ip"`
+	CreatedAt time.Time          `bson:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty"`
+	// ClaimedAt şifre güncellenirken token'ın başka bir istekle kullanılmasını engeller;
+	// güncelleme başarısız olursa kaldırılır
+	ClaimedAt *time.Time `bson:"claimed_at,omitempty"`
+}
+
+// ForgotPasswordRequest şifre sıfırlama e-postası talebidir
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest yeni şifreyi belirleme isteğidir
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
\ No newline at end of file
@@ -6,27 +6,101 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// RecurrencePattern tekrarlama desenini tanımlar
+// RecurrencePattern tekrarlama desenini tanımlar. Alanlar RFC 5545 RRULE
+// karşılıklarıyla birebir eşleşir; RRule bu desenin metin halidir.
 type RecurrencePattern struct {
-	Enabled      bool     `json:"enabled" bson:"enabled"`
-	Type         string   `json:"type" bson:"type"`           // weekly, monthly, yearly
-	DaysOfWeek   []int    `json:"daysOfWeek" bson:"daysOfWeek"` // 0-6 (Pazar-Cumartesi)
-	EndType      string   `json:"endType" bson:"endType"`     // never, after, on
-	EndAfter     int      `json:"endAfter" bson:"endAfter"`   // tekrar sayısı
-	EndDate      *time.Time `json:"endDate" bson:"endDate"`     // bitiş tarihi
+	Enabled      bool         `json:"enabled" bson:"enabled"`
+	Type         string       `json:"type" bson:"type"`                                     // daily, weekly, monthly, yearly
+	Interval     int          `json:"interval,omitempty" bson:"interval,omitempty"`         // kaç dönemde bir (0 veya 1: her dönem)
+	DaysOfWeek   []int        `json:"daysOfWeek" bson:"daysOfWeek"`                         // 0-6 (Pazar-Cumartesi), daily ve weekly için
+	ByDay        []WeekdayNum `json:"byDay,omitempty" bson:"byDay,omitempty"`               // monthly ve yearly için haftanın günleri
+	DaysOfMonth  []int        `json:"daysOfMonth,omitempty" bson:"daysOfMonth,omitempty"`   // 1-31, negatifse ay sonundan (-1: son gün)
+	Months       []int        `json:"months,omitempty" bson:"months,omitempty"`             // 1-12, yearly için
+	SetPositions []int        `json:"setPositions,omitempty" bson:"setPositions,omitempty"` // dönemdeki adaylardan seçilenler (-1: sonuncu)
+	EndType      string       `json:"endType" bson:"endType"`                               // never, after, on (gün), until (an)
+	EndAfter     int          `json:"endAfter" bson:"endAfter"`                             // tekrar sayısı
+	EndDate      *time.Time   `json:"endDate" bson:"endDate"`                               // bitiş tarihi veya anı
+	RRule        string       `json:"rrule,omitempty" bson:"rrule,omitempty"`               // ör. FREQ=MONTHLY;BYDAY=-1FR
+	LastEnd      *time.Time   `json:"-" bson:"lastEnd,omitempty"`                           // son gerçekleşmenin bitişi; sonsuz serilerde boş
+}
+
+// WeekdayNum ayın veya yılın belirli bir haftanın gününü seçer (RRULE BYDAY=2MO, -1FR)
+type WeekdayNum struct {
+	Day int `json:"day" bson:"day"` // 0-6 (Pazar-Cumartesi)
+	N   int `json:"n" bson:"n"`     // 1-5 veya -1..-5 (sondan); 0 ise dönemdeki her biri
 }
 
 // Reservation modeli
 type Reservation struct {
-	ID              primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	PartnerID       primitive.ObjectID `json:"partnerId" bson:"partnerId"`
-	Name            string            `json:"name" bson:"name"`
-	StartDate       time.Time         `json:"startDate" bson:"startDate"`
-	EndDate         time.Time         `json:"endDate" bson:"endDate"`
-	IsAllDay        bool              `json:"isAllDay" bson:"isAllDay"`
-	IsMultiDay      bool              `json:"isMultiDay" bson:"isMultiDay"`
-	Capacity        int               `json:"capacity" bson:"capacity"`
-	Recurrence      RecurrencePattern `json:"recurrence" bson:"recurrence"`
-	CreatedAt       time.Time         `json:"createdAt" bson:"createdAt"`
-	UpdatedAt       time.Time         `json:"updatedAt" bson:"updatedAt"`
-} 
\ No newline at end of file
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	PartnerID      primitive.ObjectID  `json:"partnerId" bson:"partnerId"`
+	Name           string              `json:"name" bson:"name"`
+	StartDate      time.Time           `json:"startDate" bson:"startDate"`
+	EndDate        time.Time           `json:"endDate" bson:"endDate"`
+	IsAllDay       bool                `json:"isAllDay" bson:"isAllDay"`
+	StartDay       string              `json:"startDay,omitempty" bson:"startDay,omitempty"` // tüm gün rezervasyonlarda yerel ilk gün (YYYY-MM-DD)
+	EndDay         string              `json:"endDay,omitempty" bson:"endDay,omitempty"`     // tüm gün rezervasyonlarda yerel son gün, dahil
+	TimeZone       string              `json:"timeZone,omitempty" bson:"timeZone,omitempty"` // IANA saat dilimi; boşsa partner'ınki geçerlidir
+	IsMultiDay     bool                `json:"isMultiDay" bson:"isMultiDay"`
+	Capacity       int                 `json:"capacity" bson:"capacity"`
+	Recurrence     RecurrencePattern   `json:"recurrence" bson:"recurrence"`
+	ExceptionDates []time.Time         `json:"exceptionDates,omitempty" bson:"exceptionDates,omitempty"` // iptal edilen veya ayrı kayıtla değiştirilen gerçekleşmeler (EXDATE)
+	SeriesID       *primitive.ObjectID `json:"seriesId,omitempty" bson:"seriesId,omitempty"`             // tek bir gerçekleşmeyi değiştiren kaydın ait olduğu seri
+	RecurrenceID   *time.Time          `json:"recurrenceId,omitempty" bson:"recurrenceId,omitempty"`     // değiştirilen gerçekleşmenin serideki asıl başlangıcı
+	ImportUID      string              `json:"importUid,omitempty" bson:"importUid,omitempty"`           // .ics dosyasından içe aktarılan veya CalDAV ile kaydedilen etkinliğin UID'si
+	ResourceName   string              `json:"-" bson:"resourceName,omitempty"`                          // CalDAV ile kaydedilen etkinliğin takvim uygulamasının seçtiği kaynak adı
+	ChangeSeq      int64               `json:"-" bson:"changeSeq,omitempty"`                             // kaydı son değiştiren yazmanın partner'daki sıra numarası
+	CreatedAt      time.Time           `json:"createdAt" bson:"createdAt"`
+	UpdatedAt      time.Time           `json:"updatedAt" bson:"updatedAt"`
+}
+
+// Location rezervasyonun saat dilimini, yoksa verilen partner saat dilimini döndürür
+func (r *Reservation) Location(fallback *time.Location) *time.Location {
+	if r.TimeZone != "" {
+		if loc, err := time.LoadLocation(r.TimeZone); err == nil {
+			return loc
+		}
+	}
+	return fallback
+}
+
+// ReservationInstance tekrar eden bir rezervasyonun tek bir gerçekleşmesidir.
+// StartDate/EndDate gerçekleşmenin zamanını, SeriesStartDate/SeriesEndDate
+// serinin ilk gerçekleşmesini taşır. BookedSeats/RemainingSeats bu gerçekleşmenin
+// onaylı müşteri rezervasyonlarıyla dolan ve kalan yerleridir.
+type ReservationInstance struct {
+	Reservation
+	StartDate       time.Time `json:"startDate"`
+	EndDate         time.Time `json:"endDate"`
+	SeriesStartDate time.Time `json:"seriesStartDate"`
+	SeriesEndDate   time.Time `json:"seriesEndDate"`
+	OccurrenceIndex int       `json:"occurrenceIndex"`
+	BookedSeats     int       `json:"bookedSeats"`
+	RemainingSeats  int       `json:"remainingSeats"`
+}
+
+// ReservationTombstone silinen bir rezervasyonun (seri, gerçekleşme veya tek seferlik)
+// kaydıdır. Artımlı senkronizasyonda istemciler silinenleri bu kayıtlardan öğrenir.
+type ReservationTombstone struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"` // silinen rezervasyonun ID'si
+	PartnerID primitive.ObjectID `json:"-" bson:"partnerId"`
+	DeletedAt time.Time          `json:"deletedAt" bson:"deletedAt"`
+	Seq       int64              `json:"-" bson:"seq,omitempty"` // silen yazmanın partner'daki sıra numarası
+}
+
+// ReservationChanges artımlı senkronizasyon yanıtıdır. Full true ise belirteç gönderilmemiştir
+// ve Reservations tüm rezervasyonlardır.
+type ReservationChanges struct {
+	Reservations []ReservationInstance  `json:"reservations"`
+	Deleted      []ReservationTombstone `json:"deleted"`
+	SyncToken    string                 `json:"syncToken"`
+	Full         bool                   `json:"full"`
+}
+
+// ReservationPage rezervasyon listesinin bir sayfasıdır. NextCursor sonraki sayfayı ister;
+// son sayfada boştur.
+type ReservationPage struct {
+	Reservations []ReservationInstance `json:"reservations"`
+	NextCursor   string                `json:"nextCursor,omitempty"`
+	HasMore      bool                  `json:"hasMore"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Yer ayırma (hold) durumları
+const (
+	SeatHoldStatusHeld      = "held"
+	SeatHoldStatusConfirmed = "confirmed"
+	SeatHoldStatusReleased  = "released"
+	SeatHoldStatusExpired   = "expired"
+)
+
+// SeatHold ödeme adımı sürerken bir gerçekleşmedeki yerleri kısa süreliğine ayırır.
+// Süresi dolan ayırmaların yerleri otomatik olarak serbest bırakılır.
+type SeatHold struct {
+	ID              primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	PartnerID       primitive.ObjectID  `json:"partnerId" bson:"partnerId"`
+	ReservationID   primitive.ObjectID  `json:"reservationId" bson:"reservationId"`
+	OccurrenceStart time.Time           `json:"occurrenceStart" bson:"occurrenceStart"`
+	Seats           int                 `json:"seats" bson:"seats"`
+	Status          string              `json:"status" bson:"status"`
+	BookingID       *primitive.ObjectID `json:"bookingId,omitempty" bson:"bookingId,omitempty"`
+	ExpiresAt       time.Time           `json:"expiresAt" bson:"expiresAt"`
+	CreatedAt       time.Time           `json:"createdAt" bson:"createdAt"`
+	UpdatedAt       time.Time           `json:"updatedAt" bson:"updatedAt"`
+}
+
+// CreateSeatHoldRequest yeni bir yer ayırmanın verileridir. TTLSeconds verilmezse
+// varsayılan süre kullanılır.
+type CreateSeatHoldRequest struct {
+	OccurrenceStart *time.Time `json:"occurrenceStart"`
+	Seats           int        `json:"seats" validate:"required,min=1"`
+	TTLSeconds      int        `json:"ttlSeconds" validate:"omitempty,min=60,max=1800"`
+}
+
+// ConfirmSeatHoldRequest ayırmayı onaylı müşteri rezervasyonuna çevirirken gönderilen müşteri bilgileridir
+type ConfirmSeatHoldRequest struct {
+	CustomerName  string `json:"customerName" validate:"required"`
+	CustomerEmail string `json:"customerEmail" validate:"required_without=CustomerPhone,omitempty,email"`
+	CustomerPhone string `json:"customerPhone" validate:"required_without=CustomerEmail,omitempty,phone"`
+	Notes         string `json:"notes" validate:"max=500"`
+}
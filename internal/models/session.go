@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session bir cihazdaki oturumu ve o oturumun refresh token ailesini temsil eder
+type Session struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PartnerID           primitive.ObjectID `bson:"partner_id" json:"partnerId"`
+	UserID              primitive.ObjectID `bson:"user_id" json:"userId"`
+	RefreshTokenHash    string             `bson:"refresh_token_hash" json:"-"`
+	PreviousTokenHashes []string           `bson:"previous_token_hashes" json:"-"`
+	UserAgent           string             `bson:"user_agent" json:"userAgent"`
+	IP                  string             `bson:"ip" json:"ip"`
+	CreatedAt           time.Time          `bson:"created_at" json:"createdAt"`
+	LastUsedAt          time.Time          `bson:"last_used_at" json:"lastUsedAt"`
+	ExpiresAt           time.Time          `bson:"expires_at" json:"expiresAt"`
+	RevokedAt           *time.Time         `bson:"revoked_at,omitempty" json:"revokedAt,omitempty"`
+}
\ No newline at end of file
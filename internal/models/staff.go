@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Staff account states
+const (
+	StaffStatusActive   = "active"
+	StaffStatusDisabled = "disabled"
+)
+
+// StaffUser is an employee account that belongs to a partner
+type StaffUser struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PartnerID primitive.ObjectID `bson:"partner_id" json:"partnerId"`
+	Name      string             `bson:"name" json:"name"`
+	Email     string             `bson:"email" json:"email"`
+	Password  string             `bson:"password" json:"-"`
+	Role      string             `bson:"role" json:"role"`
+	Status    string             `bson:"status" json:"status"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// CreateStaffRequest represents the data for a new staff account
+type CreateStaffRequest struct {
+	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=6"`
+	Role     string `json:"role" validate:"required,oneof=manager front_desk"`
+}
+
+// UpdateStaffRequest represents a partial update of a staff account
+type UpdateStaffRequest struct {
+	Name     *string `json:"name" validate:"omitnil,min=1"`
+	Role     *string `json:"role" validate:"omitnil,oneof=manager front_desk"`
+	Status   *string `json:"status" validate:"omitnil,oneof=active disabled"`
+	Password *string `json:"password" validate:"omitnil,min=6"`
+}
\ No newline at end of file
@@ -0,0 +1,504 @@
+package recurrence
+
+import (
+	"sort"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+)
+
+// MaxOccurrences tek bir seri için bir pencerede üretilecek en fazla tekrar sayısıdır;
+// sonu olmayan serilerin çok geniş pencerelerde sınırsız büyümesini engeller
+const MaxOccurrences = 1000
+
+// Tekrar tipleri
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+	FrequencyYearly  = "yearly"
+)
+
+// dayLayout tüm gün rezervasyonların yerel gün biçimidir
+const dayLayout = "2006-01-02"
+
+// Bitiş koşulları. EndOn bitiş tarihini gün olarak yorumlar (o gün başlayanlar dahil);
+// EndUntil RRULE'deki UNTIL gibi tam bir andır (o ana kadar başlayanlar dahil).
+const (
+	EndNever = "never"
+	EndAfter = "after"
+	EndOn    = "on"
+	EndUntil = "until"
+)
+
+// Occurrence bir rezervasyonun somut bir gerçekleşmesidir
+type Occurrence struct {
+	Start time.Time
+	End   time.Time
+	Index int // serideki sırası, ilk gerçekleşme 0
+}
+
+// rule bir tekrar deseninin genişletmeye hazır halidir
+type rule struct {
+	freq      string
+	interval  int
+	weekdays  []time.Weekday // daily ve weekly
+	byDay     []models.WeekdayNum
+	monthDays []int
+	months    []time.Month
+	setPos    []int
+	count     int        // 0 ise sınırsız
+	until     *time.Time // dahil
+}
+
+// Expand rezervasyonun [from, to) penceresiyle kesişen gerçekleşmelerini sırayla döndürür.
+// Tekrar etmeyen rezervasyonlar pencereyle kesişiyorsa tek bir gerçekleşme olarak döner.
+// Seri, rezervasyonun saat diliminde (yoksa loc'ta) yerel saatle açılır; böylece yaz
+// saati geçişlerinde gerçekleşmeler aynı yerel saatte kalır. Tüm gün rezervasyonlar
+// saklanan günlerinden açılır; saat dilimi sonradan değişse de aynı günlerde kalırlar.
+func Expand(r models.Reservation, loc *time.Location, from, to time.Time) []Occurrence {
+	return ExpandSkipping(r, loc, from, to, nil)
+}
+
+// SkipFunc genişletmede atlanacak gerçekleşmeleri seçer (ör. partner'ın kapalı olduğu günler)
+type SkipFunc func(o Occurrence) bool
+
+// ExpandSkipping Expand gibidir, ancak tekrar eden serilerde skip'in seçtiği gerçekleşmeler
+// dönmez. Atlanan gerçekleşmeler iptal edilenler gibi serideki sırayı ve adedi korur.
+func ExpandSkipping(r models.Reservation, loc *time.Location, from, to time.Time, skip SkipFunc) []Occurrence {
+	var occurrences []Occurrence
+	Each(r, loc, from, to, skip, func(o Occurrence) bool {
+		occurrences = append(occurrences, o)
+		return len(occurrences) < MaxOccurrences
+	})
+	return occurrences
+}
+
+// Each ExpandSkipping'in döndüreceği gerçekleşmeleri sırayla fn'e verir; fn false dönerse
+// genişletme durur. MaxOccurrences sınırı uygulanmaz, sınırı çağıran belirler.
+func Each(r models.Reservation, loc *time.Location, from, to time.Time, skip SkipFunc, fn func(o Occurrence) bool) {
+	zone := r.Location(loc)
+	start := seriesStart(r, zone)
+	end := endFunc(r, start)
+
+	rl, ok := ruleFromPattern(r.Recurrence, start)
+	if !ok {
+		if overlaps(start, end(start), from, to) {
+			fn(Occurrence{Start: start, End: end(start)})
+		}
+		return
+	}
+
+	rl.each(start, to, func(index int, t time.Time) bool {
+		if overlaps(t, end(t), from, to) && !isException(r, zone, t) {
+			o := Occurrence{Start: t, End: end(t), Index: index}
+			if skip == nil || !skip(o) {
+				return fn(o)
+			}
+		}
+		return true
+	})
+}
+
+// maxLastEndWalk LastEnd'in son gerçekleşmeyi ararken yürüyeceği en fazla gerçekleşme sayısıdır
+const maxLastEndWalk = 100000
+
+// LastEnd serinin son gerçekleşmesinin bitişini döndürür; tekrar etmeyen rezervasyonlarda
+// bitişin kendisidir. Seri sonsuza kadar sürüyorsa veya sonu maxLastEndWalk gerçekleşmeden
+// uzaksa nil döner. İptal edilen ve atlanan gerçekleşmeler hesaba katılmaz; dönen zaman
+// serinin gerçek sonundan önce olamaz.
+func LastEnd(r models.Reservation, loc *time.Location) *time.Time {
+	zone := r.Location(loc)
+	start := seriesStart(r, zone)
+	end := endFunc(r, start)
+
+	rl, ok := ruleFromPattern(r.Recurrence, start)
+	if !ok {
+		last := end(start)
+		return &last
+	}
+	if rl.count == 0 && rl.until == nil {
+		return nil
+	}
+
+	// Hiç gerçekleşmesi olmayan desenlerde (ör. 30 Şubat) yürüyüş bir yerde durmalıdır
+	horizon := time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+	last, walked := start, 0
+	rl.each(start, horizon, func(_ int, t time.Time) bool {
+		last = t
+		walked++
+		return walked <= maxLastEndWalk
+	})
+	if walked > maxLastEndWalk {
+		return nil
+	}
+	lastEnd := end(last)
+	return &lastEnd
+}
+
+// IndexOf at zamanında başlayan gerçekleşmenin serideki sırasını döndürür. İptal
+// edilmiş veya taşınmış gerçekleşmeler de sırayı korur; serinin gerçekleşmesi
+// olmayan zamanlar için false döner.
+func IndexOf(r models.Reservation, loc *time.Location, at time.Time) (int, bool) {
+	index, _, ok := find(r, r.Location(loc), at)
+	return index, ok
+}
+
+// At at zamanında başlayan gerçekleşmeyi döndürür; serinin o zamanda gerçekleşmesi yoksa
+// false döner. İptal edilmiş gerçekleşmeler için de döner, ayrıca IsException ile bakılmalıdır.
+func At(r models.Reservation, loc *time.Location, at time.Time) (Occurrence, bool) {
+	zone := r.Location(loc)
+	index, start, ok := find(r, zone, at)
+	if !ok {
+		return Occurrence{}, false
+	}
+	return Occurrence{Start: start, End: endFunc(r, seriesStart(r, zone))(start), Index: index}, true
+}
+
+// find at zamanında başlayan gerçekleşmenin sırasını ve yerel başlangıcını döndürür
+func find(r models.Reservation, zone *time.Location, at time.Time) (int, time.Time, bool) {
+	start := seriesStart(r, zone)
+	rl, ok := ruleFromPattern(r.Recurrence, start)
+	if !ok {
+		return 0, start, sameStart(r, zone, start, at)
+	}
+
+	// Tüm gün gerçekleşmeler gün olarak eşleştiği için at'tan biraz sonra başlayabilir
+	to := at.Add(time.Nanosecond)
+	if r.IsAllDay {
+		to = at.Add(dayRounding + time.Nanosecond)
+	}
+
+	found, foundAt := -1, time.Time{}
+	rl.each(start, to, func(index int, t time.Time) bool {
+		if sameStart(r, zone, t, at) {
+			found, foundAt = index, t
+			return false
+		}
+		return true
+	})
+	return found, foundAt, found >= 0
+}
+
+// seriesStart serinin ilk gerçekleşmesinin yerel başlangıcıdır. Tüm gün rezervasyonlarda
+// saklanan an eski bir saat diliminin gece yarısı olabileceği için yerel gün kullanılır.
+func seriesStart(r models.Reservation, zone *time.Location) time.Time {
+	if r.IsAllDay && r.StartDay != "" {
+		if d, err := time.ParseInLocation(dayLayout, r.StartDay, zone); err == nil {
+			return d
+		}
+	}
+	return r.StartDate.In(zone)
+}
+
+// dayRounding saklanan tüm gün anlarının en yakın yerel gece yarısına yuvarlanması için
+// eklenen süredir
+const dayRounding = 12 * time.Hour
+
+// sameStart a ve b'nin aynı gerçekleşmenin başlangıcı olup olmadığını döndürür. Tüm gün
+// gerçekleşmeler yerel gün olarak karşılaştırılır; iptal ve değişiklik anları eski bir saat
+// diliminin gece yarısı olarak saklanmış olabilir ve en yakın yerel gece yarısına yuvarlanır.
+func sameStart(r models.Reservation, zone *time.Location, a, b time.Time) bool {
+	if !r.IsAllDay {
+		return a.Equal(b)
+	}
+	return a.In(zone).Add(dayRounding).Format(dayLayout) == b.In(zone).Add(dayRounding).Format(dayLayout)
+}
+
+// endFunc bir gerçekleşmenin başlangıcından bitişini hesaplayan fonksiyonu döndürür.
+// Tüm gün rezervasyonlar gün sayısını korur (yaz saati geçişinde 23 veya 25 saat
+// sürebilir); diğerleri süreyi korur.
+func endFunc(r models.Reservation, start time.Time) func(time.Time) time.Time {
+	if r.IsAllDay {
+		days := allDayLength(r, start)
+		return func(t time.Time) time.Time {
+			y, m, d := t.Date()
+			return time.Date(y, m, d+days, 0, 0, 0, 0, t.Location())
+		}
+	}
+
+	duration := r.EndDate.Sub(r.StartDate)
+	if duration < 0 {
+		duration = 0
+	}
+	return func(t time.Time) time.Time { return t.Add(duration) }
+}
+
+// allDayLength tüm gün rezervasyonun kaç gün sürdüğünü saklanan yerel günlerden, bunlar
+// yoksa eski kayıtlarda olduğu gibi bitiş anından hesaplar
+func allDayLength(r models.Reservation, start time.Time) int {
+	first, err1 := time.Parse(dayLayout, r.StartDay)
+	last, err2 := time.Parse(dayLayout, r.EndDay)
+	if err1 == nil && err2 == nil {
+		return civilDays(first, last) + 1
+	}
+	// Bitiş, son günü izleyen gece yarısıdır; eski kayıtlarda aynı gün olabilir
+	return civilDays(start, r.EndDate.Add(-time.Nanosecond).In(start.Location())) + 1
+}
+
+// civilDays iki zamanın yerel tarihleri arasındaki gün farkını döndürür
+func civilDays(from, to time.Time) int {
+	fy, fm, fd := from.Date()
+	ty, tm, td := to.Date()
+	a := time.Date(fy, fm, fd, 0, 0, 0, 0, time.UTC)
+	b := time.Date(ty, tm, td, 0, 0, 0, 0, time.UTC)
+	days := int(b.Sub(a).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// IsException gerçekleşmenin iptal edilmiş veya ayrı bir kayıtla değiştirilmiş olup olmadığını döndürür
+func IsException(r models.Reservation, loc *time.Location, start time.Time) bool {
+	return isException(r, r.Location(loc), start)
+}
+
+func isException(r models.Reservation, zone *time.Location, start time.Time) bool {
+	for _, ex := range r.ExceptionDates {
+		if sameStart(r, zone, ex, start) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleFromPattern kayıtlı deseni kurala çevirir; tekrar yoksa veya desen
+// geçersizse false döner
+func ruleFromPattern(p models.RecurrencePattern, start time.Time) (rule, bool) {
+	if !p.Enabled || Validate(p) != nil {
+		return rule{}, false
+	}
+
+	rl := rule{
+		freq:      p.Type,
+		interval:  p.Interval,
+		monthDays: p.DaysOfMonth,
+		setPos:    p.SetPositions,
+	}
+	if rl.interval < 1 {
+		rl.interval = 1
+	}
+
+	switch p.Type {
+	case FrequencyDaily, FrequencyWeekly:
+		seen := map[int]bool{}
+		for _, d := range p.DaysOfWeek {
+			if !seen[d] {
+				seen[d] = true
+				rl.weekdays = append(rl.weekdays, time.Weekday(d))
+			}
+		}
+		if p.Type == FrequencyWeekly && len(rl.weekdays) == 0 {
+			rl.weekdays = []time.Weekday{start.Weekday()}
+		}
+	case FrequencyMonthly, FrequencyYearly:
+		// Eski kayıtlarda aylık/yıllık desenlerde de DaysOfWeek dolu gelebiliyordu;
+		// bu tiplerde günler yalnızca ByDay'den okunur
+		rl.byDay = p.ByDay
+		for _, m := range p.Months {
+			rl.months = append(rl.months, time.Month(m))
+		}
+		if len(rl.months) == 0 {
+			rl.months = []time.Month{start.Month()}
+		}
+	}
+
+	switch p.EndType {
+	case EndAfter:
+		rl.count = p.EndAfter
+	case EndOn:
+		// Bitiş tarihi gün olarak yorumlanır; o gün başlayan gerçekleşmeler dahildir
+		d := p.EndDate.In(start.Location())
+		until := time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, start.Location()).Add(-time.Nanosecond)
+		rl.until = &until
+	case EndUntil:
+		until := *p.EndDate
+		rl.until = &until
+	}
+
+	return rl, true
+}
+
+// each seri başlangıcından itibaren gerçekleşmeleri sırayla fn'e verir. Seri başlangıcı
+// RFC 5545'teki DTSTART gibi her zaman ilk gerçekleşmedir. fn false dönerse, bitiş
+// koşulu sağlanırsa veya to'ya ulaşılırsa durur.
+func (rl rule) each(start, to time.Time, fn func(index int, start time.Time) bool) {
+	index := 0
+	emit := func(t time.Time) bool {
+		if rl.count > 0 && index >= rl.count {
+			return false
+		}
+		if rl.until != nil && t.After(*rl.until) {
+			return false
+		}
+		if !t.Before(to) {
+			return false
+		}
+		if !fn(index, t) {
+			return false
+		}
+		index++
+		return true
+	}
+
+	if !emit(start) {
+		return
+	}
+
+	for period := 0; ; period++ {
+		periodStart, candidates := rl.period(start, period)
+		if !periodStart.Before(to) || (rl.until != nil && periodStart.After(*rl.until)) {
+			return
+		}
+		for _, t := range candidates {
+			if !t.After(start) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// period seri başlangıcından itibaren n'inci dönemin başını ve o dönemdeki aday
+// başlangıç zamanlarını sıralı olarak döndürür
+func (rl rule) period(start time.Time, n int) (time.Time, []time.Time) {
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	ns, loc := start.Nanosecond(), start.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, ns, loc)
+	}
+
+	step := n * rl.interval
+	var periodStart time.Time
+	var candidates []time.Time
+	switch rl.freq {
+	case FrequencyDaily:
+		periodStart = time.Date(y, m, d+step, 0, 0, 0, 0, loc)
+		if t := at(y, m, d+step); len(rl.weekdays) == 0 || containsWeekday(rl.weekdays, t.Weekday()) {
+			candidates = append(candidates, t)
+		}
+	case FrequencyWeekly:
+		// Haftalar pazartesi başlar (RFC 5545 varsayılanı WKST=MO)
+		monday := d - (int(start.Weekday())+6)%7 + step*7
+		periodStart = time.Date(y, m, monday, 0, 0, 0, 0, loc)
+		for _, wd := range rl.weekdays {
+			candidates = append(candidates, at(y, m, monday+(int(wd)+6)%7))
+		}
+	case FrequencyMonthly:
+		periodStart = time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, loc)
+		py, pm, _ := periodStart.Date()
+		for _, day := range rl.daysOf(py, pm, d) {
+			candidates = append(candidates, at(py, pm, day))
+		}
+	default: // FrequencyYearly
+		periodStart = time.Date(y+step, time.January, 1, 0, 0, 0, 0, loc)
+		for _, month := range rl.months {
+			for _, day := range rl.daysOf(y+step, month, d) {
+				candidates = append(candidates, at(y+step, month, day))
+			}
+		}
+	}
+
+	sortTimes(candidates)
+	return periodStart, rl.selectPositions(candidates)
+}
+
+// daysOf ayın kurala uyan günlerini döndürür. Gün kuralı yoksa serinin başladığı
+// gün kullanılır; ayda o gün yoksa (ör. 31 Nisan, artık olmayan yılda 29 Şubat) ay atlanır.
+func (rl rule) daysOf(y int, m time.Month, startDay int) []int {
+	last := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if len(rl.monthDays) == 0 && len(rl.byDay) == 0 {
+		if startDay > last {
+			return nil
+		}
+		return []int{startDay}
+	}
+
+	var days []int
+	for day := 1; day <= last; day++ {
+		if len(rl.monthDays) > 0 && !matchesMonthDay(rl.monthDays, day, last) {
+			continue
+		}
+		if len(rl.byDay) > 0 && !matchesWeekdayNum(rl.byDay, time.Date(y, m, day, 0, 0, 0, 0, time.UTC).Weekday(), day, last) {
+			continue
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+// selectPositions BYSETPOS kuralını uygular; kural yoksa adayları olduğu gibi döndürür
+func (rl rule) selectPositions(candidates []time.Time) []time.Time {
+	if len(rl.setPos) == 0 || len(candidates) == 0 {
+		return candidates
+	}
+
+	var selected []time.Time
+	for _, pos := range rl.setPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(candidates) + pos
+		}
+		if i >= 0 && i < len(candidates) && !containsTime(selected, candidates[i]) {
+			selected = append(selected, candidates[i])
+		}
+	}
+	sortTimes(selected)
+	return selected
+}
+
+func matchesMonthDay(monthDays []int, day, last int) bool {
+	for _, md := range monthDays {
+		if md == day || (md < 0 && last+md+1 == day) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesWeekdayNum(byDay []models.WeekdayNum, wd time.Weekday, day, last int) bool {
+	for _, b := range byDay {
+		if time.Weekday(b.Day) != wd {
+			continue
+		}
+		if b.N == 0 || b.N == (day-1)/7+1 || b.N == -((last-day)/7+1) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsWeekday(ws []time.Weekday, wd time.Weekday) bool {
+	for _, w := range ws {
+		if w == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTime(ts []time.Time, t time.Time) bool {
+	for _, x := range ts {
+		if x.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps [start, end) aralığının [from, to) penceresiyle kesişip kesişmediğini
+// döndürür; süresi sıfır olan gerçekleşmeler pencere içinde başlıyorsa kesişir
+func overlaps(start, end, from, to time.Time) bool {
+	if !start.Before(to) {
+		return false
+	}
+	return end.After(from) || !start.Before(from)
+}
+
+func sortTimes(ts []time.Time) {
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Before(ts[j]) })
+}
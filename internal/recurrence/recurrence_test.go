@@ -0,0 +1,164 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+)
+
+// series verilen başlangıç, süre ve desenle tekrar eden bir rezervasyon döndürür
+func series(start time.Time, duration time.Duration, p models.RecurrencePattern) models.Reservation {
+	return models.Reservation{
+		StartDate:  start,
+		EndDate:    start.Add(duration),
+		TimeZone:   start.Location().String(),
+		Recurrence: p,
+	}
+}
+
+func mustParse(t *testing.T, rrule string, loc *time.Location) models.RecurrencePattern {
+	t.Helper()
+	p, err := Parse(rrule, loc)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", rrule, err)
+	}
+	return p
+}
+
+func TestExpand(t *testing.T) {
+	istanbul := mustLoad(t, "Europe/Istanbul")
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	oct := func(day int) time.Time { return time.Date(2026, 10, day, 15, 0, 0, 0, istanbul) }
+	endOn := time.Date(2026, 10, 20, 0, 0, 0, 0, istanbul)
+
+	tests := []struct {
+		name        string
+		reservation models.Reservation
+		from, to    time.Time
+		want        []time.Time
+		indexes     []int // boşsa sıralar 0'dan artar
+	}{
+		{
+			name:        "until before the day's occurrence",
+			reservation: series(oct(15), time.Hour, mustParse(t, "FREQ=DAILY;UNTIL=20261020T090000Z", istanbul)),
+			from:        oct(1), to: oct(31),
+			want: []time.Time{oct(15), oct(16), oct(17), oct(18), oct(19)},
+		},
+		{
+			name:        "until at an occurrence is inclusive",
+			reservation: series(oct(15), time.Hour, mustParse(t, "FREQ=DAILY;UNTIL=20261020T120000Z", istanbul)),
+			from:        oct(1), to: oct(31),
+			want: []time.Time{oct(15), oct(16), oct(17), oct(18), oct(19), oct(20)},
+		},
+		{
+			name:        "date-only until covers the day",
+			reservation: series(oct(15), time.Hour, mustParse(t, "FREQ=DAILY;UNTIL=20261020", istanbul)),
+			from:        oct(1), to: oct(31),
+			want: []time.Time{oct(15), oct(16), oct(17), oct(18), oct(19), oct(20)},
+		},
+		{
+			name: "structured end date covers the day",
+			reservation: series(oct(15), time.Hour, models.RecurrencePattern{
+				Enabled: true, Type: FrequencyDaily, EndType: EndOn, EndDate: &endOn,
+			}),
+			from: oct(1), to: oct(31),
+			want: []time.Time{oct(15), oct(16), oct(17), oct(18), oct(19), oct(20)},
+		},
+		{
+			name:        "window clips the series",
+			reservation: series(oct(15), time.Hour, mustParse(t, "FREQ=DAILY", istanbul)),
+			from:        oct(17), to: oct(19),
+			want:    []time.Time{oct(17), oct(18)},
+			indexes: []int{2, 3},
+		},
+		{
+			name: "exceptions keep the index",
+			reservation: func() models.Reservation {
+				r := series(oct(15), time.Hour, mustParse(t, "FREQ=DAILY;COUNT=4", istanbul))
+				r.ExceptionDates = []time.Time{oct(16)}
+				return r
+			}(),
+			from: oct(1), to: oct(31),
+			want:    []time.Time{oct(15), oct(17), oct(18)},
+			indexes: []int{0, 2, 3},
+		},
+		{
+			name: "last weekday of the month",
+			reservation: series(time.Date(2026, 1, 30, 10, 0, 0, 0, istanbul), time.Hour,
+				mustParse(t, "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=3", istanbul)),
+			from: time.Date(2026, 1, 1, 0, 0, 0, 0, istanbul), to: time.Date(2027, 1, 1, 0, 0, 0, 0, istanbul),
+			want: []time.Time{
+				time.Date(2026, 1, 30, 10, 0, 0, 0, istanbul),
+				time.Date(2026, 2, 27, 10, 0, 0, 0, istanbul),
+				time.Date(2026, 3, 31, 10, 0, 0, 0, istanbul),
+			},
+		},
+		{
+			name: "local time is kept across daylight saving",
+			reservation: series(time.Date(2026, 3, 27, 9, 0, 0, 0, berlin), time.Hour,
+				mustParse(t, "FREQ=DAILY;COUNT=4", berlin)),
+			from: time.Date(2026, 3, 1, 0, 0, 0, 0, berlin), to: time.Date(2026, 4, 1, 0, 0, 0, 0, berlin),
+			want: []time.Time{
+				time.Date(2026, 3, 27, 8, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 28, 8, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 29, 7, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 30, 7, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Expand(tt.reservation, time.UTC, tt.from, tt.to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %d", len(got), got, len(tt.want))
+			}
+			for i, o := range got {
+				if !o.Start.Equal(tt.want[i]) {
+					t.Errorf("occurrence %d starts %v, want %v", i, o.Start, tt.want[i])
+				}
+				if d := o.End.Sub(o.Start); d != time.Hour {
+					t.Errorf("occurrence %d lasts %v, want 1h", i, d)
+				}
+				index := i
+				if tt.indexes != nil {
+					index = tt.indexes[i]
+				}
+				if o.Index != index {
+					t.Errorf("occurrence %d has index %d, want %d", i, o.Index, index)
+				}
+			}
+		})
+	}
+}
+
+func TestLastEnd(t *testing.T) {
+	istanbul := mustLoad(t, "Europe/Istanbul")
+	start := time.Date(2026, 10, 15, 15, 0, 0, 0, istanbul)
+
+	tests := []struct {
+		name  string
+		rrule string
+		want  *time.Time
+	}{
+		{"never", "FREQ=DAILY", nil},
+		{"count", "FREQ=WEEKLY;COUNT=3", ptr(time.Date(2026, 10, 29, 16, 0, 0, 0, istanbul))},
+		{"until", "FREQ=DAILY;UNTIL=20261020T090000Z", ptr(time.Date(2026, 10, 19, 16, 0, 0, 0, istanbul))},
+		{"date-only until", "FREQ=DAILY;UNTIL=20261020", ptr(time.Date(2026, 10, 20, 16, 0, 0, 0, istanbul))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LastEnd(series(start, time.Hour, mustParse(t, tt.rrule, istanbul)), time.UTC)
+			if !sameTime(got, tt.want) {
+				t.Errorf("LastEnd = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
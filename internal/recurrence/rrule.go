@@ -0,0 +1,262 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+)
+
+// weekdayCodes RRULE gün kısaltmalarıdır; sırası time.Weekday ile aynıdır
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// RRULE UNTIL değerinin kabul edilen biçimleri
+const (
+	untilLayoutUTC      = "20060102T150405Z"
+	untilLayoutFloating = "20060102T150405"
+	untilLayoutDate     = "20060102"
+)
+
+// Parse bir RFC 5545 RRULE metnini tekrar desenine çevirir. "RRULE:" öneki ve
+// takvim uygulamalarından kopyalanan DTSTART satırları kabul edilir. Yalnızca tarih
+// içeren veya saat dilimi belirtilmeyen UNTIL değerleri loc saat diliminde okunur.
+// Tarih ve saat içeren UNTIL tam bir an olarak (EndUntil), yalnızca tarih içeren UNTIL
+// o günün tamamı olarak (EndOn) saklanır.
+func Parse(rrule string, loc *time.Location) (models.RecurrencePattern, error) {
+	p := models.RecurrencePattern{Enabled: true, EndType: EndNever}
+
+	line := strings.TrimSpace(rrule)
+	for _, l := range strings.Split(line, "\n") {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(strings.ToUpper(l), "RRULE:") {
+			line = l
+			break
+		}
+	}
+	if len(line) >= 6 && strings.EqualFold(line[:6], "RRULE:") {
+		line = line[6:]
+	}
+	if line == "" {
+		return p, errors.New("RRULE boş olamaz")
+	}
+
+	var byDay []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(line, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return p, fmt.Errorf("RRULE parçası geçersiz: %s", part)
+		}
+		if seen[key] {
+			return p, fmt.Errorf("RRULE'de %s birden fazla kez geçiyor", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			p.Type = strings.ToLower(value)
+		case "INTERVAL":
+			p.Interval, err = strconv.Atoi(value)
+		case "COUNT":
+			p.EndType = EndAfter
+			p.EndAfter, err = strconv.Atoi(value)
+		case "UNTIL":
+			var until time.Time
+			var dateOnly bool
+			until, dateOnly, err = parseUntil(value, loc)
+			p.EndType = EndUntil
+			if dateOnly {
+				p.EndType = EndOn
+			}
+			p.EndDate = &until
+		case "BYDAY":
+			byDay = strings.Split(value, ",")
+		case "BYMONTHDAY":
+			p.DaysOfMonth, err = parseInts(value)
+		case "BYMONTH":
+			p.Months, err = parseInts(value)
+		case "BYSETPOS":
+			p.SetPositions, err = parseInts(value)
+		case "WKST":
+			// Haftalar her zaman pazartesi başlar
+			if value != "MO" {
+				return p, errors.New("RRULE'de yalnızca WKST=MO desteklenir")
+			}
+		default:
+			return p, fmt.Errorf("RRULE'deki %s kuralı desteklenmiyor", key)
+		}
+		if err != nil {
+			return p, fmt.Errorf("RRULE'deki %s değeri geçersiz: %s", key, value)
+		}
+	}
+
+	if p.Type == "" {
+		return p, errors.New("RRULE'de FREQ zorunludur")
+	}
+	if seen["COUNT"] && seen["UNTIL"] {
+		return p, errors.New("RRULE'de COUNT ve UNTIL birlikte kullanılamaz")
+	}
+
+	for _, code := range byDay {
+		day, n, err := parseWeekdayNum(code)
+		if err != nil {
+			return p, err
+		}
+		switch p.Type {
+		case FrequencyMonthly, FrequencyYearly:
+			p.ByDay = append(p.ByDay, models.WeekdayNum{Day: day, N: n})
+		default:
+			if n != 0 {
+				return p, fmt.Errorf("BYDAY=%s yalnızca aylık ve yıllık tekrarda kullanılabilir", code)
+			}
+			p.DaysOfWeek = append(p.DaysOfWeek, day)
+		}
+	}
+
+	if err := Validate(p); err != nil {
+		return p, err
+	}
+
+	p.RRule = Format(p, loc)
+	return p, nil
+}
+
+// Format tekrar desenini RRULE metnine çevirir; tekrar kapalıysa boş döner. EndOn'un bitiş
+// günü loc'ta yalnızca tarih olarak, EndUntil'in anı UTC olarak yazılır; böylece Parse aynı
+// deseni geri okur.
+func Format(p models.RecurrencePattern, loc *time.Location) string {
+	if !p.Enabled {
+		return ""
+	}
+
+	parts := []string{"FREQ=" + strings.ToUpper(p.Type)}
+	if p.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(p.Interval))
+	}
+
+	var days []string
+	switch p.Type {
+	case FrequencyMonthly, FrequencyYearly:
+		for _, b := range p.ByDay {
+			if b.Day < 0 || b.Day > 6 {
+				continue
+			}
+			code := weekdayCodes[b.Day]
+			if b.N != 0 {
+				code = strconv.Itoa(b.N) + code
+			}
+			days = append(days, code)
+		}
+	default:
+		sorted := append([]int(nil), p.DaysOfWeek...)
+		// Pazartesiden başlayan sırayla yazılır
+		sort.Slice(sorted, func(i, j int) bool { return (sorted[i]+6)%7 < (sorted[j]+6)%7 })
+		for _, d := range sorted {
+			if d < 0 || d > 6 {
+				continue
+			}
+			days = append(days, weekdayCodes[d])
+		}
+	}
+	if len(days) > 0 {
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if len(p.DaysOfMonth) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(p.DaysOfMonth))
+	}
+	if len(p.Months) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(p.Months))
+	}
+	if len(p.SetPositions) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(p.SetPositions))
+	}
+
+	switch p.EndType {
+	case EndAfter:
+		parts = append(parts, "COUNT="+strconv.Itoa(p.EndAfter))
+	case EndOn:
+		if p.EndDate != nil {
+			parts = append(parts, "UNTIL="+p.EndDate.In(loc).Format(untilLayoutDate))
+		}
+	case EndUntil:
+		if p.EndDate != nil {
+			parts = append(parts, "UNTIL="+p.EndDate.UTC().Format(untilLayoutUTC))
+		}
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// parseWeekdayNum "MO", "2TU" veya "-1FR" biçimindeki BYDAY değerini çözer
+func parseWeekdayNum(code string) (int, int, error) {
+	code = strings.TrimSpace(code)
+	if len(code) < 2 {
+		return 0, 0, fmt.Errorf("BYDAY değeri geçersiz: %s", code)
+	}
+	prefix, suffix := code[:len(code)-2], code[len(code)-2:]
+
+	day := -1
+	for i, c := range weekdayCodes {
+		if c == suffix {
+			day = i
+		}
+	}
+	if day < 0 {
+		return 0, 0, fmt.Errorf("BYDAY değeri geçersiz: %s", code)
+	}
+
+	n := 0
+	if prefix != "" {
+		var err error
+		if n, err = strconv.Atoi(prefix); err != nil || n == 0 {
+			return 0, 0, fmt.Errorf("BYDAY değeri geçersiz: %s", code)
+		}
+	}
+	return day, n, nil
+}
+
+// parseUntil UNTIL değerini çözer; "Z" ile biten değerler UTC, diğerleri loc'taki yerel zamandır.
+// Değer yalnızca tarihse true döner; zaman o günün yerel gece yarısıdır.
+func parseUntil(value string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.Parse(untilLayoutUTC, value); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.ParseInLocation(untilLayoutFloating, value, loc); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.ParseInLocation(untilLayoutDate, value, loc); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, errors.New("geçersiz UNTIL")
+}
+
+func parseInts(value string) ([]int, error) {
+	var out []int
+	for _, s := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
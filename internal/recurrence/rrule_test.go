@@ -0,0 +1,147 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // testler tzdata kurulu olmayan ortamlarda da çalışsın
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
+
+func TestParseFormatRoundTrip(t *testing.T) {
+	istanbul := mustLoad(t, "Europe/Istanbul")
+
+	tests := []struct {
+		rrule   string
+		endType string
+	}{
+		{"FREQ=DAILY", EndNever},
+		{"FREQ=DAILY;UNTIL=20261020T090000Z", EndUntil},
+		{"FREQ=DAILY;UNTIL=20261020", EndOn},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", EndNever},
+		{"FREQ=MONTHLY;BYDAY=-1FR", EndNever},
+		{"FREQ=MONTHLY;BYMONTHDAY=15;COUNT=10", EndAfter},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", EndNever},
+		{"FREQ=YEARLY;BYDAY=-1SU;BYMONTH=3;UNTIL=20301231T210000Z", EndUntil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rrule, func(t *testing.T) {
+			p, err := Parse(tt.rrule, istanbul)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if p.EndType != tt.endType {
+				t.Errorf("EndType = %q, want %q", p.EndType, tt.endType)
+			}
+			if got := Format(p, istanbul); got != tt.rrule {
+				t.Errorf("Format = %q, want %q", got, tt.rrule)
+			}
+			if p.RRule != tt.rrule {
+				t.Errorf("RRule = %q, want %q", p.RRule, tt.rrule)
+			}
+
+			again, err := Parse(p.RRule, istanbul)
+			if err != nil {
+				t.Fatalf("Parse(Format): %v", err)
+			}
+			if !sameTime(p.EndDate, again.EndDate) || again.EndType != p.EndType {
+				t.Errorf("Parse(Format) end = %v %v, want %v %v", again.EndType, again.EndDate, p.EndType, p.EndDate)
+			}
+		})
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestParseUntil(t *testing.T) {
+	istanbul := mustLoad(t, "Europe/Istanbul")
+
+	tests := []struct {
+		name    string
+		rrule   string
+		endType string
+		until   time.Time
+		format  string
+	}{
+		{
+			name:    "utc",
+			rrule:   "FREQ=DAILY;UNTIL=20261020T090000Z",
+			endType: EndUntil,
+			until:   time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC),
+			format:  "FREQ=DAILY;UNTIL=20261020T090000Z",
+		},
+		{
+			name:    "floating",
+			rrule:   "RRULE:FREQ=DAILY;UNTIL=20261020T150000",
+			endType: EndUntil,
+			until:   time.Date(2026, 10, 20, 15, 0, 0, 0, istanbul),
+			format:  "FREQ=DAILY;UNTIL=20261020T120000Z",
+		},
+		{
+			name:    "date",
+			rrule:   "FREQ=WEEKLY;UNTIL=20261020",
+			endType: EndOn,
+			until:   time.Date(2026, 10, 20, 0, 0, 0, 0, istanbul),
+			format:  "FREQ=WEEKLY;UNTIL=20261020",
+		},
+		{
+			name:    "with dtstart line",
+			rrule:   "DTSTART;TZID=Europe/Istanbul:20261015T150000\nRRULE:FREQ=DAILY;UNTIL=20261020T090000Z",
+			endType: EndUntil,
+			until:   time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC),
+			format:  "FREQ=DAILY;UNTIL=20261020T090000Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse(tt.rrule, istanbul)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if p.EndType != tt.endType {
+				t.Errorf("EndType = %q, want %q", p.EndType, tt.endType)
+			}
+			if p.EndDate == nil || !p.EndDate.Equal(tt.until) {
+				t.Errorf("EndDate = %v, want %v", p.EndDate, tt.until)
+			}
+			if p.RRule != tt.format {
+				t.Errorf("RRule = %q, want %q", p.RRule, tt.format)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;COUNT=3;UNTIL=20261020T090000Z",
+		"FREQ=DAILY;COUNT=3;COUNT=4",
+		"FREQ=WEEKLY;BYDAY=2MO",
+		"FREQ=WEEKLY;WKST=SU",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=MONTHLY;BYSETPOS=0",
+		"FREQ=DAILY;BYHOUR=9",
+	}
+
+	for _, rrule := range tests {
+		if _, err := Parse(rrule, time.UTC); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", rrule)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package recurrence
+
+import (
+	"errors"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+)
+
+// maxInterval ve maxSetPosition RRULE'de anlamlı olabilecek en büyük değerlerdir
+const (
+	maxInterval    = 999
+	maxSetPosition = 366
+)
+
+// Validate tekrar desenini kontrol eder; hata mesajları kullanıcıya gösterilecek şekilde Türkçedir
+func Validate(p models.RecurrencePattern) error {
+	if !p.Enabled {
+		return nil
+	}
+
+	switch p.Type {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+	default:
+		return errors.New("Tekrar tipi daily, weekly, monthly veya yearly olmalıdır")
+	}
+
+	if p.Interval < 0 || p.Interval > maxInterval {
+		return errors.New("Tekrar aralığı 1 ile 999 arasında olmalıdır")
+	}
+
+	for _, d := range p.DaysOfWeek {
+		if d < 0 || d > 6 {
+			return errors.New("Haftanın günleri 0 (Pazar) ile 6 (Cumartesi) arasında olmalıdır")
+		}
+	}
+
+	if len(p.ByDay) > 0 && p.Type != FrequencyMonthly && p.Type != FrequencyYearly {
+		return errors.New("Ayın belirli günleri (byDay) yalnızca aylık ve yıllık tekrarda kullanılabilir")
+	}
+	for _, b := range p.ByDay {
+		if b.Day < 0 || b.Day > 6 {
+			return errors.New("Haftanın günleri 0 (Pazar) ile 6 (Cumartesi) arasında olmalıdır")
+		}
+		if b.N < -5 || b.N > 5 {
+			return errors.New("Haftanın kaçıncı günü olduğu -5 ile 5 arasında olmalıdır")
+		}
+	}
+
+	if len(p.DaysOfMonth) > 0 && p.Type != FrequencyMonthly && p.Type != FrequencyYearly {
+		return errors.New("Ayın günleri yalnızca aylık ve yıllık tekrarda kullanılabilir")
+	}
+	for _, d := range p.DaysOfMonth {
+		if d == 0 || d < -31 || d > 31 {
+			return errors.New("Ayın günleri 1-31 veya sondan saymak için -1 ile -31 arasında olmalıdır")
+		}
+	}
+
+	if len(p.Months) > 0 && p.Type != FrequencyYearly {
+		return errors.New("Aylar yalnızca yıllık tekrarda kullanılabilir")
+	}
+	for _, m := range p.Months {
+		if m < 1 || m > 12 {
+			return errors.New("Aylar 1 ile 12 arasında olmalıdır")
+		}
+	}
+
+	for _, pos := range p.SetPositions {
+		if pos == 0 || pos < -maxSetPosition || pos > maxSetPosition {
+			return errors.New("Sıra seçimi (setPositions) 0 olamaz ve -366 ile 366 arasında olmalıdır")
+		}
+	}
+
+	switch p.EndType {
+	case "", EndNever:
+	case EndAfter:
+		if p.EndAfter < 1 {
+			return errors.New("Tekrar sayısı en az 1 olmalıdır")
+		}
+	case EndOn, EndUntil:
+		if p.EndDate == nil {
+			return errors.New("Tekrar bitiş tarihi zorunludur")
+		}
+	default:
+		return errors.New("Bitiş tipi never, after, on veya until olmalıdır")
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/holidays"
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+	"github.com/denizbarcak/planvia-partner-api/internal/recurrence"
+)
+
+const dayLayout = "2006-01-02"
+
+// minutesPerDay "24:00" kapanış saatinin dakika karşılığıdır
+const minutesPerDay = 24 * 60
+
+// Schedule partner'ın çalışma saatlerini, kapalı günlerini ve resmi tatillerini birleştirir.
+// Tüm günler partner'ın saat diliminde değerlendirilir.
+type Schedule struct {
+	loc          *time.Location
+	hours        [7][]span
+	hasHours     bool
+	closures     []models.Closure
+	holidays     bool
+	openHolidays map[string]bool
+	skipClosed   bool
+}
+
+// span bir açık aralığıdır; gece yarısından itibaren dakika olarak
+type span struct {
+	open, close int
+}
+
+// New partner ayarlarından bir çalışma takvimi oluşturur. Geçersiz aralıklar yok sayılır;
+// ayarlar kaydedilirken Validate ile kontrol edilir.
+func New(p *models.Partner) *Schedule {
+	s := &Schedule{
+		loc:          p.Location(),
+		holidays:     p.PublicHolidayCalendar() == models.HolidayCalendarTurkey,
+		openHolidays: map[string]bool{},
+		skipClosed:   p.SkipClosedDays,
+	}
+	for _, h := range p.BusinessHours {
+		sp, err := parseSpan(h)
+		if err != nil || h.Day < 0 || h.Day > 6 {
+			continue
+		}
+		s.hours[h.Day] = append(s.hours[h.Day], sp)
+		s.hasHours = true
+	}
+	for _, c := range p.Closures {
+		if c.EndDay == "" {
+			c.EndDay = c.StartDay
+		}
+		s.closures = append(s.closures, c)
+	}
+	for _, d := range p.OpenHolidays {
+		s.openHolidays[d] = true
+	}
+	return s
+}
+
+// Location takvimin saat dilimidir
+func (s *Schedule) Location() *time.Location {
+	return s.loc
+}
+
+// ClosedReason verilen anın yerel gününde işletme kapalıysa nedenini, açıksa boş döndürür
+func (s *Schedule) ClosedReason(t time.Time) string {
+	local := t.In(s.loc)
+	day := local.Format(dayLayout)
+
+	for _, c := range s.closures {
+		if c.StartDay <= day && day <= c.EndDay {
+			if c.Reason != "" {
+				return fmt.Sprintf("%s kapalı: %s", day, c.Reason)
+			}
+			return fmt.Sprintf("%s kapalı", day)
+		}
+	}
+	if s.holidays && !s.openHolidays[day] {
+		if h, ok := holidays.TurkeyOn(day); ok {
+			return fmt.Sprintf("%s resmi tatil: %s", day, h.Name)
+		}
+	}
+	if s.hasHours && len(s.hours[local.Weekday()]) == 0 {
+		return fmt.Sprintf("%s haftalık çalışma saatlerine göre kapalı", day)
+	}
+	return ""
+}
+
+// HolidaysUncovered takvim resmi tatilleri izliyorsa ve t'nin yerel yılının dini bayramları
+// takvimde yoksa true döner. Bu yıllarda yalnızca sabit tarihli tatiller kapalı sayılır.
+func (s *Schedule) HolidaysUncovered(t time.Time) bool {
+	return s.holidays && !holidays.TurkeyCovers(t.In(s.loc).Year())
+}
+
+// Violation gerçekleşme kapalı bir güne veya çalışma saatleri dışına düşüyorsa nedenini,
+// uygunsa boş döndürür. Tüm gün ve birden fazla güne yayılan gerçekleşmelerde yalnızca
+// günlerin açık olup olmadığına bakılır.
+func (s *Schedule) Violation(r models.Reservation, o recurrence.Occurrence) string {
+	start := o.Start.In(s.loc)
+	last := start
+	if o.End.After(o.Start) {
+		last = o.End.Add(-time.Nanosecond).In(s.loc)
+	}
+
+	for d := startOfDay(start); !d.After(last); d = d.AddDate(0, 0, 1) {
+		if reason := s.ClosedReason(d); reason != "" {
+			return reason
+		}
+	}
+
+	if r.IsAllDay || !s.hasHours || last.Format(dayLayout) != start.Format(dayLayout) {
+		return ""
+	}
+
+	from := start.Hour()*60 + start.Minute()
+	// Gece yarısında biten gerçekleşme günün sonuna kadar sürer
+	to := minutesPerDay
+	if end := o.End.In(s.loc); end.Format(dayLayout) == start.Format(dayLayout) {
+		to = end.Hour()*60 + end.Minute()
+	}
+	for _, sp := range s.hours[start.Weekday()] {
+		if sp.open <= from && to <= sp.close {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%s %s-%s çalışma saatleri dışında", start.Format(dayLayout), start.Format("15:04"), o.End.In(s.loc).Format("15:04"))
+}
+
+// Expand rezervasyonun [from, to) penceresindeki gerçekleşmelerini döndürür. Partner kapalı
+// günlerin atlanmasını seçtiyse tekrar eden serilerin kapalı günlere düşen gerçekleşmeleri dönmez.
+func (s *Schedule) Expand(r models.Reservation, from, to time.Time) []recurrence.Occurrence {
+	return recurrence.ExpandSkipping(r, s.loc, from, to, s.skip(r))
+}
+
+// Each Expand'in döndüreceği gerçekleşmeleri sırayla fn'e verir; fn false dönerse durur
+func (s *Schedule) Each(r models.Reservation, from, to time.Time, fn func(o recurrence.Occurrence) bool) {
+	recurrence.Each(r, s.loc, from, to, s.skip(r), fn)
+}
+
+// Occurrence at zamanında başlayan, iptal edilmemiş ve atlanmayan gerçekleşmeyi döndürür
+func (s *Schedule) Occurrence(r models.Reservation, at time.Time) (recurrence.Occurrence, bool) {
+	o, ok := recurrence.At(r, s.loc, at)
+	if !ok || recurrence.IsException(r, s.loc, at) {
+		return o, false
+	}
+	if skip := s.skip(r); skip != nil && skip(o) {
+		return o, false
+	}
+	return o, true
+}
+
+// Skipped serinin [from, to) penceresinde kapalı günlere düştüğü için atlanan gerçekleşmelerinin
+// başlangıçlarını döndürür
+func (s *Schedule) Skipped(r models.Reservation, from, to time.Time) []time.Time {
+	skip := s.skip(r)
+	if skip == nil {
+		return nil
+	}
+	var starts []time.Time
+	for _, o := range recurrence.Expand(r, s.loc, from, to) {
+		if skip(o) {
+			starts = append(starts, o.Start)
+		}
+	}
+	return starts
+}
+
+func (s *Schedule) skip(r models.Reservation) recurrence.SkipFunc {
+	if !s.skipClosed || !r.Recurrence.Enabled {
+		return nil
+	}
+	return func(o recurrence.Occurrence) bool {
+		return s.ClosedReason(o.Start) != ""
+	}
+}
+
+// Validate çalışma saatlerini ve kapalı günleri kontrol eder
+func Validate(hours []models.BusinessHours, closures []models.Closure) error {
+	byDay := map[int][]span{}
+	for _, h := range hours {
+		sp, err := parseSpan(h)
+		if err != nil {
+			return err
+		}
+		byDay[h.Day] = append(byDay[h.Day], sp)
+	}
+	for _, spans := range byDay {
+		sort.Slice(spans, func(i, j int) bool { return spans[i].open < spans[j].open })
+		for i := 1; i < len(spans); i++ {
+			if spans[i].open < spans[i-1].close {
+				return errors.New("Aynı güne ait çalışma saatleri çakışamaz")
+			}
+		}
+	}
+
+	for _, c := range closures {
+		if c.EndDay != "" && c.EndDay < c.StartDay {
+			return errors.New("Kapalı gün aralığının bitişi başlangıcından önce olamaz")
+		}
+	}
+	return nil
+}
+
+func parseSpan(h models.BusinessHours) (span, error) {
+	open, err := parseClock(h.Open)
+	if err != nil {
+		return span{}, err
+	}
+	closing, err := parseClock(h.Close)
+	if err != nil {
+		return span{}, err
+	}
+	if open >= closing {
+		return span{}, errors.New("Açılış saati kapanış saatinden önce olmalıdır")
+	}
+	return span{open: open, close: closing}, nil
+}
+
+// parseClock "HH:MM" biçimindeki saati gece yarısından itibaren dakikaya çevirir; "24:00" kabul edilir
+func parseClock(value string) (int, error) {
+	hh, mm, ok := strings.Cut(value, ":")
+	h, errH := strconv.Atoi(hh)
+	m, errM := strconv.Atoi(mm)
+	if !ok || len(hh) != 2 || len(mm) != 2 || errH != nil || errM != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("Geçersiz saat: %q; HH:MM biçiminde olmalıdır", value)
+	}
+	return h*60 + m, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
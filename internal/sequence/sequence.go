@@ -0,0 +1,100 @@
+// Package sequence partner başına artan sıra numaraları verir. Sıra numaraları, zaman
+// damgalarından farklı olarak yazma sırasından bağımsızdır: okuyucular yalnızca önündeki
+// bütün numaraları yazılmış olan kayıtları görür, böylece geç biten bir yazma atlanmaz.
+package sequence
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PendingTimeout verilen ama yazıldığı bildirilmeyen bir numaranın okuyucuları bekletebileceği
+// en uzun süredir. Yazma bu sürede bitmediyse başarısız sayılır ve numara atlanır.
+const PendingTimeout = time.Minute
+
+// Counter bir kayıt türü için partner başına sıra numarası sayaçlarını sequences koleksiyonunda tutar
+type Counter struct {
+	collection *mongo.Collection
+	name       string
+}
+
+func NewCounter(db *mongo.Database, name string) *Counter {
+	return &Counter{collection: db.Collection("sequences"), name: name}
+}
+
+type counter struct {
+	Seq     int64     `bson:"seq"`
+	Pending []pending `bson:"pending"`
+}
+
+// pending verilmiş ama henüz yazıldığı bildirilmemiş bir numaradır
+type pending struct {
+	Seq int64     `bson:"seq"`
+	At  time.Time `bson:"at"`
+}
+
+func (c *Counter) key(partnerID primitive.ObjectID) string {
+	return c.name + ":" + partnerID.Hex()
+}
+
+// Next partner için yeni bir numara verir. Numara kaydı yazılana kadar bekleyen sayılır;
+// kayıt yazılınca (veya yazma başarısız olunca) Done çağrılmalıdır.
+func (c *Counter) Next(ctx context.Context, partnerID primitive.ObjectID) (int64, error) {
+	now := time.Now()
+	var doc counter
+	err := c.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": c.key(partnerID)},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"seq": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$seq", 0}}, 1}}}}},
+			// Süresi dolmuş bekleyen numaralar temizlenir, yenisi eklenir
+			{{Key: "$set", Value: bson.M{"pending": bson.M{"$concatArrays": bson.A{
+				bson.M{"$filter": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$pending", bson.A{}}},
+					"cond":  bson.M{"$gt": bson.A{"$$this.at", now.Add(-PendingTimeout)}},
+				}},
+				bson.A{bson.M{"seq": "$seq", "at": now}},
+			}}}}},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&doc)
+	if err != nil {
+		return 0, err
+	}
+	return doc.Seq, nil
+}
+
+// Done numaranın kaydının yazıldığını veya yazılamadığını bildirir
+func (c *Counter) Done(ctx context.Context, partnerID primitive.ObjectID, seq int64) error {
+	_, err := c.collection.UpdateOne(ctx,
+		bson.M{"_id": c.key(partnerID)},
+		bson.M{"$pull": bson.M{"pending": bson.M{"seq": seq}}},
+	)
+	return err
+}
+
+// Visible okuyucuların güvenle görebileceği en büyük numarayı döndürür: kendisinden küçük
+// bütün numaraların kayıtları yazılmıştır. Henüz numara verilmediyse sıfır döner.
+func (c *Counter) Visible(ctx context.Context, partnerID primitive.ObjectID) (int64, error) {
+	var doc counter
+	err := c.collection.FindOne(ctx, bson.M{"_id": c.key(partnerID)}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	visible := doc.Seq
+	cutoff := time.Now().Add(-PendingTimeout)
+	for _, p := range doc.Pending {
+		if p.At.After(cutoff) && p.Seq <= visible {
+			visible = p.Seq - 1
+		}
+	}
+	return visible, nil
+}
@@ -0,0 +1,81 @@
+// Package tombstones silinen rezervasyonların kaydını tutar. Artımlı senkronizasyon ve
+// takvim aboneliklerinin Last-Modified değeri silmeleri bu kayıtlardan görür.
+package tombstones
+
+import (
+	"context"
+	"time"
+
+	"github.com/denizbarcak/planvia-partner-api/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Retention silme kayıtlarının saklandığı süredir; daha eski kayıtlar TTL index'iyle silinir.
+// Bundan eski senkronizasyon belirteçleri kabul edilmez.
+const Retention = 30 * 24 * time.Hour
+
+// Log silinen rezervasyonları reservation_tombstones koleksiyonunda saklar
+type Log struct {
+	collection *mongo.Collection
+}
+
+func NewLog(db *mongo.Database) *Log {
+	return &Log{collection: db.Collection("reservation_tombstones")}
+}
+
+// Record silinen rezervasyonları, silen yazmanın sıra numarasıyla kaydeder
+func (l *Log) Record(ctx context.Context, partnerID primitive.ObjectID, ids []primitive.ObjectID, seq int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(ids))
+	for i, id := range ids {
+		docs[i] = models.ReservationTombstone{ID: id, PartnerID: partnerID, DeletedAt: now, Seq: seq}
+	}
+	_, err := l.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// Since partner'ın sıra numarası after'dan büyük, upTo'dan büyük olmayan silme kayıtlarını
+// sıra numarasıyla getirir
+func (l *Log) Since(ctx context.Context, partnerID primitive.ObjectID, after, upTo int64) ([]models.ReservationTombstone, error) {
+	cursor, err := l.collection.Find(ctx,
+		bson.M{"partnerId": partnerID, "seq": bson.M{"$gt": after, "$lte": upTo}},
+		options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tombstones := []models.ReservationTombstone{}
+	if err := cursor.All(ctx, &tombstones); err != nil {
+		return nil, err
+	}
+	return tombstones, nil
+}
+
+// Latest partner'ın en son silme zamanını döndürür; hiç silme yoksa sıfır zaman döner
+func (l *Log) Latest(ctx context.Context, partnerID primitive.ObjectID) (time.Time, error) {
+	var latest models.ReservationTombstone
+	err := l.collection.FindOne(ctx,
+		bson.M{"partnerId": partnerID},
+		options.FindOne().SetSort(bson.D{{Key: "deletedAt", Value: -1}}),
+	).Decode(&latest)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return latest.DeletedAt, nil
+}
@@ -0,0 +1,50 @@
+package validation
+
+import (
+	"strings"
+)
+
+// NormalizePhone telefon numarasını E.164 biçimine (+905321234567) çevirir.
+// Ülke kodu olmayan numaralar Türkiye numarası kabul edilir.
+func NormalizePhone(s string) (string, bool) {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+			// ayraçlar yok sayılır
+		default:
+			return "", false
+		}
+	}
+	digits := b.String()
+
+	switch {
+	case strings.HasPrefix(digits, "+"):
+		digits = digits[1:]
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	case strings.HasPrefix(digits, "0") && len(digits) == 11:
+		digits = "90" + digits[1:]
+	case len(digits) == 10:
+		digits = "90" + digits
+	}
+
+	// Türkiye numaraları için ulusal kısım 10 hane olmalı ve geçerli bir alan koduyla başlamalı
+	if strings.HasPrefix(digits, "90") {
+		national := digits[2:]
+		if len(national) != 10 || !strings.ContainsRune("23458", rune(national[0])) {
+			return "", false
+		}
+	}
+
+	// E.164: ülke kodu dahil en fazla 15 hane, ilk hane 0 olamaz
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", false
+	}
+
+	return "+" + digits, true
+}
\ No newline at end of file
@@ -0,0 +1,67 @@
+package validation
+
+// TaxNumberProblem vergi numarasındaki sorunu Türkçe olarak açıklar; numara
+// geçerli bir 10 haneli VKN veya 11 haneli TCKN ise boş döner.
+func TaxNumberProblem(s string) string {
+	s = NormalizeTaxNumber(s)
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return "Vergi numarası yalnızca rakamlardan oluşmalıdır"
+		}
+	}
+
+	switch len(s) {
+	case 10:
+		if !validVKN(s) {
+			return "Vergi kimlik numarası (VKN) geçersiz, kontrol hanesi tutmuyor"
+		}
+	case 11:
+		if s[0] == '0' {
+			return "T.C. kimlik numarası 0 ile başlayamaz"
+		}
+		if !validTCKN(s) {
+			return "T.C. kimlik numarası geçersiz, kontrol haneleri tutmuyor"
+		}
+	default:
+		return "Vergi numarası 10 haneli VKN veya 11 haneli T.C. kimlik numarası olmalıdır"
+	}
+
+	return ""
+}
+
+// validVKN Gelir İdaresi'nin 10 haneli vergi kimlik numarası algoritmasını uygular
+func validVKN(s string) bool {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		tmp := (int(s[i]-'0') + 9 - i) % 10
+		if tmp == 0 {
+			continue
+		}
+		v := (tmp * (1 << (9 - i))) % 9
+		if v == 0 {
+			v = 9
+		}
+		sum += v
+	}
+	return (10-sum%10)%10 == int(s[9]-'0')
+}
+
+// validTCKN 11 haneli T.C. kimlik numarasının iki kontrol hanesini doğrular
+func validTCKN(s string) bool {
+	d := make([]int, 11)
+	for i := range d {
+		d[i] = int(s[i] - '0')
+	}
+
+	odd := d[0] + d[2] + d[4] + d[6] + d[8]
+	even := d[1] + d[3] + d[5] + d[7]
+	if ((odd*7-even)%10+10)%10 != d[9] {
+		return false
+	}
+
+	sum := 0
+	for _, v := range d[:10] {
+		sum += v
+	}
+	return sum%10 == d[10]
+}
\ No newline at end of file
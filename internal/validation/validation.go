@@ -0,0 +1,25 @@
+package validation
+
+import (
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// New projeye özel kurallarla (taxnumber, phone) bir validator oluşturur
+func New() *validator.Validate {
+	v := validator.New()
+	_ = v.RegisterValidation("taxnumber", func(fl validator.FieldLevel) bool {
+		return TaxNumberProblem(fl.Field().String()) == ""
+	})
+	_ = v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		_, ok := NormalizePhone(fl.Field().String())
+		return ok
+	})
+	return v
+}
+
+// NormalizeTaxNumber boşlukları temizler
+func NormalizeTaxNumber(s string) string {
+	return strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+}
\ No newline at end of file